@@ -0,0 +1,7 @@
+package main
+
+import "github.com/kei6u/dogfood/pkg/entrypoint"
+
+func main() {
+	entrypoint.RunDogfoodctl()
+}
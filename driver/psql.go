@@ -1,17 +1,98 @@
 package driver
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/kei6u/dogfood/pkg/ddconfig"
+	"github.com/kei6u/dogfood/pkg/telemetry"
+	"github.com/lib/pq"
+	sqltrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/database/sql"
 )
 
-// Connecting Postgres by using environment variables.
-func NewPsql() (db *sql.DB, close func() error, err error) {
+const (
+	psqlPingAttempts       = 5
+	psqlPingInitialBackoff = 500 * time.Millisecond
+	psqlPingMaxBackoff     = 5 * time.Second
+)
+
+// PsqlPoolConfig configures the connection pool of Postgres.
+type PsqlPoolConfig struct {
+	// MaxOpenConns is the max number of connections. It's unlimited if it's not positive.
+	MaxOpenConns int
+	// MaxIdleConns is the max number of idle connections. No connection is kept if it's not positive.
+	MaxIdleConns int
+	// ConnMaxLifetime closes connections older than it, e.g. so that failover or rotated credentials are picked up.
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime closes connections idle longer than it.
+	ConnMaxIdleTime time.Duration
+}
+
+var defaultPsqlPoolConfig = PsqlPoolConfig{
+	MaxOpenConns:    20,
+	MaxIdleConns:    10,
+	ConnMaxLifetime: 30 * time.Minute,
+	ConnMaxIdleTime: 5 * time.Minute,
+}
+
+// PsqlPoolConfigFromEnv loads a config from POSTGRES_MAX_OPEN_CONNS, POSTGRES_MAX_IDLE_CONNS,
+// POSTGRES_CONN_MAX_LIFETIME and POSTGRES_CONN_MAX_IDLE_TIME, e.g. 30m.
+// It defaults to 20 open and 10 idle connections, which live for 30 minutes and idle for 5 minutes at most.
+func PsqlPoolConfigFromEnv() (PsqlPoolConfig, error) {
+	c := defaultPsqlPoolConfig
+	for _, n := range []struct {
+		env string
+		v   *int
+	}{
+		{"POSTGRES_MAX_OPEN_CONNS", &c.MaxOpenConns},
+		{"POSTGRES_MAX_IDLE_CONNS", &c.MaxIdleConns},
+	} {
+		if v := os.Getenv(n.env); v != "" {
+			i, err := strconv.Atoi(v)
+			if err != nil {
+				return c, fmt.Errorf("%s is invalid: %w", n.env, err)
+			}
+			*n.v = i
+		}
+	}
+	for _, d := range []struct {
+		env string
+		v   *time.Duration
+	}{
+		{"POSTGRES_CONN_MAX_LIFETIME", &c.ConnMaxLifetime},
+		{"POSTGRES_CONN_MAX_IDLE_TIME", &c.ConnMaxIdleTime},
+	} {
+		if v := os.Getenv(d.env); v != "" {
+			dur, err := time.ParseDuration(v)
+			if err != nil {
+				return c, fmt.Errorf("%s is invalid: %w", d.env, err)
+			}
+			*d.v = dur
+		}
+	}
+	return c, nil
+}
+
+func (c PsqlPoolConfig) apply(db *sql.DB) {
+	db.SetMaxOpenConns(c.MaxOpenConns)
+	db.SetMaxIdleConns(c.MaxIdleConns)
+	db.SetConnMaxLifetime(c.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+}
+
+var registerTracedPsql sync.Once
+
+// NewPsql connects Postgres by using environment variables. Queries are traced by the backend selected by
+// TELEMETRY_BACKEND, and the pool is configured by PsqlPoolConfigFromEnv.
+// It waits for Postgres to be available until ctx is done.
+func NewPsql(ctx context.Context) (db *sql.DB, close func() error, err error) {
 	var host string
 	var port string
 	var user string
@@ -33,31 +114,99 @@ func NewPsql() (db *sql.DB, close func() error, err error) {
 	if dbname = os.Getenv("POSTGRES_DB"); dbname == "" {
 		return nil, nil, errors.New("dbname is missing")
 	}
+	pool, err := PsqlPoolConfigFromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
 
 	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname,
+		"host=%s port=%s user=%s password=%s dbname=%s %s",
+		quoteDSNValue(host), quoteDSNValue(port), quoteDSNValue(user), quoteDSNValue(password), quoteDSNValue(dbname),
+		psqlSSLParams(),
 	)
-	db, err = sql.Open("postgres", dsn)
+	// The DSN is never in errors, because it has the password.
+	db, err = openTracedPsql(dsn)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to open postgres by %s: %w", dsn, err)
+		return nil, nil, fmt.Errorf("failed to open postgres at %s:%s: %w", host, port, err)
 	}
-	if err := pingPsql(db); err != nil {
-		return nil, nil, fmt.Errorf("failed to ping postgres: %w", err)
+	pool.apply(db)
+	if err := pingPsql(ctx, db, psqlPingAttempts, psqlPingInitialBackoff); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("failed to ping postgres at %s:%s: %w", host, port, err)
 	}
 	return db, db.Close, nil
 }
 
-func pingPsql(db *sql.DB) error {
-	var err error
-	for i := 1; i < 5; i++ {
-		if err = db.Ping(); err != nil {
-			time.Sleep(time.Duration(i * int(time.Second)))
-			continue
-		}
+// openTracedPsql opens Postgres whose queries are traced by dd-trace sqltrace for Datadog, which tags them
+// richer, and by tracedConnector for the other backends.
+func openTracedPsql(dsn string) (*sql.DB, error) {
+	if telemetry.GetBackend() == telemetry.BackendDatadog {
+		registerTracedPsql.Do(func() {
+			sqltrace.Register("postgres", &pq.Driver{}, sqltrace.WithServiceName(ddconfig.GetService(ddconfig.WithServiceSuffix(".postgres"))))
+		})
+		return sqltrace.Open("postgres", dsn)
 	}
+	c, err := pq.NewConnector(dsn)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return sql.OpenDB(tracedConnector{c}), nil
+}
+
+// psqlSSLParams builds SSL parameters of DSN.
+// POSTGRES_SSLMODE defaults to disable, and verify-full is recommended with POSTGRES_SSLROOTCERT.
+// POSTGRES_SSLCERT and POSTGRES_SSLKEY specify a client certificate.
+func psqlSSLParams() string {
+	mode := os.Getenv("POSTGRES_SSLMODE")
+	if mode == "" {
+		mode = "disable"
+	}
+	params := []string{fmt.Sprintf("sslmode=%s", quoteDSNValue(mode))}
+	for _, p := range []struct {
+		key string
+		env string
+	}{
+		{"sslrootcert", "POSTGRES_SSLROOTCERT"},
+		{"sslcert", "POSTGRES_SSLCERT"},
+		{"sslkey", "POSTGRES_SSLKEY"},
+	} {
+		if v := os.Getenv(p.env); v != "" {
+			params = append(params, fmt.Sprintf("%s=%s", p.key, quoteDSNValue(v)))
+		}
+	}
+	return strings.Join(params, " ")
+}
+
+// quoteDSNValue quotes v as a value of DSN, so that paths and passwords may contain spaces and quotes.
+func quoteDSNValue(v string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
+
+// pinger is implemented by *sql.DB.
+type pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// pingPsql pings db up to attempts times until it succeeds, waiting for backoff doubled on every failure
+// up to psqlPingMaxBackoff. It gives up when ctx is done.
+func pingPsql(ctx context.Context, db pinger, attempts int, backoff time.Duration) error {
+	var err error
+	for i := 1; ; i++ {
+		if err = db.PingContext(ctx); err == nil {
+			return nil
+		}
+		if i >= attempts {
+			return fmt.Errorf("gave up after %d attempts: %w", attempts, err)
+		}
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("gave up after %d attempts: %v: %w", i, err, ctx.Err())
+		case <-t.C:
+		}
+		if backoff *= 2; backoff > psqlPingMaxBackoff {
+			backoff = psqlPingMaxBackoff
+		}
 	}
-	return nil
 }
@@ -0,0 +1,120 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakePinger struct {
+	failures int
+	pings    int
+}
+
+func (p *fakePinger) PingContext(context.Context) error {
+	p.pings++
+	if p.pings <= p.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestPingPsql(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		failures  int
+		wantPings int
+		wantErr   bool
+	}{
+		{name: "available", ctx: context.Background(), failures: 0, wantPings: 1},
+		{name: "available after retries", ctx: context.Background(), failures: 2, wantPings: 3},
+		{name: "unavailable", ctx: context.Background(), failures: 10, wantPings: 3, wantErr: true},
+		{name: "canceled", ctx: canceled, failures: 10, wantPings: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &fakePinger{failures: tt.failures}
+			err := pingPsql(tt.ctx, p, 3, time.Millisecond)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("pingPsql() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if p.pings != tt.wantPings {
+				t.Errorf("pinged %d times, want %d", p.pings, tt.wantPings)
+			}
+		})
+	}
+}
+
+func TestPsqlPoolConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    PsqlPoolConfig
+		wantErr bool
+	}{
+		{name: "default", want: defaultPsqlPoolConfig},
+		{
+			name: "configured",
+			env: map[string]string{
+				"POSTGRES_MAX_OPEN_CONNS":     "50",
+				"POSTGRES_MAX_IDLE_CONNS":     "0",
+				"POSTGRES_CONN_MAX_LIFETIME":  "1h",
+				"POSTGRES_CONN_MAX_IDLE_TIME": "1m",
+			},
+			want: PsqlPoolConfig{MaxOpenConns: 50, MaxIdleConns: 0, ConnMaxLifetime: time.Hour, ConnMaxIdleTime: time.Minute},
+		},
+		{name: "invalid conns", env: map[string]string{"POSTGRES_MAX_OPEN_CONNS": "many"}, wantErr: true},
+		{name: "invalid lifetime", env: map[string]string{"POSTGRES_CONN_MAX_LIFETIME": "30"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range []string{"POSTGRES_MAX_OPEN_CONNS", "POSTGRES_MAX_IDLE_CONNS", "POSTGRES_CONN_MAX_LIFETIME", "POSTGRES_CONN_MAX_IDLE_TIME"} {
+				t.Setenv(k, tt.env[k])
+			}
+			got, err := PsqlPoolConfigFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PsqlPoolConfigFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("PsqlPoolConfigFromEnv() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPsqlSSLParams(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{
+			name: "default",
+			want: "sslmode='disable'",
+		},
+		{
+			name: "paths are quoted",
+			env: map[string]string{
+				"POSTGRES_SSLMODE":     "verify-full",
+				"POSTGRES_SSLROOTCERT": "/etc/ssl/my certs/ca.pem",
+				"POSTGRES_SSLCERT":     `/etc/ssl/dog's\client.pem`,
+				"POSTGRES_SSLKEY":      "/etc/ssl/client.key",
+			},
+			want: `sslmode='verify-full' sslrootcert='/etc/ssl/my certs/ca.pem' sslcert='/etc/ssl/dog\'s\\client.pem' sslkey='/etc/ssl/client.key'`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range []string{"POSTGRES_SSLMODE", "POSTGRES_SSLROOTCERT", "POSTGRES_SSLCERT", "POSTGRES_SSLKEY"} {
+				t.Setenv(k, tt.env[k])
+			}
+			if got := psqlSSLParams(); got != tt.want {
+				t.Errorf("psqlSSLParams() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/kei6u/dogfood/pkg/tlsconfig"
 )
 
 // NewRedis returns a client to the Redis Server specified by Options.
@@ -25,10 +26,25 @@ func NewRedis(ctx context.Context) (*redis.Client, func() error, error) {
 		return nil, nil, errors.New("redis password is missing")
 	}
 
-	c := redis.NewClient(&redis.Options{
+	// REDIS_TLS_CA_FILE, REDIS_TLS_CERT_FILE and REDIS_TLS_KEY_FILE enable TLS.
+	tc, err := tlsconfig.FromEnv("REDIS_TLS")
+	if err != nil {
+		return nil, nil, fmt.Errorf("redis tls config is invalid: %w", err)
+	}
+	opts := &redis.Options{
 		Addr:     fmt.Sprintf("%s:%s", host, addr),
 		Password: pw,
 		DB:       0,
-	})
+	}
+	if tc != nil {
+		if tc.ServerName == "" {
+			tc.ServerName = host
+		}
+		if opts.TLSConfig, err = tc.ClientTLSConfig(); err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize redis tls config: %w", err)
+		}
+	}
+
+	c := redis.NewClient(opts)
 	return c, c.Close, nil
 }
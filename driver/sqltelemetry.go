@@ -0,0 +1,84 @@
+package driver
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+
+	"github.com/kei6u/dogfood/pkg/telemetry"
+)
+
+// sqlQueryOperation is an operation name of spans of queries.
+const sqlQueryOperation = "postgres.query"
+
+// tracedConnector traces queries by the telemetry backend, e.g. OpenTelemetry, which dd-trace sqltrace
+// doesn't send to. Queries run on prepared statements are not traced, because the stores don't prepare them.
+type tracedConnector struct {
+	sqldriver.Connector
+}
+
+func (c tracedConnector) Connect(ctx context.Context) (sqldriver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{conn}, nil
+}
+
+type tracedConn struct {
+	sqldriver.Conn
+}
+
+var (
+	_ sqldriver.QueryerContext     = (*tracedConn)(nil)
+	_ sqldriver.ExecerContext      = (*tracedConn)(nil)
+	_ sqldriver.ConnPrepareContext = (*tracedConn)(nil)
+	_ sqldriver.ConnBeginTx        = (*tracedConn)(nil)
+	_ sqldriver.Pinger             = (*tracedConn)(nil)
+)
+
+// QueryContext returns driver.ErrSkip if the underlying connection doesn't query directly,
+// so that database/sql prepares a statement instead.
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Rows, error) {
+	q, ok := c.Conn.(sqldriver.QueryerContext)
+	if !ok {
+		return nil, sqldriver.ErrSkip
+	}
+	span, ctx := telemetry.StartSpanFromContext(ctx, sqlQueryOperation, query)
+	defer span.Finish()
+	return q.QueryContext(ctx, query, args)
+}
+
+// ExecContext returns driver.ErrSkip if the underlying connection doesn't execute directly.
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Result, error) {
+	e, ok := c.Conn.(sqldriver.ExecerContext)
+	if !ok {
+		return nil, sqldriver.ErrSkip
+	}
+	span, ctx := telemetry.StartSpanFromContext(ctx, sqlQueryOperation, query)
+	defer span.Finish()
+	return e.ExecContext(ctx, query, args)
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (sqldriver.Stmt, error) {
+	if p, ok := c.Conn.(sqldriver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts sqldriver.TxOptions) (sqldriver.Tx, error) {
+	span, ctx := telemetry.StartSpanFromContext(ctx, sqlQueryOperation, "BEGIN")
+	defer span.Finish()
+	if b, ok := c.Conn.(sqldriver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	// Drivers without BeginTx support default options only, which database/sql checks.
+	return c.Conn.Begin()
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(sqldriver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package driver
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+	"errors"
+	"testing"
+)
+
+// fakeConn is a connection which neither queries nor executes directly.
+type fakeConn struct {
+	sqldriver.Conn
+	pinged bool
+}
+
+func (c *fakeConn) Ping(context.Context) error {
+	c.pinged = true
+	return nil
+}
+
+// fakeQueryerConn queries and executes directly.
+type fakeQueryerConn struct {
+	fakeConn
+	queries []string
+}
+
+func (c *fakeQueryerConn) QueryContext(_ context.Context, query string, _ []sqldriver.NamedValue) (sqldriver.Rows, error) {
+	c.queries = append(c.queries, query)
+	return nil, nil
+}
+
+func (c *fakeQueryerConn) ExecContext(_ context.Context, query string, _ []sqldriver.NamedValue) (sqldriver.Result, error) {
+	c.queries = append(c.queries, query)
+	return sqldriver.RowsAffected(1), nil
+}
+
+func TestTracedConn(t *testing.T) {
+	ctx := context.Background()
+
+	// database/sql prepares statements instead if the connection doesn't query directly.
+	plain := &tracedConn{&fakeConn{}}
+	if _, err := plain.QueryContext(ctx, "SELECT 1", nil); !errors.Is(err, sqldriver.ErrSkip) {
+		t.Errorf("QueryContext() error = %v, want %v", err, sqldriver.ErrSkip)
+	}
+	if _, err := plain.ExecContext(ctx, "SELECT 1", nil); !errors.Is(err, sqldriver.ErrSkip) {
+		t.Errorf("ExecContext() error = %v, want %v", err, sqldriver.ErrSkip)
+	}
+
+	conn := &fakeQueryerConn{}
+	traced := &tracedConn{conn}
+	if _, err := traced.QueryContext(ctx, "SELECT 1", nil); err != nil {
+		t.Errorf("QueryContext() error = %v", err)
+	}
+	if _, err := traced.ExecContext(ctx, "DELETE FROM outbox", nil); err != nil {
+		t.Errorf("ExecContext() error = %v", err)
+	}
+	if err := traced.Ping(ctx); err != nil || !conn.pinged {
+		t.Errorf("Ping() error = %v, pinged = %v, want it forwarded", err, conn.pinged)
+	}
+	if len(conn.queries) != 2 || conn.queries[0] != "SELECT 1" || conn.queries[1] != "DELETE FROM outbox" {
+		t.Errorf("queries = %v, want them forwarded", conn.queries)
+	}
+}
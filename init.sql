@@ -1,8 +1,105 @@
-CREATE TABLE record
+-- init.sql creates the schema of a fresh database, and migrates an existing one when it's applied again,
+-- e.g. `psql -f init.sql`. Every statement is idempotent.
+
+CREATE TABLE IF NOT EXISTS record
 (
+	id BIGSERIAL PRIMARY KEY,
 	dogfood_name varchar(50) NOT NULL,
 	gram INTEGER NOT NULL,
 	dog_name varchar(50) NOT NULL,
 	eaten_at TIMESTAMP NOT NULL,
-	PRIMARY KEY(dogfood_name, dog_name, eaten_at)
+	deleted_at TIMESTAMP
+);
+-- Records created before they had IDs and were soft deleted are numbered, and their primary key of
+-- (dogfood_name, dog_name, eaten_at) is replaced by id, because deleted records may have the same key.
+DO $$
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'record' AND column_name = 'id'
+	) THEN
+		ALTER TABLE record DROP CONSTRAINT IF EXISTS record_pkey;
+		ALTER TABLE record ADD COLUMN id BIGSERIAL PRIMARY KEY;
+	END IF;
+END $$;
+ALTER TABLE record ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+-- record_key makes records unique among ones which are not soft deleted.
+CREATE UNIQUE INDEX IF NOT EXISTS record_key ON record (dogfood_name, dog_name, eaten_at) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS record_eaten_at_idx ON record (eaten_at) WHERE deleted_at IS NULL;
+
+-- audit_event is an append-only log of mutations of record, which is written in the same transaction.
+-- record_id is not a foreign key, so that events of purged records are kept.
+CREATE TABLE IF NOT EXISTS audit_event
+(
+	id BIGSERIAL PRIMARY KEY,
+	record_id BIGINT NOT NULL,
+	action varchar(20) NOT NULL,
+	actor varchar(100) NOT NULL,
+	request_id varchar(100) NOT NULL,
+	before JSONB,
+	after JSONB,
+	occurred_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS audit_event_record_id_idx ON audit_event (record_id, id DESC);
+
+CREATE OR REPLACE FUNCTION audit_event_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_event is append-only';
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS audit_event_append_only ON audit_event;
+CREATE TRIGGER audit_event_append_only BEFORE UPDATE OR DELETE ON audit_event
+	FOR EACH ROW EXECUTE FUNCTION audit_event_append_only();
+
+CREATE TABLE IF NOT EXISTS alert
+(
+	id BIGSERIAL PRIMARY KEY,
+	rule_name varchar(100) NOT NULL,
+	dog_name varchar(50) NOT NULL,
+	rule_type varchar(50) NOT NULL,
+	state varchar(20) NOT NULL,
+	message TEXT NOT NULL,
+	transitioned_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS alert_rule_name_transitioned_at_idx ON alert (rule_name, transitioned_at DESC);
+-- pending is true until a transition is notified, which is retried on every evaluation.
+ALTER TABLE alert ADD COLUMN IF NOT EXISTS pending BOOLEAN NOT NULL DEFAULT false;
+CREATE INDEX IF NOT EXISTS alert_pending_idx ON alert (id) WHERE pending;
+
+-- secret is encrypted by WEBHOOK_SECRET_KEY, because it signs payloads and can't be hashed.
+CREATE TABLE IF NOT EXISTS webhook_subscription
+(
+	id BIGSERIAL PRIMARY KEY,
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+
+-- webhook_delivery is an outbox of webhook_subscription, which is written in the same transaction as record.
+CREATE TABLE IF NOT EXISTS webhook_delivery
+(
+	id BIGSERIAL PRIMARY KEY,
+	subscription_id BIGINT NOT NULL REFERENCES webhook_subscription (id) ON DELETE CASCADE,
+	event_type varchar(50) NOT NULL,
+	payload TEXT NOT NULL,
+	status varchar(20) NOT NULL,
+	attempts INTEGER NOT NULL,
+	next_attempt_at TIMESTAMP NOT NULL,
+	last_error TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	delivered_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS webhook_delivery_pending_idx ON webhook_delivery (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_delivery_subscription_id_idx ON webhook_delivery (subscription_id, id DESC);
+
+-- outbox holds events until they are published to a message broker.
+CREATE TABLE IF NOT EXISTS outbox
+(
+	id BIGSERIAL PRIMARY KEY,
+	event_id varchar(32) NOT NULL UNIQUE,
+	topic varchar(100) NOT NULL,
+	key varchar(100) NOT NULL,
+	payload BYTEA NOT NULL,
+	created_at TIMESTAMP NOT NULL
 );
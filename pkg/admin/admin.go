@@ -0,0 +1,119 @@
+// Package admin serves endpoints for operators: metrics, profiles, build info, config and the log level.
+// They must not be exposed publicly, so the admin server listens on localhost by default.
+// Metrics are also served alone by the metrics server, which agents outside of the host scrape.
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultAddr is the default address of the admin server.
+	DefaultAddr = "localhost:9092"
+
+	MetricsPath   = "/metrics"
+	PprofPath     = "/debug/pprof/"
+	BuildInfoPath = "/buildinfo"
+	ConfigPath    = "/config"
+	// LogLevelPath serves the log level, which is changed by PUT with {"level":"debug"}.
+	LogLevelPath = "/loglevel"
+)
+
+// Addr returns addr to listen on. A port without host, e.g. 9092, listens on localhost
+// rather than all interfaces.
+func Addr(addr string) string {
+	if addr != "" && !strings.Contains(addr, ":") {
+		return "localhost:" + addr
+	}
+	return addr
+}
+
+// AddrFromEnv returns an address of the admin server by ADMIN_ADDR, or localhost:9092 by default.
+func AddrFromEnv() string {
+	if addr := os.Getenv("ADMIN_ADDR"); addr != "" {
+		return Addr(addr)
+	}
+	return DefaultAddr
+}
+
+// MetricsAddrFromEnv returns an address of the metrics server by METRICS_ADDR, e.g. :9091.
+// A port without host listens on all interfaces. It's empty by default, which disables the metrics server.
+func MetricsAddrFromEnv() string {
+	addr := os.Getenv("METRICS_ADDR")
+	if addr != "" && !strings.Contains(addr, ":") {
+		return ":" + addr
+	}
+	return addr
+}
+
+type options struct {
+	level    *zap.AtomicLevel
+	gatherer prometheus.Gatherer
+}
+
+type Option func(*options)
+
+// WithLogLevel serves level at /loglevel.
+func WithLogLevel(level zap.AtomicLevel) Option {
+	return func(o *options) {
+		o.level = &level
+	}
+}
+
+// WithMetrics serves metrics gathered by g at /metrics.
+func WithMetrics(g prometheus.Gatherer) Option {
+	return func(o *options) {
+		o.gatherer = g
+	}
+}
+
+// NewServer returns a server of the admin endpoints listening on addr.
+// Profiles, build info and config are always served.
+func NewServer(addr string, opts ...Option) *http.Server {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(PprofPath, pprof.Index)
+	mux.HandleFunc(PprofPath+"cmdline", pprof.Cmdline)
+	mux.HandleFunc(PprofPath+"profile", pprof.Profile)
+	mux.HandleFunc(PprofPath+"symbol", pprof.Symbol)
+	mux.HandleFunc(PprofPath+"trace", pprof.Trace)
+	mux.HandleFunc(BuildInfoPath, func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, ReadBuildInfo())
+	})
+	mux.HandleFunc(ConfigPath, func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, redactedEnv(os.Environ()))
+	})
+	if o.gatherer != nil {
+		mux.Handle(MetricsPath, promhttp.HandlerFor(o.gatherer, promhttp.HandlerOpts{}))
+	}
+	if o.level != nil {
+		mux.Handle(LogLevelPath, o.level)
+	}
+	return &http.Server{Addr: addr, Handler: mux}
+}
+
+// NewMetricsServer returns a server which serves only metrics gathered by g at /metrics, listening on addr.
+// It's served in plaintext even if the other servers are served over TLS, so that agents scrape it by http.
+func NewMetricsServer(addr string, g prometheus.Gatherer) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(MetricsPath, promhttp.HandlerFor(g, promhttp.HandlerOpts{}))
+	return &http.Server{Addr: addr, Handler: mux}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
@@ -0,0 +1,37 @@
+package admin
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Version and Commit are set at build time, e.g.
+// go build -ldflags "-X github.com/kei6u/dogfood/pkg/admin.Version=v1.0.0 -X github.com/kei6u/dogfood/pkg/admin.Commit=abc123".
+var (
+	Version = "dev"
+	Commit  = "unknown"
+)
+
+// BuildInfo describes how the binary was built.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"go_version"`
+	// Module is the main module, and ModuleVersion is its version, which is (devel) unless it's installed by go install.
+	Module        string `json:"module,omitempty"`
+	ModuleVersion string `json:"module_version,omitempty"`
+}
+
+// ReadBuildInfo returns build info of the running binary.
+func ReadBuildInfo() BuildInfo {
+	bi := BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		GoVersion: runtime.Version(),
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		bi.Module = info.Main.Path
+		bi.ModuleVersion = info.Main.Version
+	}
+	return bi
+}
@@ -0,0 +1,29 @@
+package alerting
+
+import "time"
+
+// State is a state of an alert.
+type State string
+
+const (
+	StateFiring   State = "firing"
+	StateResolved State = "resolved"
+)
+
+// Alert is a state transition of a rule.
+type Alert struct {
+	ID             int64     `json:"-"`
+	RuleName       string    `json:"ruleName"`
+	Dog            string    `json:"dog"`
+	Type           RuleType  `json:"type"`
+	State          State     `json:"state"`
+	Message        string    `json:"message"`
+	TransitionedAt time.Time `json:"transitionedAt"`
+}
+
+// ListFilter filters alerts.
+type ListFilter struct {
+	Dog      string
+	State    State
+	PageSize int
+}
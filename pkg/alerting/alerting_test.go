@@ -0,0 +1,299 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type fakeStore struct {
+	meals       []Meal // in descending order of time, each of which is 100 grams
+	transitions []*Alert
+	notified    map[int64]bool
+	locked      bool
+}
+
+func (s *fakeStore) LastMeals(_ context.Context, _ string, n int) ([]Meal, error) {
+	if len(s.meals) < n {
+		return s.meals, nil
+	}
+	return s.meals[:n], nil
+}
+
+func (s *fakeStore) GramsSince(_ context.Context, _ string, since time.Time) (int64, error) {
+	var grams int64
+	for _, m := range s.meals {
+		if !m.EatenAt.Before(since) {
+			grams += 100
+		}
+	}
+	return grams, nil
+}
+
+func (s *fakeStore) LatestState(_ context.Context, ruleName string) (State, error) {
+	for i := len(s.transitions) - 1; i >= 0; i-- {
+		if s.transitions[i].RuleName == ruleName {
+			return s.transitions[i].State, nil
+		}
+	}
+	return "", nil
+}
+
+func (s *fakeStore) AddTransition(_ context.Context, a *Alert) error {
+	s.transitions = append(s.transitions, a)
+	a.ID = int64(len(s.transitions))
+	return nil
+}
+
+func (s *fakeStore) PendingTransitions(context.Context) ([]*Alert, error) {
+	var alerts []*Alert
+	for _, a := range s.transitions {
+		if !s.notified[a.ID] {
+			alerts = append(alerts, a)
+		}
+	}
+	return alerts, nil
+}
+
+func (s *fakeStore) MarkNotified(_ context.Context, id int64) error {
+	if s.notified == nil {
+		s.notified = map[int64]bool{}
+	}
+	s.notified[id] = true
+	return nil
+}
+
+func (s *fakeStore) TryLock(context.Context) (func(), bool, error) {
+	if s.locked {
+		return nil, false, nil
+	}
+	s.locked = true
+	return func() { s.locked = false }, true, nil
+}
+
+func (s *fakeStore) ListTransitions(context.Context, ListFilter) ([]*Alert, error) {
+	return s.transitions, nil
+}
+
+type recordingNotifier struct {
+	alerts []*Alert
+	err    error
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, a *Alert) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.alerts = append(n.alerts, a)
+	return nil
+}
+
+func TestEvaluator_Evaluate(t *testing.T) {
+	now := time.Date(2021, 12, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		rule      Rule
+		meals     []Meal
+		wantState State
+	}{
+		{
+			name:      "no meal fires",
+			rule:      Rule{Name: "r", Dog: "pochi", Type: RuleTypeNoMeal, Hours: 12},
+			meals:     []Meal{{"a", now.Add(-13 * time.Hour)}},
+			wantState: StateFiring,
+		},
+		{
+			name:  "no meal doesn't fire",
+			rule:  Rule{Name: "r", Dog: "pochi", Type: RuleTypeNoMeal, Hours: 12},
+			meals: []Meal{{"a", now.Add(-time.Hour)}},
+		},
+		{
+			name:      "never eaten fires",
+			rule:      Rule{Name: "r", Dog: "pochi", Type: RuleTypeNoMeal, Hours: 12},
+			wantState: StateFiring,
+		},
+		{
+			name:      "overfeeding fires",
+			rule:      Rule{Name: "r", Dog: "pochi", Type: RuleTypeDailyGramsAbove, Grams: 150},
+			meals:     []Meal{{"a", now.Add(-time.Hour)}, {"a", now.Add(-2 * time.Hour)}},
+			wantState: StateFiring,
+		},
+		{
+			name:  "meals older than a day are not counted",
+			rule:  Rule{Name: "r", Dog: "pochi", Type: RuleTypeDailyGramsAbove, Grams: 150},
+			meals: []Meal{{"a", now.Add(-time.Hour)}, {"a", now.Add(-25 * time.Hour)}},
+		},
+		{
+			name:      "underfeeding fires",
+			rule:      Rule{Name: "r", Dog: "pochi", Type: RuleTypeDailyGramsBelow, Grams: 150},
+			meals:     []Meal{{"a", now.Add(-time.Hour)}},
+			wantState: StateFiring,
+		},
+		{
+			name:      "brand switched fires",
+			rule:      Rule{Name: "r", Dog: "pochi", Type: RuleTypeBrandSwitched},
+			meals:     []Meal{{"b", now.Add(-time.Hour)}, {"a", now.Add(-2 * time.Hour)}},
+			wantState: StateFiring,
+		},
+		{
+			name:  "same brand doesn't fire",
+			rule:  Rule{Name: "r", Dog: "pochi", Type: RuleTypeBrandSwitched},
+			meals: []Meal{{"a", now.Add(-time.Hour)}, {"a", now.Add(-2 * time.Hour)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{meals: tt.meals}
+			n := &recordingNotifier{}
+			e := NewEvaluator(store, []Rule{tt.rule}, n, zap.NewNop())
+			e.now = func() time.Time { return now }
+			e.Evaluate(context.Background())
+
+			var got State
+			if len(store.transitions) > 0 {
+				got = store.transitions[len(store.transitions)-1].State
+			}
+			if got != tt.wantState {
+				t.Errorf("state = %q, want %q", got, tt.wantState)
+			}
+			if len(n.alerts) != len(store.transitions) {
+				t.Errorf("notified %d times, want %d", len(n.alerts), len(store.transitions))
+			}
+		})
+	}
+}
+
+func TestEvaluator_Transitions(t *testing.T) {
+	now := time.Date(2021, 12, 1, 12, 0, 0, 0, time.UTC)
+	store := &fakeStore{meals: []Meal{{"a", now.Add(-13 * time.Hour)}}}
+	n := &recordingNotifier{}
+	e := NewEvaluator(store, []Rule{{Name: "r", Dog: "pochi", Type: RuleTypeNoMeal, Hours: 12}}, n, zap.NewNop())
+	e.now = func() time.Time { return now }
+
+	// Firing is recorded once however many times the rule is evaluated.
+	e.Evaluate(context.Background())
+	e.Evaluate(context.Background())
+	store.meals = append([]Meal{{"a", now}}, store.meals...)
+	e.Evaluate(context.Background())
+
+	var got []State
+	for _, a := range n.alerts {
+		got = append(got, a.State)
+	}
+	if len(got) != 2 || got[0] != StateFiring || got[1] != StateResolved {
+		t.Errorf("transitions = %v, want [firing resolved]", got)
+	}
+}
+
+func TestEvaluator_RetryNotification(t *testing.T) {
+	now := time.Date(2021, 12, 1, 12, 0, 0, 0, time.UTC)
+	store := &fakeStore{meals: []Meal{{"a", now.Add(-13 * time.Hour)}}}
+	n := &recordingNotifier{err: errors.New("unavailable")}
+	e := NewEvaluator(store, []Rule{{Name: "r", Dog: "pochi", Type: RuleTypeNoMeal, Hours: 12}}, n, zap.NewNop())
+	e.now = func() time.Time { return now }
+
+	e.Evaluate(context.Background())
+	if len(store.transitions) != 1 || len(n.alerts) != 0 {
+		t.Fatalf("transitions = %d, notified = %d, want 1 and 0", len(store.transitions), len(n.alerts))
+	}
+
+	n.err = nil
+	e.Evaluate(context.Background())
+	if len(store.transitions) != 1 || len(n.alerts) != 1 || n.alerts[0].State != StateFiring {
+		t.Fatalf("transitions = %d, notified = %v, want 1 and [firing]", len(store.transitions), n.alerts)
+	}
+
+	e.Evaluate(context.Background())
+	if len(n.alerts) != 1 {
+		t.Errorf("notified = %d, want 1 not to notify twice", len(n.alerts))
+	}
+}
+
+func TestEvaluator_Locked(t *testing.T) {
+	now := time.Date(2021, 12, 1, 12, 0, 0, 0, time.UTC)
+	store := &fakeStore{meals: []Meal{{"a", now.Add(-13 * time.Hour)}}, locked: true}
+	n := &recordingNotifier{}
+	e := NewEvaluator(store, []Rule{{Name: "r", Dog: "pochi", Type: RuleTypeNoMeal, Hours: 12}}, n, zap.NewNop())
+	e.now = func() time.Time { return now }
+
+	e.Evaluate(context.Background())
+	if len(store.transitions) != 0 || len(n.alerts) != 0 {
+		t.Errorf("transitions = %d, notified = %d, want nothing while another replica holds the lock", len(store.transitions), len(n.alerts))
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	received := make(chan Alert, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a Alert
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			t.Errorf("failed to decode body: %v", err)
+		}
+		received <- a
+	}))
+	defer ts.Close()
+
+	n := &WebhookNotifier{URL: ts.URL}
+	want := Alert{RuleName: "r", Dog: "pochi", Type: RuleTypeNoMeal, State: StateFiring, Message: "hungry"}
+	if err := n.Notify(context.Background(), &want); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if got := <-received; got.RuleName != want.RuleName || got.State != want.State || got.Message != want.Message {
+		t.Errorf("received %+v, want %+v", got, want)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	if err := (&WebhookNotifier{URL: failing.URL}).Notify(context.Background(), &want); err == nil {
+		t.Error("Notify() error = nil, want error")
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			content: `[{"name": "a", "dog": "pochi", "type": "no_meal", "hours": 12}, {"name": "b", "dog": "pochi", "type": "brand_switched"}]`,
+		},
+		{
+			name:    "missing hours",
+			content: `[{"name": "a", "dog": "pochi", "type": "no_meal"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown type",
+			content: `[{"name": "a", "dog": "pochi", "type": "unknown"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "duplicated name",
+			content: `[{"name": "a", "dog": "pochi", "type": "brand_switched"}, {"name": "a", "dog": "hachi", "type": "brand_switched"}]`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadRules(path); (err != nil) != tt.wantErr {
+				t.Errorf("LoadRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
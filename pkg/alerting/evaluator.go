@@ -0,0 +1,155 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Evaluator evaluates rules on a schedule, records state transitions and notifies them.
+// Only one of replicas evaluates rules at once, and transitions are notified at least once,
+// because they are recorded as pending first and retried until notified.
+type Evaluator struct {
+	store    Store
+	rules    []Rule
+	notifier Notifier
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+// NewEvaluator returns Evaluator.
+func NewEvaluator(store Store, rules []Rule, notifier Notifier, logger *zap.Logger) *Evaluator {
+	return &Evaluator{
+		store:    store,
+		rules:    rules,
+		notifier: notifier,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Run evaluates rules every interval until ctx is done.
+func (e *Evaluator) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		e.Evaluate(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Evaluate evaluates all rules once unless another replica is evaluating them, and then notifies
+// pending transitions. Failures of a rule don't stop evaluation of the others.
+func (e *Evaluator) Evaluate(ctx context.Context) {
+	unlock, acquired, err := e.store.TryLock(ctx)
+	if err != nil {
+		e.logger.Error("failed to lock to evaluate rules", zap.Error(err))
+		return
+	}
+	if !acquired {
+		e.logger.Debug("skip evaluating rules, which another replica is evaluating")
+		return
+	}
+	defer unlock()
+
+	for _, r := range e.rules {
+		if err := e.evaluate(ctx, r); err != nil {
+			e.logger.Error("failed to evaluate rule", zap.String("rule", r.Name), zap.Error(err))
+		}
+	}
+	if err := e.notifyPending(ctx); err != nil {
+		e.logger.Error("failed to notify alerts, retry on the next evaluation", zap.Error(err))
+	}
+}
+
+// notifyPending notifies pending transitions in order, and stops at the first failure
+// so that transitions are not notified out of order.
+func (e *Evaluator) notifyPending(ctx context.Context) error {
+	alerts, err := e.store.PendingTransitions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, a := range alerts {
+		if err := e.notifier.Notify(ctx, a); err != nil {
+			return fmt.Errorf("failed to notify %s of rule %s: %w", a.State, a.RuleName, err)
+		}
+		if err := e.store.MarkNotified(ctx, a.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Evaluator) evaluate(ctx context.Context, r Rule) error {
+	now := e.now()
+	firing, message, err := e.check(ctx, r, now)
+	if err != nil {
+		return err
+	}
+	st := StateResolved
+	if firing {
+		st = StateFiring
+	}
+
+	latest, err := e.store.LatestState(ctx, r.Name)
+	if err != nil {
+		return err
+	}
+	// A rule which has never fired doesn't need to be resolved.
+	if latest == st || (latest == "" && st == StateResolved) {
+		return nil
+	}
+
+	a := &Alert{
+		RuleName:       r.Name,
+		Dog:            r.Dog,
+		Type:           r.Type,
+		State:          st,
+		Message:        message,
+		TransitionedAt: now,
+	}
+	return e.store.AddTransition(ctx, a)
+}
+
+func (e *Evaluator) check(ctx context.Context, r Rule, now time.Time) (bool, string, error) {
+	switch r.Type {
+	case RuleTypeNoMeal:
+		meals, err := e.store.LastMeals(ctx, r.Dog, 1)
+		if err != nil {
+			return false, "", err
+		}
+		if len(meals) == 0 {
+			return true, fmt.Sprintf("%s has never eaten", r.Dog), nil
+		}
+		since := now.Sub(meals[0].EatenAt)
+		return since >= time.Duration(r.Hours)*time.Hour,
+			fmt.Sprintf("%s has not eaten for %s", r.Dog, since.Truncate(time.Minute)), nil
+	case RuleTypeDailyGramsAbove, RuleTypeDailyGramsBelow:
+		grams, err := e.store.GramsSince(ctx, r.Dog, now.Add(-24*time.Hour))
+		if err != nil {
+			return false, "", err
+		}
+		message := fmt.Sprintf("%s ate %d grams in the last 24 hours, threshold is %d grams", r.Dog, grams, r.Grams)
+		if r.Type == RuleTypeDailyGramsAbove {
+			return grams > int64(r.Grams), message, nil
+		}
+		return grams < int64(r.Grams), message, nil
+	case RuleTypeBrandSwitched:
+		meals, err := e.store.LastMeals(ctx, r.Dog, 2)
+		if err != nil {
+			return false, "", err
+		}
+		if len(meals) < 2 {
+			return false, fmt.Sprintf("%s has not eaten twice", r.Dog), nil
+		}
+		return meals[0].DogfoodName != meals[1].DogfoodName,
+			fmt.Sprintf("%s switched from %s to %s", r.Dog, meals[1].DogfoodName, meals[0].DogfoodName), nil
+	}
+	return false, "", fmt.Errorf("type %s is not supported", r.Type)
+}
@@ -0,0 +1,76 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// Notifier delivers alert transitions.
+type Notifier interface {
+	Notify(ctx context.Context, a *Alert) error
+}
+
+// LogNotifier logs alert transitions.
+type LogNotifier struct {
+	Logger *zap.Logger
+}
+
+func (n *LogNotifier) Notify(_ context.Context, a *Alert) error {
+	n.Logger.Warn(
+		"alert transitioned",
+		zap.String("rule", a.RuleName),
+		zap.String("dog", a.Dog),
+		zap.String("type", string(a.Type)),
+		zap.String("state", string(a.State)),
+		zap.String("message", a.Message),
+	)
+	return nil
+}
+
+// WebhookNotifier posts alert transitions as JSON to URL.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, a *Alert) error {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c := n.Client
+	if c == nil {
+		c = http.DefaultClient
+	}
+	res, err := c.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post alert: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", res.Status)
+	}
+	return nil
+}
+
+// MultiNotifier delivers alert transitions to all notifiers.
+type MultiNotifier []Notifier
+
+func (ns MultiNotifier) Notify(ctx context.Context, a *Alert) error {
+	var err error
+	for _, n := range ns {
+		err = multierr.Append(err, n.Notify(ctx, a))
+	}
+	return err
+}
@@ -0,0 +1,80 @@
+package alerting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// RuleType is a type of a rule.
+type RuleType string
+
+const (
+	// RuleTypeNoMeal fires when a dog has not eaten for Hours.
+	RuleTypeNoMeal RuleType = "no_meal"
+	// RuleTypeDailyGramsAbove fires when a dog ate more than Grams in the last 24 hours.
+	RuleTypeDailyGramsAbove RuleType = "daily_grams_above"
+	// RuleTypeDailyGramsBelow fires when a dog ate less than Grams in the last 24 hours.
+	RuleTypeDailyGramsBelow RuleType = "daily_grams_below"
+	// RuleTypeBrandSwitched fires when the last meal of a dog is a different brand from the previous one.
+	RuleTypeBrandSwitched RuleType = "brand_switched"
+)
+
+// Rule is evaluated per dog.
+type Rule struct {
+	Name  string   `json:"name"`
+	Dog   string   `json:"dog"`
+	Type  RuleType `json:"type"`
+	Hours int      `json:"hours,omitempty"`
+	Grams int      `json:"grams,omitempty"`
+}
+
+// Validate validates r.
+func (r Rule) Validate() error {
+	if r.Name == "" {
+		return errors.New("name is missing")
+	}
+	if r.Dog == "" {
+		return fmt.Errorf("dog of %s is missing", r.Name)
+	}
+	switch r.Type {
+	case RuleTypeNoMeal:
+		if r.Hours <= 0 {
+			return fmt.Errorf("hours of %s must be positive", r.Name)
+		}
+	case RuleTypeDailyGramsAbove, RuleTypeDailyGramsBelow:
+		if r.Grams <= 0 {
+			return fmt.Errorf("grams of %s must be positive", r.Name)
+		}
+	case RuleTypeBrandSwitched:
+	default:
+		return fmt.Errorf("type %s of %s is not supported", r.Type, r.Name)
+	}
+	return nil
+}
+
+// LoadRules loads rules from a JSON file, e.g.
+//
+//	[{"name": "pochi-hungry", "dog": "pochi", "type": "no_meal", "hours": 12}]
+func LoadRules(path string) ([]Rule, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+	var rules []Rule
+	if err := json.Unmarshal(b, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse rules: %w", err)
+	}
+	names := make(map[string]struct{}, len(rules))
+	for _, r := range rules {
+		if err := r.Validate(); err != nil {
+			return nil, fmt.Errorf("rule is invalid: %w", err)
+		}
+		if _, ok := names[r.Name]; ok {
+			return nil, fmt.Errorf("rule %s is duplicated", r.Name)
+		}
+		names[r.Name] = struct{}{}
+	}
+	return rules, nil
+}
@@ -0,0 +1,184 @@
+package alerting
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// Store reads feeding records and persists alert transitions.
+type Store interface {
+	// LastMeals returns dogfood names and times of the last n meals of dog in descending order of time.
+	LastMeals(ctx context.Context, dog string, n int) ([]Meal, error)
+	// GramsSince returns total grams dog ate since.
+	GramsSince(ctx context.Context, dog string, since time.Time) (int64, error)
+	// LatestState returns the latest state of a rule, or an empty state if the rule has never transitioned.
+	LatestState(ctx context.Context, ruleName string) (State, error)
+	// AddTransition appends a transition with a new ID, which is pending until MarkNotified.
+	AddTransition(ctx context.Context, a *Alert) error
+	// PendingTransitions lists transitions which have not been notified in ascending order of ID.
+	PendingTransitions(ctx context.Context) ([]*Alert, error)
+	// MarkNotified marks a transition notified.
+	MarkNotified(ctx context.Context, id int64) error
+	// TryLock acquires a lock to evaluate rules unless another holds it, so that only one of replicas
+	// evaluates rules at once. unlock must be called if acquired.
+	TryLock(ctx context.Context) (unlock func(), acquired bool, err error)
+	// ListTransitions lists transitions in descending order of time.
+	ListTransitions(ctx context.Context, f ListFilter) ([]*Alert, error)
+}
+
+// Meal is a meal of a dog.
+type Meal struct {
+	DogfoodName string
+	EatenAt     time.Time
+}
+
+// evaluationLockKey is a key of an advisory lock to evaluate rules.
+const evaluationLockKey int64 = 0x646f67666f6f64 // dogfood
+
+type psqlStore struct {
+	db *sql.DB
+}
+
+// NewPsqlStore returns Store backed by the record and alert tables of Postgres.
+func NewPsqlStore(db *sql.DB) Store {
+	return &psqlStore{db}
+}
+
+func (s *psqlStore) LastMeals(ctx context.Context, dog string, n int) ([]Meal, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT dogfood_name, eaten_at FROM record WHERE dog_name = $1 AND deleted_at IS NULL ORDER BY eaten_at DESC LIMIT $2",
+		dog, n,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query last meals: %w", err)
+	}
+	defer rows.Close()
+	var meals []Meal
+	for rows.Next() {
+		var m Meal
+		if err := rows.Scan(&m.DogfoodName, &m.EatenAt); err != nil {
+			return nil, fmt.Errorf("failed to scan last meals: %w", err)
+		}
+		meals = append(meals, m)
+	}
+	return meals, rows.Err()
+}
+
+func (s *psqlStore) GramsSince(ctx context.Context, dog string, since time.Time) (int64, error) {
+	var grams int64
+	if err := s.db.QueryRowContext(
+		ctx,
+		"SELECT COALESCE(SUM(gram), 0) FROM record WHERE dog_name = $1 AND eaten_at >= $2 AND deleted_at IS NULL",
+		dog, since,
+	).Scan(&grams); err != nil {
+		return 0, fmt.Errorf("failed to sum grams: %w", err)
+	}
+	return grams, nil
+}
+
+func (s *psqlStore) LatestState(ctx context.Context, ruleName string) (State, error) {
+	var st State
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT state FROM alert WHERE rule_name = $1 ORDER BY transitioned_at DESC, id DESC LIMIT 1",
+		ruleName,
+	).Scan(&st)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query the latest state: %w", err)
+	}
+	return st, nil
+}
+
+func (s *psqlStore) AddTransition(ctx context.Context, a *Alert) error {
+	if err := s.db.QueryRowContext(
+		ctx,
+		`INSERT INTO alert (rule_name, dog_name, rule_type, state, message, transitioned_at, pending)
+		VALUES ($1, $2, $3, $4, $5, $6, true) RETURNING id`,
+		a.RuleName, a.Dog, a.Type, a.State, a.Message, a.TransitionedAt,
+	).Scan(&a.ID); err != nil {
+		return fmt.Errorf("failed to insert a transition: %w", err)
+	}
+	return nil
+}
+
+func (s *psqlStore) PendingTransitions(ctx context.Context) ([]*Alert, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT id, rule_name, dog_name, rule_type, state, message, transitioned_at FROM alert WHERE pending ORDER BY id",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending alerts: %w", err)
+	}
+	defer rows.Close()
+	var alerts []*Alert
+	for rows.Next() {
+		var a Alert
+		if err := rows.Scan(&a.ID, &a.RuleName, &a.Dog, &a.Type, &a.State, &a.Message, &a.TransitionedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan pending alerts: %w", err)
+		}
+		alerts = append(alerts, &a)
+	}
+	return alerts, rows.Err()
+}
+
+func (s *psqlStore) MarkNotified(ctx context.Context, id int64) error {
+	if _, err := s.db.ExecContext(ctx, "UPDATE alert SET pending = false WHERE id = $1", id); err != nil {
+		return fmt.Errorf("failed to mark an alert notified: %w", err)
+	}
+	return nil
+}
+
+// TryLock holds a session-level advisory lock on a dedicated connection, which is released
+// by Postgres if the replica dies while holding it.
+func (s *psqlStore) TryLock(ctx context.Context) (func(), bool, error) {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get a connection to lock: %w", err)
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", evaluationLockKey).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, false, fmt.Errorf("failed to lock: %w", err)
+	}
+	if !acquired {
+		conn.Close()
+		return nil, false, nil
+	}
+	return func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", evaluationLockKey); err != nil {
+			// The connection is discarded rather than reused, which ends the session holding the lock.
+			_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}, true, nil
+}
+
+func (s *psqlStore) ListTransitions(ctx context.Context, f ListFilter) ([]*Alert, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT rule_name, dog_name, rule_type, state, message, transitioned_at FROM alert
+		WHERE ($1 = '' OR dog_name = $1) AND ($2 = '' OR state = $2)
+		ORDER BY transitioned_at DESC, id DESC LIMIT $3`,
+		f.Dog, f.State, f.PageSize,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alerts: %w", err)
+	}
+	defer rows.Close()
+	var alerts []*Alert
+	for rows.Next() {
+		var a Alert
+		if err := rows.Scan(&a.RuleName, &a.Dog, &a.Type, &a.State, &a.Message, &a.TransitionedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan alerts: %w", err)
+		}
+		alerts = append(alerts, &a)
+	}
+	return alerts, rows.Err()
+}
@@ -0,0 +1,149 @@
+// Package client is a client of the dogfood backend, which wires up TLS, authentication,
+// retries and trace propagation once for all callers.
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"github.com/kei6u/dogfood/pkg/telemetry"
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// APIKeyHeader is metadata which carries an API key.
+	APIKeyHeader = "x-api-key"
+
+	defaultAddr = "localhost:50100"
+)
+
+// Client is dogfoodpb.DogFoodServiceClient on a connection owned by itself.
+type Client struct {
+	dogfoodpb.DogFoodServiceClient
+	conn *grpc.ClientConn
+}
+
+type options struct {
+	addr        string
+	tlsConfig   *tls.Config
+	apiKey      string
+	retry       retryPolicy
+	dialOptions []grpc.DialOption
+}
+
+// Option configures Client.
+type Option func(o *options)
+
+// WithAddress sets host:port of the backend, which defaults to localhost:50100.
+func WithAddress(addr string) Option {
+	return func(o *options) {
+		o.addr = addr
+	}
+}
+
+// WithTLS connects over TLS, which is plaintext by default. tlsconfig.Config.ClientTLSConfig builds c from files.
+func WithTLS(c *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = c
+	}
+}
+
+// WithAPIKey sends key in APIKeyHeader on every RPC.
+func WithAPIKey(key string) Option {
+	return func(o *options) {
+		o.apiKey = key
+	}
+}
+
+// WithRetry retries idempotent unary RPCs failed with Unavailable up to maxAttempts in total, waiting initial
+// backoff doubling up to maxBackoff with jitter. maxAttempts of 1 disables retries.
+func WithRetry(maxAttempts int, initialBackoff, maxBackoff time.Duration) Option {
+	return func(o *options) {
+		o.retry.maxAttempts = maxAttempts
+		o.retry.initialBackoff = initialBackoff
+		o.retry.maxBackoff = maxBackoff
+	}
+}
+
+// WithRetryNonIdempotent retries non-idempotent RPCs too, e.g. CreateRecord and CreateWebhookSubscription.
+// They may be applied twice if the backend becomes unavailable after applying them.
+func WithRetryNonIdempotent() Option {
+	return func(o *options) {
+		o.retry.nonIdempotent = true
+	}
+}
+
+// WithDialOptions appends options to grpc.Dial, e.g. a custom dialer.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+// New returns Client. The connection is established lazily, so New doesn't block.
+func New(ctx context.Context, opts ...Option) (*Client, error) {
+	o := &options{
+		addr:  defaultAddr,
+		retry: defaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	creds := insecure.NewCredentials()
+	if o.tlsConfig != nil {
+		creds = credentials.NewTLS(o.tlsConfig)
+	}
+	unary := []grpc.UnaryClientInterceptor{
+		// Every attempt is traced as a child of the caller's span.
+		o.retry.unaryClientInterceptor(),
+		telemetry.UnaryClientInterceptor(),
+	}
+	stream := []grpc.StreamClientInterceptor{telemetry.StreamClientInterceptor()}
+	if o.apiKey != "" {
+		unary = append(unary, apiKeyUnaryClientInterceptor(o.apiKey))
+		stream = append(stream, apiKeyStreamClientInterceptor(o.apiKey))
+	}
+	dialOpts := append([]grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(unary...),
+		grpc.WithChainStreamInterceptor(stream...),
+	}, o.dialOptions...)
+
+	conn, err := grpc.DialContext(ctx, o.addr, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %w", o.addr, err)
+	}
+	return &Client{
+		DogFoodServiceClient: dogfoodpb.NewDogFoodServiceClient(conn),
+		conn:                 conn,
+	}, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Records returns an iterator over records matching req.
+func (c *Client) Records(ctx context.Context, req *dogfoodpb.ListRecordsRequest) *RecordIterator {
+	return NewRecordIterator(ctx, c, req)
+}
+
+func apiKeyUnaryClientInterceptor(key string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(metadata.AppendToOutgoingContext(ctx, APIKeyHeader, key), method, req, reply, cc, opts...)
+	}
+}
+
+func apiKeyStreamClientInterceptor(key string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(metadata.AppendToOutgoingContext(ctx, APIKeyHeader, key), desc, cc, method, opts...)
+	}
+}
@@ -0,0 +1,260 @@
+package client
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kei6u/dogfood/pkg/telemetry"
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type fakeServer struct {
+	dogfoodpb.UnimplementedDogFoodServiceServer
+
+	mu sync.Mutex
+	// unavailable is how many times RPCs fail with Unavailable before succeeding.
+	unavailable  int
+	calls        int
+	apiKeys      []string
+	traceparents []string
+	records      []*dogfoodpb.Record // in ascending order of eaten_at
+}
+
+func (s *fakeServer) CreateRecord(ctx context.Context, req *dogfoodpb.CreateRecordRequest) (*dogfoodpb.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.apiKeys = append(s.apiKeys, md.Get(APIKeyHeader)...)
+	s.traceparents = append(s.traceparents, md.Get("traceparent")...)
+	if s.unavailable > 0 {
+		s.unavailable--
+		return nil, status.Error(codes.Unavailable, "backend is not ready")
+	}
+	if req.GetGram() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "gram must be positive")
+	}
+	return &dogfoodpb.Record{DogfoodName: req.GetDogfoodName(), Gram: req.GetGram(), DogName: req.GetDogName(), EatenAt: timestamppb.Now()}, nil
+}
+
+// ListRecords behaves like the backend, whose from is inclusive.
+func (s *fakeServer) ListRecords(_ context.Context, req *dogfoodpb.ListRecordsRequest) (*dogfoodpb.ListRecordsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.unavailable > 0 {
+		s.unavailable--
+		return nil, status.Error(codes.Unavailable, "backend is not ready")
+	}
+	var rs []*dogfoodpb.Record
+	for _, r := range s.records {
+		t := r.GetEatenAt().AsTime()
+		if !t.Before(req.GetFrom().AsTime()) && t.Before(req.GetTo().AsTime()) && len(rs) < int(req.GetPageSize()) {
+			rs = append(rs, r)
+		}
+	}
+	if len(rs) == 0 {
+		return nil, status.Error(codes.NotFound, "no record are found")
+	}
+	return &dogfoodpb.ListRecordsResponse{Records: rs, To: rs[len(rs)-1].GetEatenAt()}, nil
+}
+
+func newTestClient(t *testing.T, s *fakeServer, opts ...Option) *Client {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	dogfoodpb.RegisterDogFoodServiceServer(gs, s)
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	opts = append(opts, WithAddress("bufnet"), WithDialOptions(grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	})))
+	c, err := New(context.Background(), opts...)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestClient_Retry(t *testing.T) {
+	tests := []struct {
+		name          string
+		unavailable   int
+		gram          int32
+		nonIdempotent bool
+		wantCode      codes.Code
+		wantCalls     int
+	}{
+		{
+			name:          "succeeds after retries",
+			unavailable:   2,
+			gram:          100,
+			nonIdempotent: true,
+			wantCode:      codes.OK,
+			wantCalls:     3,
+		},
+		{
+			name:          "gives up after max attempts",
+			unavailable:   5,
+			gram:          100,
+			nonIdempotent: true,
+			wantCode:      codes.Unavailable,
+			wantCalls:     3,
+		},
+		{
+			name:          "doesn't retry other errors",
+			nonIdempotent: true,
+			wantCode:      codes.InvalidArgument,
+			wantCalls:     1,
+		},
+		{
+			name:        "doesn't retry non-idempotent methods by default",
+			unavailable: 2,
+			gram:        100,
+			wantCode:    codes.Unavailable,
+			wantCalls:   1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &fakeServer{unavailable: tt.unavailable}
+			opts := []Option{WithRetry(3, time.Millisecond, 10*time.Millisecond), WithAPIKey("secret")}
+			if tt.nonIdempotent {
+				opts = append(opts, WithRetryNonIdempotent())
+			}
+			c := newTestClient(t, s, opts...)
+			_, err := c.CreateRecord(context.Background(), &dogfoodpb.CreateRecordRequest{DogfoodName: "a", Gram: tt.gram, DogName: "pochi"})
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("CreateRecord() error = %v, want %s", err, tt.wantCode)
+			}
+			if s.calls != tt.wantCalls {
+				t.Errorf("CreateRecord is called %d times, want %d", s.calls, tt.wantCalls)
+			}
+			for _, k := range s.apiKeys {
+				if k != "secret" {
+					t.Errorf("API key = %s, want secret", k)
+				}
+			}
+			if len(s.apiKeys) != tt.wantCalls {
+				t.Errorf("API key is sent %d times, want %d", len(s.apiKeys), tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestClient_RetryIdempotent(t *testing.T) {
+	now := time.Now()
+	s := &fakeServer{unavailable: 2, records: []*dogfoodpb.Record{{DogfoodName: "a", EatenAt: timestamppb.New(now)}}}
+	c := newTestClient(t, s, WithRetry(3, time.Millisecond, 10*time.Millisecond))
+	_, err := c.ListRecords(context.Background(), &dogfoodpb.ListRecordsRequest{
+		From:     timestamppb.New(now.Add(-time.Hour)),
+		To:       timestamppb.New(now.Add(time.Hour)),
+		PageSize: 10,
+	})
+	if err != nil {
+		t.Errorf("ListRecords() error = %v", err)
+	}
+	if s.calls != 3 {
+		t.Errorf("ListRecords is called %d times, want 3", s.calls)
+	}
+}
+
+func TestClient_TracePropagation(t *testing.T) {
+	stop, err := telemetry.Start(context.Background(), telemetry.BackendStdout, "dogfood-client-test")
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() {
+		stop(context.Background())
+		telemetry.Start(context.Background(), telemetry.BackendNone, "dogfood-client-test")
+	})
+
+	s := &fakeServer{unavailable: 1}
+	c := newTestClient(t, s, WithRetry(2, time.Millisecond, 10*time.Millisecond), WithRetryNonIdempotent())
+	span, ctx := telemetry.StartSpanFromContext(context.Background(), "test", "CreateRecord")
+	defer span.Finish()
+	if _, err := c.CreateRecord(ctx, &dogfoodpb.CreateRecordRequest{DogfoodName: "a", Gram: 100, DogName: "pochi"}); err != nil {
+		t.Fatalf("CreateRecord() error = %v", err)
+	}
+
+	// Every attempt carries the caller's trace ID.
+	traceID := trace.SpanContextFromContext(ctx).TraceID().String()
+	if len(s.traceparents) != 2 {
+		t.Fatalf("traceparent is sent %d times, want 2", len(s.traceparents))
+	}
+	for _, tp := range s.traceparents {
+		if !strings.Contains(tp, traceID) {
+			t.Errorf("traceparent = %s, want trace ID %s", tp, traceID)
+		}
+	}
+}
+
+func TestRecordIterator(t *testing.T) {
+	start := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+	s := &fakeServer{}
+	for i := 0; i < 7; i++ {
+		s.records = append(s.records, &dogfoodpb.Record{DogName: "pochi", DogfoodName: "a", Gram: int32(i + 1), EatenAt: timestamppb.New(start.Add(time.Duration(i) * time.Minute))})
+	}
+	// Records at the boundary of pages must be neither skipped nor duplicated.
+	s.records = append(s.records[:3], append([]*dogfoodpb.Record{
+		{DogName: "hachi", DogfoodName: "a", Gram: 100, EatenAt: s.records[2].GetEatenAt()},
+	}, s.records[3:]...)...)
+	c := newTestClient(t, s)
+
+	tests := []struct {
+		name string
+		req  *dogfoodpb.ListRecordsRequest
+		want int
+	}{
+		{
+			name: "all pages",
+			req:  &dogfoodpb.ListRecordsRequest{From: timestamppb.New(start), To: timestamppb.New(start.Add(time.Hour)), PageSize: 3},
+			want: 8,
+		},
+		{
+			name: "page size equal to records",
+			req:  &dogfoodpb.ListRecordsRequest{From: timestamppb.New(start), To: timestamppb.New(start.Add(time.Hour)), PageSize: 8},
+			want: 8,
+		},
+		{
+			name: "no records",
+			req:  &dogfoodpb.ListRecordsRequest{From: timestamppb.New(start.Add(time.Hour)), To: timestamppb.New(start.Add(2 * time.Hour))},
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := c.Records(context.Background(), tt.req)
+			seen := map[string]bool{}
+			n := 0
+			for it.Next() {
+				r := it.Record()
+				key := r.GetDogName() + r.GetEatenAt().AsTime().String()
+				if seen[key] {
+					t.Errorf("record %s is duplicated", key)
+				}
+				seen[key] = true
+				n++
+			}
+			if err := it.Err(); err != nil {
+				t.Fatalf("Err() = %v", err)
+			}
+			if n != tt.want {
+				t.Errorf("iterated %d records, want %d", n, tt.want)
+			}
+		})
+	}
+}
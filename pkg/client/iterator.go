@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const defaultPageSize = 100
+
+// RecordLister lists records, which is implemented by dogfoodpb.DogFoodServiceClient.
+type RecordLister interface {
+	ListRecords(ctx context.Context, in *dogfoodpb.ListRecordsRequest, opts ...grpc.CallOption) (*dogfoodpb.ListRecordsResponse, error)
+}
+
+// RecordIterator iterates over records in ascending order of eaten_at, fetching a page at a time.
+//
+//	it := c.Records(ctx, req)
+//	for it.Next() {
+//		r := it.Record()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type RecordIterator struct {
+	ctx    context.Context
+	lister RecordLister
+	req    *dogfoodpb.ListRecordsRequest
+
+	page []*dogfoodpb.Record
+	cur  *dogfoodpb.Record
+	done bool
+	err  error
+	// seen holds records at the cursor, because from of ListRecords is inclusive.
+	seen map[recordKey]struct{}
+}
+
+type recordKey struct {
+	dogName     string
+	dogfoodName string
+	eatenAt     int64
+}
+
+// NewRecordIterator returns RecordIterator over records matching req. page_size of req is a page size,
+// which defaults to 100, and to of req defaults to now.
+func NewRecordIterator(ctx context.Context, lister RecordLister, req *dogfoodpb.ListRecordsRequest) *RecordIterator {
+	r := &dogfoodpb.ListRecordsRequest{
+		From:     req.GetFrom(),
+		To:       req.GetTo(),
+		PageSize: req.GetPageSize(),
+	}
+	if r.PageSize <= 0 {
+		r.PageSize = defaultPageSize
+	}
+	if r.To == nil {
+		r.To = timestamppb.Now()
+	}
+	return &RecordIterator{
+		ctx:    ctx,
+		lister: lister,
+		req:    r,
+		seen:   make(map[recordKey]struct{}),
+	}
+}
+
+// Next advances to the next record, and returns false when there are no more records or an error occurs.
+func (it *RecordIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		it.fetch()
+	}
+	it.cur, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Record returns the current record.
+func (it *RecordIterator) Record() *dogfoodpb.Record {
+	return it.cur
+}
+
+// Err returns an error which stopped the iteration.
+func (it *RecordIterator) Err() error {
+	return it.err
+}
+
+func (it *RecordIterator) fetch() {
+	res, err := it.lister.ListRecords(it.ctx, it.req)
+	if status.Code(err) == codes.NotFound {
+		it.done = true
+		return
+	}
+	if err != nil {
+		it.err = err
+		return
+	}
+	rs := res.GetRecords()
+	// A page shorter than requested is the last one.
+	if len(rs) < int(it.req.GetPageSize()) {
+		it.done = true
+	}
+	cursor := it.req.GetFrom().AsTime()
+	for _, r := range rs {
+		eatenAt := r.GetEatenAt().AsTime()
+		key := recordKey{r.GetDogName(), r.GetDogfoodName(), eatenAt.UnixNano()}
+		if _, ok := it.seen[key]; ok {
+			continue
+		}
+		if eatenAt.After(cursor) {
+			cursor = eatenAt
+			it.seen = make(map[recordKey]struct{})
+		}
+		it.seen[key] = struct{}{}
+		it.page = append(it.page, r)
+	}
+	// A full page of records at the cursor can't be paged further.
+	if len(it.page) == 0 {
+		it.done = true
+	}
+	it.req.From = timestamppb.New(cursor)
+}
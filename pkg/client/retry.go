@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var defaultRetryPolicy = retryPolicy{
+	maxAttempts:    4,
+	initialBackoff: 100 * time.Millisecond,
+	maxBackoff:     2 * time.Second,
+}
+
+// idempotentMethods are retried by default, because applying them twice has the same effect as once.
+// The others, e.g. CreateRecord, may be applied twice if the backend becomes unavailable after applying them,
+// so they are retried only by WithRetryNonIdempotent.
+var idempotentMethods = map[string]bool{
+	"/dogfoodpb.v1.DogFoodService/UpdateRecord":              true,
+	"/dogfoodpb.v1.DogFoodService/DeleteRecord":              true,
+	"/dogfoodpb.v1.DogFoodService/RestoreRecord":             true,
+	"/dogfoodpb.v1.DogFoodService/ListAuditEvents":           true,
+	"/dogfoodpb.v1.DogFoodService/ListRecords":               true,
+	"/dogfoodpb.v1.DogFoodService/ListAlerts":                true,
+	"/dogfoodpb.v1.DogFoodService/GetWebhookSubscription":    true,
+	"/dogfoodpb.v1.DogFoodService/ListWebhookSubscriptions":  true,
+	"/dogfoodpb.v1.DogFoodService/UpdateWebhookSubscription": true,
+	"/dogfoodpb.v1.DogFoodService/DeleteWebhookSubscription": true,
+	"/dogfoodpb.v1.DogFoodService/ListWebhookDeliveries":     true,
+}
+
+type retryPolicy struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	nonIdempotent  bool
+}
+
+func (p retryPolicy) retryable(method string) bool {
+	return p.nonIdempotent || idempotentMethods[method]
+}
+
+// backoff returns a delay before the attempt after attempts, with full jitter.
+func (p retryPolicy) backoff(attempts int) time.Duration {
+	d := p.initialBackoff
+	for i := 1; i < attempts && d < p.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.maxBackoff {
+		d = p.maxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
+
+func (p retryPolicy) unaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if !p.retryable(method) {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		for attempts := 1; ; attempts++ {
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if status.Code(err) != codes.Unavailable || attempts >= p.maxAttempts {
+				return err
+			}
+			t := time.NewTimer(p.backoff(attempts))
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return err
+			case <-t.C:
+			}
+		}
+	}
+}
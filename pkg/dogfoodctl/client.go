@@ -0,0 +1,56 @@
+package dogfoodctl
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+
+	"github.com/kei6u/dogfood/pkg/client"
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"google.golang.org/grpc"
+)
+
+// recordClient is a subset of dogfoodpb.DogFoodServiceClient used by dogfoodctl,
+// which is also implemented over the REST gateway.
+type recordClient interface {
+	client.RecordLister
+	CreateRecord(ctx context.Context, in *dogfoodpb.CreateRecordRequest, opts ...grpc.CallOption) (*dogfoodpb.Record, error)
+}
+
+var _ recordClient = dogfoodpb.DogFoodServiceClient(nil)
+
+// newClient returns a client by c.Transport, and a function to close it.
+// TLS and the API key are used by both transports.
+func newClient(ctx context.Context, c *Config) (recordClient, func() error, error) {
+	var clientTLS *tls.Config
+	if tc := c.TLS.tlsconfig(); tc != nil {
+		var err error
+		if clientTLS, err = tc.ClientTLSConfig(); err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize TLS config: %w", err)
+		}
+	}
+	if c.Transport == TransportREST {
+		var hc *http.Client
+		if clientTLS != nil {
+			t := http.DefaultTransport.(*http.Transport).Clone()
+			t.TLSClientConfig = clientTLS
+			hc = &http.Client{Transport: t}
+		}
+		rc := newRESTClient(c.Addr, hc)
+		rc.apiKey = c.APIKey
+		return rc, func() error { return nil }, nil
+	}
+	opts := []client.Option{client.WithAddress(c.Addr)}
+	if clientTLS != nil {
+		opts = append(opts, client.WithTLS(clientTLS))
+	}
+	if c.APIKey != "" {
+		opts = append(opts, client.WithAPIKey(c.APIKey))
+	}
+	cl, err := client.New(ctx, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cl, cl.Close, nil
+}
@@ -0,0 +1,182 @@
+package dogfoodctl
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/kei6u/dogfood/pkg/tlsconfig"
+)
+
+const (
+	TransportGRPC = "grpc"
+	TransportREST = "rest"
+
+	defaultGRPCAddr = "localhost:50100"
+	defaultRESTAddr = "http://localhost:50001"
+	defaultTimeout  = 10 * time.Second
+)
+
+// Config is connection settings, which are read from a config file, overridden by
+// DOGFOODCTL_* environment variables, and then by flags.
+type Config struct {
+	// Addr is host:port of the backend for gRPC, or a URL of the gateway for REST.
+	Addr      string     `json:"addr"`
+	Transport string     `json:"transport"`
+	Output    string     `json:"output"`
+	Timeout   Duration   `json:"timeout"`
+	TLS       *TLSConfig `json:"tls,omitempty"`
+	// APIKey is sent to the backend as x-api-key metadata over gRPC, or X-Api-Key header over REST.
+	APIKey string `json:"apiKey,omitempty"`
+}
+
+// Duration is time.Duration written like 10s in a config file.
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like 10s: %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// TLSConfig configures TLS of gRPC, and of REST when Addr is https.
+type TLSConfig struct {
+	CAFile     string `json:"caFile"`
+	CertFile   string `json:"certFile"`
+	KeyFile    string `json:"keyFile"`
+	ServerName string `json:"serverName"`
+}
+
+func (c *TLSConfig) tlsconfig() *tlsconfig.Config {
+	if c == nil {
+		return nil
+	}
+	return &tlsconfig.Config{
+		CAFile:     c.CAFile,
+		CertFile:   c.CertFile,
+		KeyFile:    c.KeyFile,
+		ServerName: c.ServerName,
+	}
+}
+
+// defaultConfigPath returns $HOME/.dogfoodctl.yaml.
+func defaultConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".dogfoodctl.yaml")
+}
+
+// loadConfig reads a config file at path, or at DOGFOODCTL_CONFIG or the default path if path is empty.
+// A missing file at the default path is not an error.
+func loadConfig(path string) (*Config, error) {
+	c := &Config{Output: formatTable, Timeout: Duration{defaultTimeout}}
+	explicit := true
+	if path == "" {
+		path = os.Getenv("DOGFOODCTL_CONFIG")
+	}
+	if path == "" {
+		path = defaultConfigPath()
+		explicit = false
+	}
+	if path != "" {
+		b, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist) && !explicit:
+		case err != nil:
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		default:
+			if err := yaml.Unmarshal(b, c); err != nil {
+				return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+			}
+		}
+	}
+
+	if v := os.Getenv("DOGFOODCTL_ADDR"); v != "" {
+		c.Addr = v
+	}
+	if v := os.Getenv("DOGFOODCTL_TRANSPORT"); v != "" {
+		c.Transport = v
+	}
+	if v := os.Getenv("DOGFOODCTL_OUTPUT"); v != "" {
+		c.Output = v
+	}
+	if v := os.Getenv("DOGFOODCTL_API_KEY"); v != "" {
+		c.APIKey = v
+	}
+	if v := os.Getenv("DOGFOODCTL_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("DOGFOODCTL_TIMEOUT is invalid: %w", err)
+		}
+		c.Timeout = Duration{d}
+	}
+	return c, nil
+}
+
+// commonFlags are flags of all commands, which override Config.
+type commonFlags struct {
+	config    string
+	addr      string
+	transport string
+	output    string
+}
+
+func (f *commonFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.config, "config", "", "path to a config file (default $DOGFOODCTL_CONFIG or ~/.dogfoodctl.yaml)")
+	fs.StringVar(&f.addr, "addr", "", "host:port of the backend for grpc, or a URL of the gateway for rest")
+	fs.StringVar(&f.transport, "transport", "", "grpc or rest (default grpc)")
+	fs.StringVar(&f.output, "o", "", "output format, table, json or yaml (default table)")
+}
+
+// resolve returns Config overridden by flags.
+func (f *commonFlags) resolve() (*Config, error) {
+	c, err := loadConfig(f.config)
+	if err != nil {
+		return nil, err
+	}
+	if f.addr != "" {
+		c.Addr = f.addr
+	}
+	if f.transport != "" {
+		c.Transport = f.transport
+	}
+	if f.output != "" {
+		c.Output = f.output
+	}
+	if c.Transport == "" {
+		c.Transport = TransportGRPC
+	}
+	if c.Addr == "" {
+		c.Addr = defaultGRPCAddr
+		if c.Transport == TransportREST {
+			c.Addr = defaultRESTAddr
+		}
+	}
+	switch c.Transport {
+	case TransportGRPC, TransportREST:
+	default:
+		return nil, fmt.Errorf("transport %s is not supported, it must be grpc or rest", c.Transport)
+	}
+	switch c.Output {
+	case formatTable, formatJSON, formatYAML:
+	default:
+		return nil, fmt.Errorf("output %s is not supported, it must be table, json or yaml", c.Output)
+	}
+	return c, nil
+}
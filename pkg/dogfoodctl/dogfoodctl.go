@@ -0,0 +1,289 @@
+// Package dogfoodctl implements a command-line client of dogfood, which talks to the backend
+// over gRPC or to the gateway over REST.
+package dogfoodctl
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kei6u/dogfood/pkg/client"
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultListSince    = 24 * time.Hour
+	defaultListLimit    = 100
+	defaultTailInterval = 5 * time.Second
+	listPageSize        = 100
+	tailPageSize        = 100
+)
+
+const usage = `Usage: dogfoodctl COMMAND [flags]
+
+Commands:
+  create  create a record
+  list    list records
+  tail    print records as they are created
+
+Connection settings are read from a config file, DOGFOODCTL_ADDR, DOGFOODCTL_TRANSPORT,
+DOGFOODCTL_OUTPUT, DOGFOODCTL_API_KEY and DOGFOODCTL_TIMEOUT, and flags in this order.
+Run 'dogfoodctl COMMAND -h' for flags of a command.
+`
+
+// Run runs a command by args, and returns an exit code.
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	cmds := map[string]func(context.Context, []string, io.Writer, io.Writer) error{
+		"create": runCreate,
+		"list":   runList,
+		"tail":   runTail,
+	}
+	cmd, ok := cmds[args[0]]
+	if !ok {
+		if args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+			fmt.Fprint(stdout, usage)
+			return 0
+		}
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+	if err := cmd(ctx, args[1:], stdout, stderr); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		fmt.Fprintf(stderr, "error: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+var errUsage = errors.New("usage error")
+
+func newFlagSet(name string, stderr io.Writer) (*flag.FlagSet, *commonFlags) {
+	fs := flag.NewFlagSet("dogfoodctl "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var cf commonFlags
+	cf.register(fs)
+	return fs, &cf
+}
+
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected arguments: %v\n", fs.Args())
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+func runCreate(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs, cf := newFlagSet("create", stderr)
+	dog := fs.String("dog", "", "name of dog (required)")
+	dogfood := fs.String("dogfood", "", "name of dogfood brand (required)")
+	gram := fs.Int("gram", 0, "grams dog ate (required)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *dog == "" || *dogfood == "" || *gram <= 0 {
+		fmt.Fprintln(stderr, "-dog, -dogfood and positive -gram are required")
+		fs.Usage()
+		return errUsage
+	}
+	c, err := cf.resolve()
+	if err != nil {
+		return err
+	}
+	cl, closeClient, err := newClient(ctx, c)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout.Duration)
+	defer cancel()
+	r, err := cl.CreateRecord(ctx, &dogfoodpb.CreateRecordRequest{
+		DogName:     *dog,
+		DogfoodName: *dogfood,
+		Gram:        int32(*gram),
+	})
+	if err != nil {
+		return err
+	}
+	p := newPrinter(stdout, c.Output)
+	if err := p.Print(r); err != nil {
+		return err
+	}
+	return p.Flush()
+}
+
+func runList(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs, cf := newFlagSet("list", stderr)
+	from := fs.String("from", "", "start time of eaten_at in RFC 3339, or duration before now like 1h (default 24h)")
+	to := fs.String("to", "", "end time of eaten_at in RFC 3339, or duration before now (default now)")
+	limit := fs.Int("limit", defaultListLimit, "maximum number of records")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	now := time.Now()
+	fromTime, err := parseTime(*from, now, now.Add(-defaultListSince))
+	if err != nil {
+		return fmt.Errorf("-from is invalid: %w", err)
+	}
+	toTime, err := parseTime(*to, now, now)
+	if err != nil {
+		return fmt.Errorf("-to is invalid: %w", err)
+	}
+	c, err := cf.resolve()
+	if err != nil {
+		return err
+	}
+	cl, closeClient, err := newClient(ctx, c)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout.Duration)
+	defer cancel()
+	pageSize := *limit
+	if pageSize > listPageSize {
+		pageSize = listPageSize
+	}
+	it := client.NewRecordIterator(ctx, cl, &dogfoodpb.ListRecordsRequest{
+		From:     timestamppb.New(fromTime),
+		To:       timestamppb.New(toTime),
+		PageSize: int32(pageSize),
+	})
+	p := newPrinter(stdout, c.Output)
+	for n := 0; n < *limit && it.Next(); n++ {
+		if err := p.Print(it.Record()); err != nil {
+			return err
+		}
+	}
+	if err := p.Flush(); err != nil {
+		return err
+	}
+	return it.Err()
+}
+
+func runTail(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs, cf := newFlagSet("tail", stderr)
+	interval := fs.Duration("interval", defaultTailInterval, "polling interval, which should be longer over the rate-limited gateway")
+	since := fs.Duration("since", 0, "print records created within the duration before starting")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	c, err := cf.resolve()
+	if err != nil {
+		return err
+	}
+	cl, closeClient, err := newClient(ctx, c)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	t := newTailer(cl, time.Now().Add(-*since), c.Timeout.Duration)
+	p := newPrinter(stdout, c.Output)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		rs, err := t.poll(ctx)
+		switch status.Code(err) {
+		case codes.OK:
+		case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded:
+			fmt.Fprintf(stderr, "warning: %s, retry in %s\n", err, *interval)
+		case codes.Canceled:
+			return nil
+		default:
+			return err
+		}
+		for _, r := range rs {
+			if err := p.Print(r); err != nil {
+				return err
+			}
+		}
+		if err := p.Flush(); err != nil {
+			return err
+		}
+		// Tables are printed row by row, since rows can't be aligned with future ones.
+		p.tw = nil
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// tailer polls records created after a cursor.
+type tailer struct {
+	lister  client.RecordLister
+	cursor  time.Time
+	timeout time.Duration
+	// seen holds records at the cursor, because from of ListRecords is inclusive.
+	seen map[string]struct{}
+}
+
+func newTailer(lister client.RecordLister, since time.Time, timeout time.Duration) *tailer {
+	return &tailer{lister: lister, cursor: since, timeout: timeout, seen: map[string]struct{}{}}
+}
+
+// poll returns records created since the last poll in order of eaten_at.
+func (t *tailer) poll(ctx context.Context) ([]*dogfoodpb.Record, error) {
+	pctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	it := client.NewRecordIterator(pctx, t.lister, &dogfoodpb.ListRecordsRequest{
+		From:     timestamppb.New(t.cursor),
+		PageSize: tailPageSize,
+	})
+	var rs []*dogfoodpb.Record
+	for it.Next() {
+		r := it.Record()
+		eatenAt := r.GetEatenAt().AsTime()
+		key := fmt.Sprintf("%s\x00%s\x00%d", r.GetDogName(), r.GetDogfoodName(), eatenAt.UnixNano())
+		if _, ok := t.seen[key]; ok {
+			continue
+		}
+		if eatenAt.After(t.cursor) {
+			t.cursor = eatenAt
+			t.seen = map[string]struct{}{}
+		}
+		t.seen[key] = struct{}{}
+		rs = append(rs, r)
+	}
+	if err := it.Err(); err != nil && ctx.Err() != nil {
+		return rs, status.FromContextError(ctx.Err()).Err()
+	}
+	return rs, it.Err()
+}
+
+// parseTime parses RFC 3339, or duration before now. It returns def if s is empty.
+func parseTime(s string, now, def time.Time) (time.Time, error) {
+	if s == "" {
+		return def, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,227 @@
+package dogfoodctl
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestRESTClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/dogfood/record":
+			b, _ := io.ReadAll(r.Body)
+			if want := `{"dogfoodName":"a","gram":100,"dogName":"pochi"}`; string(bytes.ReplaceAll(b, []byte(" "), nil)) != want {
+				t.Errorf("body = %s, want %s", b, want)
+			}
+			io.WriteString(w, `{"dogfoodName":"a","gram":100,"dogName":"pochi","eatenAt":"2021-12-01T08:00:00Z","unknown":1}`)
+		case "/v1/dogfood/records":
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"code":5,"message":"no record are found","details":[]}`)
+		default:
+			http.Error(w, "exceeds rate limit", http.StatusTooManyRequests)
+		}
+	}))
+	defer ts.Close()
+
+	c := newRESTClient(ts.URL+"/", nil)
+	r, err := c.CreateRecord(context.Background(), &dogfoodpb.CreateRecordRequest{DogfoodName: "a", Gram: 100, DogName: "pochi"})
+	if err != nil {
+		t.Fatalf("CreateRecord() error = %v", err)
+	}
+	if r.GetDogName() != "pochi" || !r.GetEatenAt().AsTime().Equal(time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("CreateRecord() = %v", r)
+	}
+
+	if _, err := c.ListRecords(context.Background(), &dogfoodpb.ListRecordsRequest{}); status.Code(err) != codes.NotFound {
+		t.Errorf("ListRecords() error = %v, want NotFound", err)
+	}
+
+	c.baseURL = ts.URL + "/ratelimited"
+	if _, err := c.ListRecords(context.Background(), &dogfoodpb.ListRecordsRequest{}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("ListRecords() error = %v, want ResourceExhausted", err)
+	}
+}
+
+func TestNewClient_REST(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Api-Key"); got != "secret" {
+			t.Errorf("X-Api-Key = %q, want secret", got)
+		}
+		io.WriteString(w, `{"records":[]}`)
+	}))
+	defer ts.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, closeClient, err := newClient(context.Background(), &Config{
+		Addr:      ts.URL,
+		Transport: TransportREST,
+		TLS:       &TLSConfig{CAFile: caFile},
+		APIKey:    "secret",
+	})
+	if err != nil {
+		t.Fatalf("newClient() error = %v", err)
+	}
+	defer closeClient()
+	// The server is trusted only by the CA file.
+	if _, err := c.ListRecords(context.Background(), &dogfoodpb.ListRecordsRequest{}); err != nil {
+		t.Errorf("ListRecords() error = %v", err)
+	}
+}
+
+type fakeClient struct {
+	records []*dogfoodpb.Record // in ascending order of eaten_at
+}
+
+func (c *fakeClient) CreateRecord(context.Context, *dogfoodpb.CreateRecordRequest, ...grpc.CallOption) (*dogfoodpb.Record, error) {
+	return nil, status.Error(codes.Unimplemented, "not implemented")
+}
+
+func (c *fakeClient) ListRecords(_ context.Context, in *dogfoodpb.ListRecordsRequest, _ ...grpc.CallOption) (*dogfoodpb.ListRecordsResponse, error) {
+	var rs []*dogfoodpb.Record
+	for _, r := range c.records {
+		t := r.GetEatenAt().AsTime()
+		if !t.Before(in.GetFrom().AsTime()) && t.Before(in.GetTo().AsTime()) && len(rs) < int(in.GetPageSize()) {
+			rs = append(rs, r)
+		}
+	}
+	if len(rs) == 0 {
+		return nil, status.Error(codes.NotFound, "no record are found")
+	}
+	return &dogfoodpb.ListRecordsResponse{Records: rs, To: rs[len(rs)-1].GetEatenAt()}, nil
+}
+
+func TestTailer_Poll(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	record := func(dog string, d time.Duration) *dogfoodpb.Record {
+		return &dogfoodpb.Record{DogName: dog, DogfoodName: "a", Gram: 1, EatenAt: timestamppb.New(start.Add(d))}
+	}
+	c := &fakeClient{}
+	// More records than a page, some of which share eaten_at.
+	for i := 0; i < tailPageSize+10; i++ {
+		c.records = append(c.records, record("pochi", time.Duration(i)*time.Second))
+	}
+	c.records = append(c.records, record("hachi", time.Duration(tailPageSize+9)*time.Second))
+
+	tl := newTailer(c, start, time.Second)
+	rs, err := tl.poll(context.Background())
+	if err != nil {
+		t.Fatalf("poll() error = %v", err)
+	}
+	if len(rs) != len(c.records) {
+		t.Errorf("poll() returned %d records, want %d", len(rs), len(c.records))
+	}
+
+	rs, err = tl.poll(context.Background())
+	if err != nil || len(rs) != 0 {
+		t.Errorf("poll() = %d records, %v, want no records", len(rs), err)
+	}
+
+	c.records = append(c.records, record("hachi", time.Duration(tailPageSize+20)*time.Second))
+	rs, err = tl.poll(context.Background())
+	if err != nil || len(rs) != 1 {
+		t.Errorf("poll() = %d records, %v, want 1 record", len(rs), err)
+	}
+}
+
+func TestCommonFlags_Resolve(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	config := "addr: backend:50100\noutput: yaml\ntimeout: 3s\ntls:\n  caFile: ca.pem\n"
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DOGFOODCTL_OUTPUT", "json")
+
+	tests := []struct {
+		name  string
+		flags commonFlags
+		want  Config
+	}{
+		{
+			name:  "env overrides file",
+			flags: commonFlags{config: path},
+			want:  Config{Addr: "backend:50100", Transport: TransportGRPC, Output: formatJSON, Timeout: Duration{3 * time.Second}},
+		},
+		{
+			name:  "flags override env",
+			flags: commonFlags{config: path, addr: "http://gateway:50001", transport: TransportREST, output: formatTable},
+			want:  Config{Addr: "http://gateway:50001", Transport: TransportREST, Output: formatTable, Timeout: Duration{3 * time.Second}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.flags.resolve()
+			if err != nil {
+				t.Fatalf("resolve() error = %v", err)
+			}
+			if got.Addr != tt.want.Addr || got.Transport != tt.want.Transport || got.Output != tt.want.Output || got.Timeout != tt.want.Timeout {
+				t.Errorf("resolve() = %+v, want %+v", got, tt.want)
+			}
+			if got.TLS == nil || got.TLS.CAFile != "ca.pem" {
+				t.Errorf("TLS = %+v, want caFile ca.pem", got.TLS)
+			}
+		})
+	}
+
+	if _, err := (&commonFlags{config: path, transport: "http"}).resolve(); err == nil {
+		t.Error("resolve() error = nil, want error for unsupported transport")
+	}
+}
+
+func TestPrinter(t *testing.T) {
+	rs := []*dogfoodpb.Record{
+		{DogName: "pochi", DogfoodName: "a", Gram: 100, EatenAt: timestamppb.New(time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC))},
+		{DogName: "hachi", DogfoodName: "b", Gram: 50, EatenAt: timestamppb.New(time.Date(2021, 12, 1, 9, 0, 0, 0, time.UTC))},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: formatYAML,
+			want: "dogName: pochi\ndogfoodName: a\neatenAt: \"2021-12-01T08:00:00Z\"\ngram: 100\n" +
+				"---\n" +
+				"dogName: hachi\ndogfoodName: b\neatenAt: \"2021-12-01T09:00:00Z\"\ngram: 50\n",
+		},
+		{
+			format: formatTable,
+			want: fmt.Sprintf("DOG    DOGFOOD  GRAM  EATEN AT\npochi  a        100   %s\nhachi  b        50    %s\n",
+				rs[0].GetEatenAt().AsTime().Local().Format(time.RFC3339),
+				rs[1].GetEatenAt().AsTime().Local().Format(time.RFC3339)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			p := newPrinter(&buf, tt.format)
+			for _, r := range rs {
+				if err := p.Print(r); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := p.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("printed\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package dogfoodctl
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ghodss/yaml"
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+// printer prints records. Records printed one by one, e.g. by tail, are JSON lines or YAML documents.
+type printer struct {
+	w       io.Writer
+	format  string
+	tw      *tabwriter.Writer
+	printed int
+}
+
+func newPrinter(w io.Writer, format string) *printer {
+	return &printer{w: w, format: format}
+}
+
+// Print prints a record.
+func (p *printer) Print(r *dogfoodpb.Record) error {
+	defer func() { p.printed++ }()
+	switch p.format {
+	case formatJSON:
+		b, err := protojson.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.w, "%s\n", b)
+		return err
+	case formatYAML:
+		b, err := protojson.Marshal(r)
+		if err != nil {
+			return err
+		}
+		y, err := yaml.JSONToYAML(b)
+		if err != nil {
+			return err
+		}
+		if p.printed > 0 {
+			if _, err := io.WriteString(p.w, "---\n"); err != nil {
+				return err
+			}
+		}
+		_, err = p.w.Write(y)
+		return err
+	}
+	if p.tw == nil {
+		p.tw = tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(p.tw, "DOG\tDOGFOOD\tGRAM\tEATEN AT")
+	}
+	fmt.Fprintf(p.tw, "%s\t%s\t%d\t%s\n", r.GetDogName(), r.GetDogfoodName(), r.GetGram(), r.GetEatenAt().AsTime().Local().Format(time.RFC3339))
+	return nil
+}
+
+// Flush writes buffered rows of a table.
+func (p *printer) Flush() error {
+	if p.tw == nil {
+		return nil
+	}
+	return p.tw.Flush()
+}
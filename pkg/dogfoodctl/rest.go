@@ -0,0 +1,126 @@
+package dogfoodctl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// restAPIKeyHeader is forwarded as API key metadata by the gRPC gateway.
+const restAPIKeyHeader = "X-Api-Key"
+
+// restClient calls the REST gateway. Errors are converted to gRPC status like grpc-gateway does reversely.
+type restClient struct {
+	baseURL string
+	hc      *http.Client
+	// apiKey is sent as X-Api-Key header if it's set.
+	apiKey string
+}
+
+func newRESTClient(baseURL string, hc *http.Client) *restClient {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return &restClient{baseURL: strings.TrimSuffix(baseURL, "/"), hc: hc}
+}
+
+func (c *restClient) CreateRecord(ctx context.Context, in *dogfoodpb.CreateRecordRequest, _ ...grpc.CallOption) (*dogfoodpb.Record, error) {
+	out := &dogfoodpb.Record{}
+	if err := c.post(ctx, "/v1/dogfood/record", in, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *restClient) ListRecords(ctx context.Context, in *dogfoodpb.ListRecordsRequest, _ ...grpc.CallOption) (*dogfoodpb.ListRecordsResponse, error) {
+	out := &dogfoodpb.ListRecordsResponse{}
+	if err := c.post(ctx, "/v1/dogfood/records", in, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *restClient) post(ctx context.Context, path string, in, out proto.Message) error {
+	b, err := protojson.Marshal(in)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to encode request: %s", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(b))
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to build request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set(restAPIKeyHeader, c.apiKey)
+	}
+	res, err := c.hc.Do(req)
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "failed to call %s: %s", path, err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "failed to read response: %s", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return restError(res.StatusCode, body)
+	}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(body, out); err != nil {
+		return status.Errorf(codes.Internal, "failed to decode response: %s", err)
+	}
+	return nil
+}
+
+// restError converts an error body of grpc-gateway, or a plain text error of the gateway, e.g. rate limiting.
+func restError(code int, body []byte) error {
+	var e struct {
+		Code    int32  `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &e); err == nil && e.Code != 0 {
+		return status.Error(codes.Code(e.Code), e.Message)
+	}
+	msg := strings.TrimSpace(string(body))
+	if msg == "" {
+		msg = http.StatusText(code)
+	}
+	return status.Error(codeFromHTTPStatus(code), fmt.Sprintf("%d: %s", code, msg))
+}
+
+func codeFromHTTPStatus(code int) codes.Code {
+	switch code {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	if code >= 500 {
+		return codes.Internal
+	}
+	return codes.Unknown
+}
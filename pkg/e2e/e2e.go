@@ -0,0 +1,169 @@
+// Package e2e boots the dogfood backend and gateway in-process, so that tests drive full HTTP flows
+// through the rate limiting gateway, the gRPC gateway and the gRPC server without Docker.
+// Records are stored in memory, and rate of requests is limited in memory unless WithLimiter is given.
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	redisrate "github.com/go-redis/redis_rate/v9"
+	"github.com/kei6u/dogfood/pkg/gateway"
+	"github.com/kei6u/dogfood/pkg/store"
+	protov1 "github.com/kei6u/dogfood/proto/v1"
+	"go.uber.org/zap"
+)
+
+// stopTimeout is a deadline to drain servers on cleanup.
+const stopTimeout = 5 * time.Second
+
+// Harness is the backend and the gateway running in-process.
+type Harness struct {
+	// GatewayURL is a base URL of the rate limiting gateway, e.g. http://127.0.0.1:12345.
+	GatewayURL string
+	// BackendURL is a base URL of the gRPC gateway of the backend, which is not rate limited.
+	BackendURL string
+	// GRPCAddr is an address of the gRPC server of the backend.
+	GRPCAddr string
+	// AdminURL is a base URL of the admin server of the backend.
+	AdminURL string
+	// Store is where the backend stores records.
+	Store store.Store
+}
+
+type config struct {
+	store   store.Store
+	limiter gateway.Limiter
+	limit   redisrate.Limit
+	logger  *zap.Logger
+	apiKeys map[string]string
+}
+
+type Option func(*config)
+
+// WithStore stores records in st instead of memory.
+func WithStore(st store.Store) Option {
+	return func(c *config) {
+		c.store = st
+	}
+}
+
+// WithLimiter limits rate of requests by l, e.g. redis_rate.Limiter with miniredis.
+func WithLimiter(l gateway.Limiter) Option {
+	return func(c *config) {
+		c.limiter = l
+	}
+}
+
+// WithLimit sets a rate limit of the gateway. It defaults to 60 requests per hour.
+func WithLimit(limit redisrate.Limit) Option {
+	return func(c *config) {
+		c.limit = limit
+	}
+}
+
+// WithLogger sets a logger of servers. Nothing is logged by default.
+func WithLogger(l *zap.Logger) Option {
+	return func(c *config) {
+		c.logger = l
+	}
+}
+
+// WithAPIKeys authenticates callers of the backend by API keys by their names.
+func WithAPIKeys(keys map[string]string) Option {
+	return func(c *config) {
+		c.apiKeys = keys
+	}
+}
+
+// Start boots the backend and the gateway on ephemeral ports, which are stopped when t finishes.
+func Start(t testing.TB, opts ...Option) *Harness {
+	t.Helper()
+	c := &config{
+		limit:  redisrate.PerHour(60),
+		logger: zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.store == nil {
+		c.store = store.NewMemoryStore()
+	}
+	if c.limiter == nil {
+		c.limiter = gateway.NewMemoryLimiter()
+	}
+
+	ls, err := listen()
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s, err := protov1.NewServer(
+		protov1.WithLogger(c.logger.Named("backend")),
+		protov1.WithStore(c.store),
+		protov1.WithGRPCAddr(ls.GRPC.Addr().String()),
+		protov1.WithGRPCGatewayAddr(ls.GRPCGateway.Addr().String()),
+		protov1.WithAPIKeys(c.apiKeys),
+	)
+	if err != nil {
+		closeListeners(ls)
+		t.Fatalf("failed to initialize backend: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(ctx, ls) }()
+	t.Cleanup(func() {
+		ctx, stopCancel := context.WithTimeout(context.Background(), stopTimeout)
+		defer stopCancel()
+		if err := s.Stop(ctx); err != nil {
+			t.Errorf("failed to stop backend: %v", err)
+		}
+		cancel()
+		<-served
+	})
+
+	h := &Harness{
+		BackendURL: fmt.Sprintf("http://%s", ls.GRPCGateway.Addr()),
+		GRPCAddr:   ls.GRPC.Addr().String(),
+		AdminURL:   fmt.Sprintf("http://%s", ls.Admin.Addr()),
+		Store:      c.store,
+	}
+
+	gw := gateway.New(c.limiter, c.logger.Named("gateway"), gateway.WithLimit(c.limit))
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	t.Cleanup(transport.CloseIdleConnections)
+	if err := gw.RegisterReverseProxy(h.BackendURL, transport, gateway.BackendPatterns); err != nil {
+		t.Fatalf("failed to register a reverse proxy: %v", err)
+	}
+	hc := gateway.NewHealthChecker(&http.Client{Transport: transport}, h.BackendURL)
+	ts := httptest.NewServer(gw.Handler(hc, func() bool { return false }))
+	t.Cleanup(ts.Close)
+	h.GatewayURL = ts.URL
+	return h
+}
+
+// listen binds listeners of the backend on the loopback interface.
+func listen() (*protov1.Listeners, error) {
+	var ls protov1.Listeners
+	for _, l := range []*net.Listener{&ls.Admin, &ls.GRPC, &ls.GRPCGateway} {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			closeListeners(&ls)
+			return nil, err
+		}
+		*l = lis
+	}
+	return &ls, nil
+}
+
+func closeListeners(ls *protov1.Listeners) {
+	for _, l := range []net.Listener{ls.Admin, ls.GRPC, ls.GRPCGateway} {
+		if l != nil {
+			l.Close()
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package e2e
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	redisrate "github.com/go-redis/redis_rate/v9"
+	"github.com/kei6u/dogfood/pkg/admin"
+	"github.com/kei6u/dogfood/pkg/gateway"
+	"github.com/kei6u/dogfood/pkg/requestid"
+)
+
+func do(t *testing.T, method, url, body string) (*http.Response, string) {
+	t.Helper()
+	return doAs(t, "", method, url, body)
+}
+
+// doAs sends a request with apiKey unless it's empty.
+func doAs(t *testing.T, apiKey, method, url, body string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to build a request: %v", err)
+	}
+	if apiKey != "" {
+		req.Header.Set("X-Api-Key", apiKey)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s error = %v", method, url, err)
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("failed to read a response: %v", err)
+	}
+	return res, string(b)
+}
+
+func TestHarness_Records(t *testing.T) {
+	h := Start(t)
+	from := time.Now().Add(-time.Minute).Format(time.RFC3339)
+
+	for _, dog := range []string{"pochi", "hachi"} {
+		res, body := do(t, http.MethodPost, h.GatewayURL+gateway.CreateRecordRequestURI, `{"dogfood_name":"a","gram":100,"dog_name":"`+dog+`"}`)
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("CreateRecord status = %d, body = %s", res.StatusCode, body)
+		}
+		if res.Header.Get("X-RateLimit-Remaining") == "" {
+			t.Error("X-RateLimit-Remaining is missing")
+		}
+	}
+
+	res, body := do(t, http.MethodPost, h.GatewayURL+gateway.ListRecordsRequestURI, `{"from":"`+from+`","to":"`+time.Now().Add(time.Minute).Format(time.RFC3339)+`","page_size":10}`)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("ListRecords status = %d, body = %s", res.StatusCode, body)
+	}
+	var list struct {
+		Records []struct {
+			DogName string `json:"dogName"`
+		} `json:"records"`
+	}
+	if err := json.Unmarshal([]byte(body), &list); err != nil {
+		t.Fatalf("failed to decode ListRecords response %s: %v", body, err)
+	}
+	if len(list.Records) != 2 || list.Records[0].DogName != "pochi" || list.Records[1].DogName != "hachi" {
+		t.Errorf("ListRecords = %s, want pochi and hachi in order", body)
+	}
+
+	res, body = do(t, http.MethodPost, h.GatewayURL+gateway.ListRecordsRequestURI, `{"from":"2000-01-01T00:00:00Z","to":"2000-01-02T00:00:00Z","page_size":10}`)
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("ListRecords of no records status = %d, body = %s, want 404", res.StatusCode, body)
+	}
+
+	res, body = do(t, http.MethodGet, h.GatewayURL+gateway.ExportRecordsRequestURI+"?dog_name=hachi&from="+from, "")
+	if res.StatusCode != http.StatusOK || strings.Count(body, "hachi") != 1 || strings.Contains(body, "pochi") {
+		t.Errorf("ExportRecords status = %d, body = %s, want only hachi", res.StatusCode, body)
+	}
+}
+
+func TestHarness_Corrections(t *testing.T) {
+	h := Start(t, WithAPIKeys(map[string]string{"alice": "alice-key", "bob": "bob-key"}))
+
+	res, body := doAs(t, "alice-key", http.MethodPost, h.GatewayURL+gateway.CreateRecordRequestURI, `{"dogfood_name":"a","gram":100,"dog_name":"pochi"}`)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("CreateRecord status = %d, body = %s", res.StatusCode, body)
+	}
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal([]byte(body), &created); err != nil || created.ID == "" {
+		t.Fatalf("CreateRecord = %s, want an ID", body)
+	}
+	recordURL := h.GatewayURL + gateway.RecordRequestURIPrefix + created.ID
+
+	tests := []struct {
+		name   string
+		apiKey string
+		method string
+		url    string
+		body   string
+		want   int
+	}{
+		{"update without API key", "", http.MethodPatch, recordURL, `{"gram":80}`, http.StatusUnauthorized},
+		{"purge with invalid API key", "mallory-key", http.MethodDelete, recordURL + "?purge=true", "", http.StatusUnauthorized},
+		{"update", "bob-key", http.MethodPatch, recordURL, `{"gram":80}`, http.StatusOK},
+		{"update invalid", "bob-key", http.MethodPatch, recordURL, `{"gram":-1}`, http.StatusBadRequest},
+		{"update missing", "bob-key", http.MethodPatch, h.GatewayURL + gateway.RecordRequestURIPrefix + "999", `{"gram":80}`, http.StatusNotFound},
+		{"delete", "bob-key", http.MethodDelete, recordURL, "", http.StatusOK},
+		{"delete deleted", "bob-key", http.MethodDelete, recordURL, "", http.StatusNotFound},
+		{"restore", "bob-key", http.MethodPost, recordURL + ":restore", "{}", http.StatusOK},
+		{"restore restored", "bob-key", http.MethodPost, recordURL + ":restore", "{}", http.StatusNotFound},
+		{"purge", "bob-key", http.MethodDelete, recordURL + "?purge=true", "", http.StatusOK},
+		{"restore purged", "bob-key", http.MethodPost, recordURL + ":restore", "{}", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		res, body := doAs(t, tt.apiKey, tt.method, tt.url, tt.body)
+		if res.StatusCode != tt.want {
+			t.Errorf("%s status = %d, body = %s, want %d", tt.name, res.StatusCode, body, tt.want)
+		}
+	}
+
+	res, body = do(t, http.MethodGet, h.GatewayURL+gateway.AuditEventsRequestURI+"?record_id="+created.ID, "")
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("ListAuditEvents without API key status = %d, body = %s, want 401", res.StatusCode, body)
+	}
+	res, body = doAs(t, "alice-key", http.MethodGet, h.GatewayURL+gateway.AuditEventsRequestURI+"?record_id="+created.ID, "")
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("ListAuditEvents status = %d, body = %s", res.StatusCode, body)
+	}
+	var list struct {
+		Events []struct {
+			Action string `json:"action"`
+			Actor  string `json:"actor"`
+		} `json:"events"`
+	}
+	if err := json.Unmarshal([]byte(body), &list); err != nil {
+		t.Fatalf("failed to decode ListAuditEvents response %s: %v", body, err)
+	}
+	var history []string
+	for _, e := range list.Events {
+		history = append(history, e.Action+" by "+e.Actor)
+	}
+	want := "purge by bob, restore by bob, delete by bob, update by bob, create by alice"
+	if got := strings.Join(history, ", "); got != want {
+		t.Errorf("ListAuditEvents = %s, want %s", got, want)
+	}
+}
+
+func TestHarness_RequestID(t *testing.T) {
+	h := Start(t, WithAPIKeys(map[string]string{"alice": "alice-key"}))
+
+	req, err := http.NewRequest(http.MethodPatch, h.GatewayURL+gateway.RecordRequestURIPrefix+"999", strings.NewReader(`{"gram":80}`))
+	if err != nil {
+		t.Fatalf("failed to build a request: %v", err)
+	}
+	req.Header.Set(requestid.Header, "abc-123")
+	req.Header.Set("X-Api-Key", "alice-key")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("UpdateRecord error = %v", err)
+	}
+	defer res.Body.Close()
+	b, _ := io.ReadAll(res.Body)
+	if got := res.Header.Values(requestid.Header); len(got) != 1 || got[0] != "abc-123" {
+		t.Errorf("%s = %v, want abc-123 once", requestid.Header, got)
+	}
+	if res.StatusCode != http.StatusNotFound || !strings.Contains(string(b), `"requestId":"abc-123"`) {
+		t.Errorf("UpdateRecord status = %d, body = %s, want 404 with the request ID in details", res.StatusCode, b)
+	}
+
+	res, body := do(t, http.MethodGet, h.GatewayURL+gateway.LivenessProbeRequestURI, "")
+	if res.StatusCode != http.StatusOK || len(res.Header.Get(requestid.Header)) != 32 {
+		t.Errorf("livenessProbe status = %d, body = %s, %s = %q, want a generated one", res.StatusCode, body, requestid.Header, res.Header.Get(requestid.Header))
+	}
+}
+
+func TestHarness_RateLimit(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to run miniredis: %v", err)
+	}
+	defer s.Close()
+	r := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer r.Close()
+
+	tests := []struct {
+		name    string
+		limiter gateway.Limiter
+	}{
+		{
+			name:    "memory",
+			limiter: gateway.NewMemoryLimiter(),
+		},
+		{
+			name:    "redis",
+			limiter: redisrate.NewLimiter(r),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Start(t, WithLimiter(tt.limiter), WithLimit(redisrate.PerHour(2)))
+			body := `{"from":"2000-01-01T00:00:00Z","to":"2000-01-02T00:00:00Z","page_size":10}`
+			for i := 0; i < 2; i++ {
+				if res, body := do(t, http.MethodPost, h.GatewayURL+gateway.ListRecordsRequestURI, body); res.StatusCode != http.StatusNotFound {
+					t.Fatalf("request %d status = %d, body = %s, want 404", i, res.StatusCode, body)
+				}
+			}
+			res, _ := do(t, http.MethodPost, h.GatewayURL+gateway.ListRecordsRequestURI, body)
+			if res.StatusCode != http.StatusTooManyRequests {
+				t.Errorf("status = %d, want 429", res.StatusCode)
+			}
+			if res.Header.Get("X-RateLimit-Reset") == "" {
+				t.Error("X-RateLimit-Reset is missing")
+			}
+			// Limits are per pattern.
+			if res, body := do(t, http.MethodPost, h.GatewayURL+gateway.CreateRecordRequestURI, `{"dogfood_name":"a","gram":100,"dog_name":"pochi"}`); res.StatusCode != http.StatusOK {
+				t.Errorf("CreateRecord status = %d, body = %s, want 200", res.StatusCode, body)
+			}
+		})
+	}
+}
+
+func TestHarness_Health(t *testing.T) {
+	h := Start(t)
+	for _, u := range []string{
+		h.GatewayURL + gateway.LivenessProbeRequestURI,
+		h.GatewayURL + gateway.ReadinessProbeRequestURI,
+		h.GatewayURL + gateway.StartupProbeRequestURI,
+		h.BackendURL + gateway.ReadinessProbeRequestURI,
+		h.AdminURL + admin.MetricsPath,
+		h.AdminURL + admin.BuildInfoPath,
+	} {
+		if res, body := do(t, http.MethodGet, u, ""); res.StatusCode != http.StatusOK {
+			t.Errorf("GET %s status = %d, body = %s", u, res.StatusCode, body)
+		}
+	}
+
+	res, body := do(t, http.MethodGet, h.GatewayURL+gateway.HealthReportRequestURI, "")
+	var report struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(body), &report); err != nil {
+		t.Fatalf("failed to decode a health report %s: %v", body, err)
+	}
+	if res.StatusCode != http.StatusOK || report.Status != "ok" {
+		t.Errorf("health report status = %d, body = %s", res.StatusCode, body)
+	}
+
+	// Subsystems persisted in Postgres are not available with the in-memory store.
+	if res, body := do(t, http.MethodGet, h.GatewayURL+gateway.ListAlertsRequestURI, ""); res.StatusCode != http.StatusNotImplemented {
+		t.Errorf("ListAlerts status = %d, body = %s, want 501", res.StatusCode, body)
+	}
+}
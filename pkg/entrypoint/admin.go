@@ -0,0 +1,40 @@
+package entrypoint
+
+import (
+	"net/http"
+
+	"github.com/kei6u/dogfood/pkg/admin"
+	"github.com/kei6u/dogfood/pkg/lifecycle"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// startAdmin serves the admin server on ADMIN_ADDR in background, which is drained by lc.
+// It doesn't fail the process, because the admin server is only for operators.
+func startAdmin(logger *zap.Logger, lc *lifecycle.Manager, opts ...admin.Option) {
+	s := admin.NewServer(admin.AddrFromEnv(), opts...)
+	lc.Drain("admin server", lifecycle.HTTPServer(s))
+	go func() {
+		logger.Info("admin server has started", zap.String("addr", s.Addr))
+		if err := s.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("failed to listen and serve admin server", zap.Error(err))
+		}
+	}()
+}
+
+// startMetrics serves metrics gathered by g on METRICS_ADDR in background, which is drained by lc.
+// It's not served if METRICS_ADDR is not set.
+func startMetrics(logger *zap.Logger, lc *lifecycle.Manager, g prometheus.Gatherer) {
+	addr := admin.MetricsAddrFromEnv()
+	if addr == "" {
+		return
+	}
+	s := admin.NewMetricsServer(addr, g)
+	lc.Drain("metrics server", lifecycle.HTTPServer(s))
+	go func() {
+		logger.Info("metrics server has started", zap.String("addr", s.Addr))
+		if err := s.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("failed to listen and serve metrics server", zap.Error(err))
+		}
+	}()
+}
@@ -0,0 +1,186 @@
+package entrypoint
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/kei6u/dogfood/pkg/admin"
+	"github.com/kei6u/dogfood/pkg/gateway"
+	"github.com/kei6u/dogfood/pkg/lifecycle"
+	"github.com/kei6u/dogfood/pkg/logging"
+	"github.com/kei6u/dogfood/pkg/store"
+	"github.com/kei6u/dogfood/pkg/tlsconfig"
+	protov1 "github.com/kei6u/dogfood/proto/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	defaultAllInOneAddr = "8080"
+	bufconnSize         = 1 << 20
+	// inMemoryBackendAddr is a URL of the gRPC gateway of the backend for the rate limiting gateway,
+	// whose host is never resolved because connections are made in memory.
+	inMemoryBackendAddr = "http://backend"
+)
+
+// RunDogfood runs a subcommand of dogfood.
+func RunDogfood() {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s COMMAND\n\nCommands:\n  all-in-one  run the gateway and the backend in a single process with in-memory records\n", os.Args[0])
+	}
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "all-in-one":
+		runAllInOne()
+	case "-h", "-help", "--help", "help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+}
+
+// newAllInOne wires the rate limiting gateway, the gRPC gateway and the gRPC server in memory, and returns
+// the backend server to start and a handler of the gateway. The gRPC server listens in memory unless grpcAddr
+// is specified, and opts configure the backend further.
+func newAllInOne(logger *zap.Logger, grpcAddr string, shuttingDown func() bool, opts ...protov1.Option) (*protov1.Server, http.Handler, error) {
+	gwLis := bufconn.Listen(bufconnSize)
+	ls := protov1.Listeners{GRPCGateway: gwLis}
+	// Metrics of the gateway and the backend are served together by the admin server of the backend.
+	registry := prometheus.NewRegistry()
+	opts = append([]protov1.Option{
+		protov1.WithLogger(logger.Named("backend")),
+		protov1.WithStore(store.NewMemoryStore()),
+		protov1.WithMetricsRegistry(registry),
+	}, opts...)
+	if grpcAddr != "" {
+		opts = append(opts, protov1.WithGRPCAddr(grpcAddr))
+	} else {
+		grpcLis := bufconn.Listen(bufconnSize)
+		ls.GRPC = grpcLis
+		opts = append(opts, protov1.WithGatewayDialOptions(grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return grpcLis.DialContext(ctx)
+		})))
+	}
+	s, err := protov1.NewServer(append(opts, protov1.WithListeners(ls))...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	backendTransport := http.DefaultTransport.(*http.Transport).Clone()
+	backendTransport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return gwLis.DialContext(ctx)
+	}
+	gw := gateway.New(gateway.NewMemoryLimiter(), logger.Named("gateway"))
+	if err := gw.RegisterReverseProxy(inMemoryBackendAddr, backendTransport, gateway.BackendPatterns); err != nil {
+		return nil, nil, fmt.Errorf("failed to register a revere proxy to gateway: %w", err)
+	}
+	if err := gw.RegisterMetrics(registry); err != nil {
+		return nil, nil, fmt.Errorf("failed to register metrics of gateway: %w", err)
+	}
+	hc := gateway.NewHealthChecker(&http.Client{Transport: backendTransport}, inMemoryBackendAddr)
+	return s, gw.Handler(hc, shuttingDown), nil
+}
+
+// runAllInOne runs the rate limiting gateway, the gRPC gateway and the gRPC server in one process for demos
+// and small installs. They are connected in memory, records are stored in memory and rate of requests is
+// limited in memory, so neither Redis nor Postgres is required.
+// ADDR is a port of the gateway, which defaults to 8080, and GRPC_ADDR exposes the gRPC server if specified.
+func runAllInOne() {
+	logger, level, err := logging.NewFromEnv()
+	if err != nil {
+		logger.Fatal("exit due to invalid logger config", zap.Error(err))
+	}
+	defer logger.Sync()
+
+	lcOpts, err := lifecycle.OptionsFromEnv()
+	if err != nil {
+		logger.Fatal("exit due to invalid shutdown config", zap.Error(err))
+	}
+	lc := lifecycle.New(logger, lcOpts...)
+
+	stopTelemetry := startTelemetry(logger)
+
+	serverTLS, err := tlsconfig.FromEnv("TLS")
+	if err != nil {
+		logger.Fatal("exit due to invalid TLS config", zap.Error(err))
+	}
+
+	apiKeys, err := protov1.APIKeysFromEnv()
+	if err != nil {
+		logger.Fatal("exit due to invalid API keys", zap.Error(err))
+	}
+
+	opts := []protov1.Option{
+		protov1.WithAdminAddr(admin.AddrFromEnv()),
+		protov1.WithMetricsAddr(admin.MetricsAddrFromEnv()),
+		protov1.WithAdminOptions(admin.WithLogLevel(level)),
+		protov1.WithAPIKeys(apiKeys),
+	}
+	s, handler, err := newAllInOne(logger, os.Getenv("GRPC_ADDR"), lc.ShuttingDown, opts...)
+	if err != nil {
+		logger.Fatal("exit due to a failure of initializeing dogfood all-in-one", zap.Error(err))
+	}
+
+	addr := getEnvOrDefault("ADDR", defaultAllInOneAddr)
+	hs := &http.Server{
+		Addr:    fmt.Sprintf(":%s", addr),
+		Handler: handler,
+	}
+	if serverTLS != nil {
+		if hs.TLSConfig, err = serverTLS.ServerTLSConfig(); err != nil {
+			logger.Fatal("exit due to invalid TLS config", zap.Error(err))
+		}
+	}
+
+	// The gateway is drained before the backend, because it forwards requests to the backend.
+	lc.OnNotReady(s.MarkNotReady)
+	lc.Drain("dogfood all-in-one", func(ctx context.Context) error {
+		return multierr.Append(lifecycle.HTTPServer(hs)(ctx), s.Stop(ctx))
+	})
+	lc.Close("telemetry", stopTelemetry)
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, os.Kill, syscall.SIGINT, syscall.SIGTERM)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errc := make(chan error, 2)
+	go func() { errc <- s.Start(ctx) }()
+	go func() {
+		logger.Info("dogfood all-in-one has started", zap.String("port", addr))
+		listenAndServe := hs.ListenAndServe
+		if hs.TLSConfig != nil {
+			listenAndServe = func() error { return hs.ListenAndServeTLS("", "") }
+		}
+		if err := listenAndServe(); err != http.ErrServerClosed {
+			errc <- fmt.Errorf("failed to listen and serve: %w", err)
+		}
+	}()
+
+	var serveErr error
+	select {
+	case <-c:
+	case serveErr = <-errc:
+	}
+	logger.Info("dogfood all-in-one is shutting down")
+	if err := lc.Shutdown(context.Background()); err != nil {
+		logger.Warn("failed to shutdown gracefully", zap.Error(err))
+	}
+	if serveErr != nil {
+		logger.Fatal("exit due to a failure of dogfood all-in-one", zap.Error(serveErr))
+	}
+	logger.Info("bye~~")
+}
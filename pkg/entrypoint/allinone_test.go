@@ -0,0 +1,66 @@
+package entrypoint
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kei6u/dogfood/pkg/gateway"
+	protov1 "github.com/kei6u/dogfood/proto/v1"
+	"go.uber.org/zap"
+)
+
+func TestNewAllInOne(t *testing.T) {
+	s, handler, err := newAllInOne(zap.NewNop(), "", func() bool { return false }, protov1.WithAdminAddr("127.0.0.1:0"))
+	if err != nil {
+		t.Fatalf("newAllInOne() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	started := make(chan error, 1)
+	go func() { started <- s.Start(ctx) }()
+	defer func() {
+		if err := s.Stop(ctx); err != nil {
+			t.Errorf("Stop() error = %v", err)
+		}
+		<-started
+	}()
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	// Requests go through the rate limiting gateway, the gRPC gateway and the gRPC server in memory.
+	from := time.Now().Add(-time.Minute).Format(time.RFC3339)
+	res, err := http.Post(ts.URL+gateway.CreateRecordRequestURI, "application/json", strings.NewReader(`{"dogfood_name":"royal","gram":100,"dog_name":"pochi"}`))
+	if err != nil {
+		t.Fatalf("CreateRecord error = %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("CreateRecord status = %d, want 200", res.StatusCode)
+	}
+
+	to := time.Now().Add(time.Minute).Format(time.RFC3339)
+	res, err = http.Post(ts.URL+gateway.ListRecordsRequestURI, "application/json", strings.NewReader(`{"from":"`+from+`","to":"`+to+`","page_size":10}`))
+	if err != nil {
+		t.Fatalf("ListRecords error = %v", err)
+	}
+	defer res.Body.Close()
+	var list struct {
+		Records []struct {
+			DogName     string `json:"dogName"`
+			DogfoodName string `json:"dogfoodName"`
+			Gram        int32  `json:"gram"`
+		} `json:"records"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode records: %v", err)
+	}
+	if res.StatusCode != http.StatusOK || len(list.Records) != 1 ||
+		list.Records[0].DogName != "pochi" || list.Records[0].DogfoodName != "royal" || list.Records[0].Gram != 100 {
+		t.Errorf("ListRecords = %d %+v, want the created record", res.StatusCode, list.Records)
+	}
+}
@@ -7,22 +7,30 @@ import (
 	"syscall"
 
 	"github.com/kei6u/dogfood/driver"
+	"github.com/kei6u/dogfood/pkg/admin"
+	"github.com/kei6u/dogfood/pkg/health"
+	"github.com/kei6u/dogfood/pkg/lifecycle"
+	"github.com/kei6u/dogfood/pkg/logging"
+	"github.com/kei6u/dogfood/pkg/store"
+	"github.com/kei6u/dogfood/pkg/tlsconfig"
 	protov1 "github.com/kei6u/dogfood/proto/v1"
 	"go.uber.org/zap"
 )
 
 func RunBackend() {
-	logger, _ := zap.NewProduction()
+	logger, level, err := logging.NewFromEnv()
+	if err != nil {
+		logger.Fatal("exit due to invalid logger config", zap.Error(err))
+	}
 	defer logger.Sync()
 
-	startAPM()
-	defer stopAPM()
-
-	db, closeDB, err := driver.NewPsql()
+	lcOpts, err := lifecycle.OptionsFromEnv()
 	if err != nil {
-		logger.Fatal("exit due to connection failure of database", zap.Error(err))
+		logger.Fatal("exit due to invalid shutdown config", zap.Error(err))
 	}
-	defer closeDB()
+	lc := lifecycle.New(logger, lcOpts...)
+
+	stopTelemetry := startTelemetry(logger)
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, os.Kill, syscall.SIGINT, syscall.SIGTERM)
@@ -33,21 +41,71 @@ func RunBackend() {
 		cancel()
 	}()
 
-	s, err := protov1.NewServer(
-		ctx,
-		os.Getenv("GRPC_ADDR"),
-		os.Getenv("GRPC_GATEWAY_ADDR"),
-		logger,
-		db,
-	)
+	// Signals cancel waiting for Postgres to be available as well.
+	db, closeDB, err := driver.NewPsql(ctx)
 	if err != nil {
-		logger.Fatal("exit due to a failure of initializeing dogfood backend server", zap.Error(err))
+		if ctx.Err() != nil {
+			logger.Info("exit while waiting for database", zap.Error(err))
+			stopTelemetry()
+			return
+		}
+		logger.Fatal("exit due to connection failure of database", zap.Error(err))
 	}
-	if err := s.Start(ctx); err != nil {
-		logger.Warn("exit due to a failure of starting dogfood backend server", zap.Error(err))
-		return
+
+	tc, err := tlsconfig.FromEnv("TLS")
+	if err != nil {
+		logger.Fatal("exit due to invalid TLS config", zap.Error(err))
+	}
+
+	apiKeys, err := protov1.APIKeysFromEnv()
+	if err != nil {
+		logger.Fatal("exit due to invalid API keys", zap.Error(err))
 	}
 
-	<-ctx.Done()
-	s.Stop()
+	broker, pingBroker, closeBroker, err := newBroker(ctx)
+	if err != nil {
+		logger.Fatal("exit due to invalid message broker config", zap.Error(err))
+	}
+
+	opts := []protov1.Option{
+		protov1.WithGRPCAddr(os.Getenv("GRPC_ADDR")),
+		protov1.WithGRPCGatewayAddr(os.Getenv("GRPC_GATEWAY_ADDR")),
+		protov1.WithAdminAddr(admin.AddrFromEnv()),
+		protov1.WithMetricsAddr(admin.MetricsAddrFromEnv()),
+		protov1.WithAdminOptions(admin.WithLogLevel(level)),
+		protov1.WithLogger(logger),
+		protov1.WithStore(store.NewPsqlStore(db)),
+		protov1.WithTLS(tc),
+		protov1.WithBroker(broker),
+		protov1.WithAPIKeys(apiKeys),
+	}
+	if pingBroker != nil {
+		// Records are still stored while Redis is down, and events are published after it's back.
+		opts = append(opts, protov1.WithHealthCheck("redis", pingBroker, health.WithNonCritical()))
+	}
+	s, err := protov1.NewServer(opts...)
+	if err != nil {
+		logger.Fatal("exit due to a failure of initializeing dogfood backend server", zap.Error(err))
+	}
+	lc.OnNotReady(s.MarkNotReady)
+	lc.Drain("dogfood backend server", s.Stop)
+	lc.Close("postgres", closeDB)
+	lc.Close("message broker", closeBroker)
+	lc.Close("telemetry", stopTelemetry)
+
+	// Start returns when servers are stopped by shutdown, or when any of them fails.
+	startErr := make(chan error, 1)
+	go func() { startErr <- s.Start(ctx) }()
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+	case serveErr = <-startErr:
+	}
+	if err := lc.Shutdown(context.Background()); err != nil {
+		logger.Warn("failed to shutdown gracefully", zap.Error(err))
+	}
+	if serveErr != nil {
+		logger.Fatal("exit due to a failure of dogfood backend server", zap.Error(serveErr))
+	}
 }
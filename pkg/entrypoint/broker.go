@@ -0,0 +1,46 @@
+package entrypoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/kei6u/dogfood/driver"
+	"github.com/kei6u/dogfood/pkg/health"
+	"github.com/kei6u/dogfood/pkg/outbox"
+)
+
+// newBroker returns a message broker specified by OUTBOX_BROKER, which is redis or none,
+// and a health check of a server which the broker depends on if any.
+// The broker is nil and events are not published if it's none or not specified.
+// memory is rejected, because nothing consumes events published in the backend process and they are lost.
+func newBroker(ctx context.Context) (outbox.Broker, health.CheckFunc, func() error, error) {
+	nop := func() error { return nil }
+	switch b := os.Getenv("OUTBOX_BROKER"); b {
+	case "", "none":
+		return nil, nil, nop, nil
+	case "memory":
+		return nil, nil, nil, errors.New("OUTBOX_BROKER memory is only for tests, because nothing consumes events in the backend")
+	case "redis":
+		var maxLen int64
+		if v := os.Getenv("OUTBOX_REDIS_STREAM_MAXLEN"); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("OUTBOX_REDIS_STREAM_MAXLEN is invalid: %w", err)
+			}
+			maxLen = n
+		}
+		r, rClose, err := driver.NewRedis(ctx)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to initialize redis client: %w", err)
+		}
+		ping := func(ctx context.Context) error {
+			return r.Ping(ctx).Err()
+		}
+		return outbox.NewRedisStreamsBroker(r, maxLen), ping, rClose, nil
+	default:
+		return nil, nil, nil, fmt.Errorf("OUTBOX_BROKER %s is not supported", b)
+	}
+}
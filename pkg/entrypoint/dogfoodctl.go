@@ -0,0 +1,18 @@
+package entrypoint
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/kei6u/dogfood/pkg/dogfoodctl"
+)
+
+// RunDogfoodctl runs a command of dogfoodctl until it completes or is interrupted.
+func RunDogfoodctl() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := dogfoodctl.Run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
@@ -3,40 +3,38 @@ package entrypoint
 import (
 	"context"
 	"fmt"
-	"net"
 	"net/http"
-	"net/http/httputil"
-	"net/url"
 	"os"
 	"os/signal"
-	"strconv"
-	"strings"
 	"syscall"
-	"time"
 
 	redisrate "github.com/go-redis/redis_rate/v9"
 	"github.com/kei6u/dogfood/driver"
-	"github.com/kei6u/dogfood/pkg/ddconfig"
-	"github.com/kei6u/dogfood/pkg/httplib"
+	"github.com/kei6u/dogfood/pkg/admin"
+	"github.com/kei6u/dogfood/pkg/gateway"
+	"github.com/kei6u/dogfood/pkg/lifecycle"
+	"github.com/kei6u/dogfood/pkg/logging"
+	"github.com/kei6u/dogfood/pkg/tlsconfig"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
-	http_dd "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
-	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
-)
-
-const (
-	createRecordRequestURI   = "/v1/dogfood/record"
-	listRecordsRequestURI    = "/v1/dogfood/records"
-	livenessProbeRequestURI  = "/v1/healthcheck/livenessProbe"
-	readinessProbeRequestURI = "/v1/healthcheck/readinessProbe"
-	startupProbeRequestURI   = "/v1/healthcheck/startupProbe"
 )
 
 func RunGateway() {
-	logger, _ := zap.NewProduction()
+	logger, level, err := logging.NewFromEnv()
+	if err != nil {
+		logger.Fatal("logger config is invalid", zap.Error(err))
+	}
 	defer logger.Sync()
 
-	startAPM()
-	defer stopAPM()
+	lcOpts, err := lifecycle.OptionsFromEnv()
+	if err != nil {
+		logger.Fatal("shutdown config is invalid", zap.Error(err))
+	}
+	lc := lifecycle.New(logger, lcOpts...)
+
+	startAdmin(logger, lc, admin.WithLogLevel(level), admin.WithMetrics(prometheus.DefaultGatherer))
+	startMetrics(logger, lc, prometheus.DefaultGatherer)
+	stopTelemetry := startTelemetry(logger)
 
 	// Loading environment variables.
 	var addr string
@@ -55,206 +53,90 @@ func RunGateway() {
 	if err != nil {
 		logger.Fatal("failed to initialize redis client", zap.Error(err))
 	}
-	defer rClose()
 	limiter := redisrate.NewLimiter(r)
 
-	gw := newGateway(limiter, logger)
-	if err := gw.registerReverseProxy(
-		dogfoodBackendAddr,
-		[]string{
-			createRecordRequestURI,
-			listRecordsRequestURI,
-		},
-	); err != nil {
-		logger.Fatal("failed to register a revere proxy to gateway", zap.Error(err))
+	// TLS_* configures the listener, and BACKEND_TLS_* configures connections to the backend.
+	serverTLS, err := tlsconfig.FromEnv("TLS")
+	if err != nil {
+		logger.Fatal("TLS config is invalid", zap.Error(err))
+	}
+	backendTLS, err := tlsconfig.FromEnv("BACKEND_TLS")
+	if err != nil {
+		logger.Fatal("BACKEND_TLS config is invalid", zap.Error(err))
 	}
 
-	// Reverse Proxy
-	http.HandleFunc(gw.handleFunc(createRecordRequestURI))
-	http.HandleFunc(gw.handleFunc(listRecordsRequestURI))
+	backendTransport, err := newBackendTransport(backendTLS)
+	if err != nil {
+		logger.Fatal("failed to initialize transport to backend", zap.Error(err))
+	}
 
-	// Health check
-	http.HandleFunc(livenessProbeRequestURI, func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
-	http.HandleFunc(readinessProbeRequestURI, func(w http.ResponseWriter, _ *http.Request) {
-		if err := r.Ping(ctx).Err(); err != nil {
-			logger.Error("readiness probe failed", zap.Error(err))
-			http.Error(w, fmt.Sprintf("readiness probe failed: %s", err), 503)
-			return
-		}
-		w.WriteHeader(http.StatusOK)
-	})
-	http.HandleFunc(startupProbeRequestURI, func(w http.ResponseWriter, _ *http.Request) {
-		if err := r.Set(ctx, startupProbeRequestURI, true, time.Second).Err(); err != nil {
-			logger.Error("startup probe failed", zap.Error(err))
-			http.Error(w, fmt.Sprintf("startup probe failed: %s", err), 503)
-			return
-		}
-		if _, err := r.Get(ctx, startupProbeRequestURI).Result(); err != nil {
-			logger.Error("startup probe failed", zap.Error(err))
-			http.Error(w, fmt.Sprintf("startup probe failed: %s", err), 503)
-			return
-		}
-		w.WriteHeader(http.StatusOK)
+	gw := gateway.New(limiter, logger)
+	if err := gw.RegisterReverseProxy(dogfoodBackendAddr, backendTransport, gateway.BackendPatterns); err != nil {
+		logger.Fatal("failed to register a revere proxy to gateway", zap.Error(err))
+	}
+	if err := gw.RegisterMetrics(prometheus.DefaultRegisterer); err != nil {
+		logger.Fatal("failed to register metrics of gateway", zap.Error(err))
+	}
+
+	hc := gateway.NewHealthChecker(&http.Client{Transport: backendTransport}, dogfoodBackendAddr)
+	hc.Register("redis", func(ctx context.Context) error {
+		return r.Ping(ctx).Err()
 	})
 
 	s := &http.Server{
-		Addr: fmt.Sprintf(":%s", addr),
-		Handler: http_dd.WrapHandler(
-			http.DefaultServeMux,
-			ddconfig.GetService(),
-			"",
-			http_dd.WithIgnoreRequest(func(r *http.Request) bool {
-				return strings.Contains(strings.ToLower(r.RequestURI), "healthcheck")
-			}),
-		),
+		Addr:    fmt.Sprintf(":%s", addr),
+		Handler: gw.Handler(hc, lc.ShuttingDown),
+	}
+
+	if serverTLS != nil {
+		if s.TLSConfig, err = serverTLS.ServerTLSConfig(); err != nil {
+			logger.Fatal("failed to initialize TLS config", zap.Error(err))
+		}
 	}
 
+	lc.Drain("dogfood gateway", lifecycle.HTTPServer(s))
+	lc.Close("redis", rClose)
+	lc.Close("telemetry", stopTelemetry)
+
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, os.Kill, syscall.SIGINT, syscall.SIGTERM)
-	var cancel context.CancelFunc
-	ctx, cancel = context.WithCancel(context.Background())
 
+	errc := make(chan error, 1)
 	go func() {
 		logger.Info("dogfood gateway has started", zap.String("port", addr))
-		if err := s.ListenAndServe(); err != nil {
-			logger.Info("failed to listen and serve", zap.Error(err))
+		listenAndServe := s.ListenAndServe
+		if s.TLSConfig != nil {
+			listenAndServe = func() error { return s.ListenAndServeTLS("", "") }
+		}
+		if err := listenAndServe(); err != http.ErrServerClosed {
+			errc <- fmt.Errorf("failed to listen and serve: %w", err)
 		}
-	}()
-	go func() {
-		<-c
-		logger.Info("dogfood gateway is shutting down")
-		s.Shutdown(ctx)
-		cancel()
 	}()
 
-	<-ctx.Done()
-	logger.Info("bye~~")
-}
-
-type gateway struct {
-	limiter    *redisrate.Limiter
-	rpLookup   map[string]*httputil.ReverseProxy // key: addr, e.g. /v1/dogfood/record
-	addrLookup map[string]string                 // key: addr, value: pattern
-	l          *zap.Logger
-}
-
-func newGateway(limiter *redisrate.Limiter, l *zap.Logger) *gateway {
-	return &gateway{
-		limiter,
-		make(map[string]*httputil.ReverseProxy),
-		map[string]string{},
-		l,
+	var serveErr error
+	select {
+	case <-c:
+	case serveErr = <-errc:
 	}
-}
-
-func (gw *gateway) registerReverseProxy(addr string, patterns []string) error {
-	u, err := url.Parse(addr)
-	if err != nil {
-		return fmt.Errorf("target host is invalid: %w", err)
+	logger.Info("dogfood gateway is shutting down")
+	if err := lc.Shutdown(context.Background()); err != nil {
+		logger.Warn("failed to shutdown gracefully", zap.Error(err))
 	}
-	rp := httputil.NewSingleHostReverseProxy(u)
-	for _, p := range patterns {
-		gw.addrLookup[p] = addr
+	if serveErr != nil {
+		logger.Fatal("exit due to a failure of dogfood gateway", zap.Error(serveErr))
 	}
-	gw.rpLookup[addr] = rp
-	return nil
+	logger.Info("bye~~")
 }
 
-func (gw *gateway) handleFunc(pattern string) (string, http.HandlerFunc) {
-	return pattern, func(w http.ResponseWriter, r *http.Request) {
-		span, ctx := tracer.StartSpanFromContext(r.Context(), ddconfig.GetService(), tracer.ResourceName(pattern))
-		fields := []zap.Field{
-			zap.Uint64("dd.trace_id", span.Context().TraceID()),
-			zap.Uint64("dd.span_id", span.Context().SpanID()),
-		}
-		defer span.Finish()
-		r = r.WithContext(ctx)
-		if err := tracer.Inject(span.Context(), tracer.HTTPHeadersCarrier(r.Header)); err != nil {
-			gw.l.Error("failed to inject span", append(fields, zap.Error(err))...)
-			http.Error(w, fmt.Sprintf("failed to inject span: %v", err), 500)
-			return
-		}
-
-		addr, ok := gw.addrLookup[pattern]
-		if !ok {
-			gw.l.Error("requested pattern is not found", append(fields, zap.String("pattern", pattern))...)
-			http.Error(w, fmt.Sprintf("%s is not supported", pattern), 400)
-			return
-		}
-
-		ip := httplib.GetIP(r)
-		if ip == nil {
-			gw.l.Error(fmt.Sprintf("ip address: %s is invalid format", ip.String()), fields...)
-			http.Error(w, "ip address is missing", 400)
-			return
-		}
-
-		statuscode, err := gw.ratelimit(w, r, pattern, ip)
+// newBackendTransport returns a transport to the backend, which presents a client certificate if configured.
+func newBackendTransport(tc *tlsconfig.Config) (http.RoundTripper, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if tc != nil {
+		clientTLS, err := tc.ClientTLSConfig()
 		if err != nil {
-			gw.l.Error("request failed", append(fields, zap.Error(err))...)
-			http.Error(w, fmt.Sprintf("request failed: %s", err), statuscode)
-			return
+			return nil, fmt.Errorf("failed to initialize TLS config: %w", err)
 		}
-
-		gw.l.Info(fmt.Sprintf("reverse proxy: %s to %s:%s", pattern, addr, pattern), fields...)
-		gw.rpLookup[addr].ServeHTTP(w, r)
-	}
-}
-
-func (gw *gateway) ratelimit(w http.ResponseWriter, r *http.Request, pattern string, ip net.IP) (int, error) {
-	sctx, err := tracer.Extract(tracer.HTTPHeadersCarrier(r.Header))
-	if err != nil {
-		return http.StatusInternalServerError, fmt.Errorf("failed to extract span context: %v", err)
-	}
-	span := tracer.StartSpan(ddconfig.GetService(ddconfig.WithServiceSuffix(".ratelimit")), tracer.ChildOf(sctx))
-	defer span.Finish()
-
-	key := fmt.Sprintf("%s %s", ip.String(), pattern)
-	res, err := gw.limiter.Allow(r.Context(), key, getLimit())
-	if err != nil {
-		return http.StatusInternalServerError, fmt.Errorf("failed to allow request to %s from %s: %v", ip.String(), pattern, err)
-	}
-	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
-	if res.Allowed == 0 {
-		retryAfter := strconv.Itoa(int(res.RetryAfter / time.Second))
-		w.Header().Set("X-RateLimit-Reset", retryAfter)
-		return http.StatusTooManyRequests, fmt.Errorf("exceeds rate limit, retry in %s second(s", retryAfter)
-	}
-	return http.StatusOK, nil
-}
-
-// For dynamic rate limit configuration.
-var (
-	defaultLimit    redisrate.Limit                      = redisrate.PerHour(60)
-	limitByTimeUnit map[string]func(int) redisrate.Limit = map[string]func(int) redisrate.Limit{
-		"second": redisrate.PerSecond,
-		"Second": redisrate.PerSecond,
-		"SECOND": redisrate.PerSecond,
-		"minute": redisrate.PerMinute,
-		"Minute": redisrate.PerMinute,
-		"MINUTE": redisrate.PerMinute,
-		"hour":   redisrate.PerHour,
-		"Hour":   redisrate.PerHour,
-		"HOUR":   redisrate.PerHour,
-	}
-)
-
-func getLimit() redisrate.Limit {
-	var unit string
-	var limit string
-	if unit = os.Getenv("RATELIMIT_TIME_UNIT"); unit == "" {
-		return defaultLimit
-	}
-	if limit = os.Getenv("RATELIMIT_LIMIT"); limit == "" {
-		return defaultLimit
-	}
-	l, ok := limitByTimeUnit[unit]
-	if !ok {
-		return defaultLimit
-	}
-	v, err := strconv.Atoi(limit)
-	if err != nil {
-		return defaultLimit
+		t.TLSClientConfig = clientTLS
 	}
-	return l(v)
+	return t, nil
 }
@@ -0,0 +1,180 @@
+package entrypoint
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/kei6u/dogfood/pkg/recordio"
+	"github.com/kei6u/dogfood/pkg/tlsconfig"
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const defaultImportBatchSize = 500
+
+// RunImporter imports records from a CSV or NDJSON file to the backend through ImportRecords.
+// Rows which can't be parsed are reported together with rows rejected by the backend.
+func RunImporter() {
+	fs := flag.NewFlagSet("importer", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s [flags] FILE\n\nFILE is CSV or NDJSON, or - to read from stdin.\n\n", fs.Name())
+		fs.PrintDefaults()
+	}
+	addr := fs.String("addr", getEnvOrDefault("DOGFOOD_BACKEND_GRPC_ADDR", "localhost:50100"), "gRPC address of the backend")
+	format := fs.String("format", "", "csv or ndjson, which is detected by an extension of FILE if not specified")
+	dryRun := fs.Bool("dry-run", false, "validate all rows and detect duplicates without importing them")
+	singleTx := fs.Bool("single-transaction", false, "import nothing if any row fails")
+	batchSize := fs.Int("batch-size", defaultImportBatchSize, "rows per message")
+	fs.Parse(os.Args[1:])
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	res, err := runImporter(fs.Arg(0), *addr, *format, *batchSize, &dogfoodpb.ImportOptions{
+		DryRun:            *dryRun,
+		SingleTransaction: *singleTx,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to import records: %s\n", err)
+		os.Exit(1)
+	}
+
+	for _, e := range res.GetErrors() {
+		fmt.Printf("line %d: %s\n", e.GetLine(), e.GetMessage())
+	}
+	switch {
+	case *dryRun:
+		fmt.Printf("dry run: %d records would be imported, %d rows failed\n", res.GetImported(), res.GetFailed())
+	case !res.GetCommitted():
+		fmt.Printf("rolled back: %d rows failed\n", res.GetFailed())
+	default:
+		fmt.Printf("%d records were imported, %d rows failed\n", res.GetImported(), res.GetFailed())
+	}
+	if res.GetFailed() > 0 {
+		os.Exit(1)
+	}
+}
+
+func runImporter(path, addr, format string, batchSize int, opts *dogfoodpb.ImportOptions) (*dogfoodpb.ImportRecordsResponse, error) {
+	contentType, err := importContentType(path, format)
+	if err != nil {
+		return nil, err
+	}
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		defer f.Close()
+		in = f
+	}
+	rr, err := recordio.NewReader(contentType, in)
+	if err != nil {
+		return nil, err
+	}
+
+	// BACKEND_TLS_* configures connections to the backend like the gateway.
+	tc, err := tlsconfig.FromEnv("BACKEND_TLS")
+	if err != nil {
+		return nil, fmt.Errorf("BACKEND_TLS config is invalid: %w", err)
+	}
+	creds := grpc.WithTransportCredentials(insecure.NewCredentials())
+	if tc != nil {
+		clientTLS, err := tc.ClientTLSConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize TLS config: %w", err)
+		}
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(clientTLS))
+	}
+	conn, err := grpc.Dial(addr, creds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	return importRecords(context.Background(), dogfoodpb.NewDogFoodServiceClient(conn), rr, batchSize, opts)
+}
+
+// importRecords streams rows in batches. Rows which can't be parsed are sent with their errors,
+// so that the backend reports them with the others in order of line.
+func importRecords(ctx context.Context, c dogfoodpb.DogFoodServiceClient, rr recordio.Reader, batchSize int, opts *dogfoodpb.ImportOptions) (*dogfoodpb.ImportRecordsResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := c.ImportRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	send := func(req *dogfoodpb.ImportRecordsRequest) error {
+		// Send returns io.EOF if the backend aborted the stream, whose status is returned by CloseAndRecv.
+		if err := stream.Send(req); err == io.EOF {
+			_, err = stream.CloseAndRecv()
+			return err
+		} else if err != nil {
+			return err
+		}
+		return nil
+	}
+	req := &dogfoodpb.ImportRecordsRequest{Options: opts}
+	for {
+		row, err := rr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Canceling the stream rolls back a single transaction.
+			return nil, fmt.Errorf("failed to read rows: %w", err)
+		}
+		r := &dogfoodpb.ImportRow{Line: row.Line, Record: row.Record}
+		if row.Err != nil {
+			r.Error = row.Err.Error()
+		}
+		req.Rows = append(req.Rows, r)
+		if len(req.Rows) >= batchSize {
+			if err := send(req); err != nil {
+				return nil, err
+			}
+			req = &dogfoodpb.ImportRecordsRequest{}
+		}
+	}
+	// The options are sent even if there are no rows.
+	if len(req.Rows) > 0 || req.Options != nil {
+		if err := send(req); err != nil {
+			return nil, err
+		}
+	}
+	return stream.CloseAndRecv()
+}
+
+// importContentType returns a content type by format, or by an extension of path.
+func importContentType(path, format string) (string, error) {
+	if format == "" {
+		switch filepath.Ext(path) {
+		case ".ndjson", ".jsonl":
+			format = "ndjson"
+		default:
+			format = "csv"
+		}
+	}
+	switch format {
+	case "csv":
+		return recordio.ContentTypeCSV, nil
+	case "ndjson":
+		return recordio.ContentTypeNDJSON, nil
+	}
+	return "", fmt.Errorf("format %s is not supported", format)
+}
+
+func getEnvOrDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
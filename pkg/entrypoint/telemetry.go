@@ -0,0 +1,22 @@
+package entrypoint
+
+import (
+	"context"
+
+	"github.com/kei6u/dogfood/pkg/ddconfig"
+	"github.com/kei6u/dogfood/pkg/telemetry"
+	"go.uber.org/zap"
+)
+
+// startTelemetry starts telemetry selected by TELEMETRY_BACKEND, and returns a function to flush and stop it.
+// Telemetry is disabled rather than failing the process if it cannot start.
+func startTelemetry(logger *zap.Logger) func() error {
+	stop, err := telemetry.Start(context.Background(), telemetry.GetBackend(), ddconfig.GetService(), telemetry.WithLogger(logger))
+	if err != nil {
+		logger.Warn("telemetry is disabled due to a failure of starting it", zap.Error(err))
+		return func() error { return nil }
+	}
+	return func() error {
+		return stop(context.Background())
+	}
+}
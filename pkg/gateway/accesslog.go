@@ -0,0 +1,172 @@
+package gateway
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kei6u/dogfood/pkg/httplib"
+	"github.com/kei6u/dogfood/pkg/telemetry"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Outcomes of rate limiting in access logs.
+const (
+	rateLimitAllowed = "allowed"
+	rateLimitDenied  = "denied"
+	rateLimitError   = "error"
+)
+
+// AccessLogConfig configures access logs of the gateway.
+type AccessLogConfig struct {
+	// SampleRate is a ratio of logged requests in [0, 1]. Requests failed with 5xx are always logged.
+	SampleRate float64
+	// HealthChecks logs health checks, which are skipped by default.
+	HealthChecks bool
+}
+
+var defaultAccessLogConfig = AccessLogConfig{SampleRate: 1}
+
+// AccessLogConfigFromEnv returns a config by ACCESS_LOG_SAMPLE_RATE and ACCESS_LOG_HEALTH_CHECKS.
+// It logs all requests except health checks by default.
+func AccessLogConfigFromEnv() AccessLogConfig {
+	c := defaultAccessLogConfig
+	if v := os.Getenv("ACCESS_LOG_SAMPLE_RATE"); v != "" {
+		if rate, err := strconv.ParseFloat(v, 64); err == nil && rate >= 0 && rate <= 1 {
+			c.SampleRate = rate
+		}
+	}
+	if v := os.Getenv("ACCESS_LOG_HEALTH_CHECKS"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.HealthChecks = b
+		}
+	}
+	return c
+}
+
+// WithAccessLog configures access logs. It defaults to AccessLogConfigFromEnv.
+func WithAccessLog(c AccessLogConfig) Option {
+	return func(gw *Gateway) {
+		gw.accessLog = c
+	}
+}
+
+// accessEntry is filled while a request is handled, and logged after the response is written.
+type accessEntry struct {
+	pattern     string
+	rateLimit   string
+	upstream    string
+	upstreamErr error
+}
+
+type accessEntryKey struct{}
+
+// entryFromContext returns an entry of a request, which is discarded if the request is not logged.
+func entryFromContext(ctx context.Context) *accessEntry {
+	if e, ok := ctx.Value(accessEntryKey{}).(*accessEntry); ok {
+		return e
+	}
+	return &accessEntry{}
+}
+
+// responseRecorder records the status and the size of a response.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush flushes streamed responses, e.g. exported records.
+func (w *responseRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *responseRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func isHealthCheck(r *http.Request) bool {
+	return strings.Contains(strings.ToLower(r.URL.Path), "healthcheck")
+}
+
+// observe records metrics of a request handled by h, and logs it with structured fields.
+func (gw *Gateway) observe(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		e := &accessEntry{}
+		rec := &responseRecorder{ResponseWriter: w}
+		h.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), accessEntryKey{}, e)))
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		duration := time.Since(start)
+
+		route, status := routeOf(r, e), strconv.Itoa(rec.status)
+		gw.metrics.requests.WithLabelValues(route, methodOf(r), status).Inc()
+		gw.metrics.requestDuration.WithLabelValues(route, status).Observe(duration.Seconds())
+
+		if !gw.accessLog.HealthChecks && isHealthCheck(r) {
+			return
+		}
+		level := zapcore.InfoLevel
+		if rec.status >= http.StatusInternalServerError {
+			level = zapcore.ErrorLevel
+		} else if rand.Float64() >= gw.accessLog.SampleRate {
+			return
+		}
+		fields := append([]zap.Field{
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.String("pattern", e.pattern),
+			zap.Int("status", rec.status),
+			zap.Duration("duration", duration),
+			zap.Int64("bytes", rec.bytes),
+			zap.String("client_ip", httplib.GetIP(r).String()),
+			zap.String("ratelimit", e.rateLimit),
+			zap.String("upstream", e.upstream),
+		}, telemetry.LogFields(r.Context())...)
+		if e.upstreamErr != nil {
+			fields = append(fields, zap.NamedError("upstream_error", e.upstreamErr))
+		}
+		if ce := gw.l.Check(level, "access"); ce != nil {
+			ce.Write(fields...)
+		}
+	})
+}
+
+// proxyErrorHandler logs failures to reach upstreams, and responds with 502 as the default does.
+func (gw *Gateway) proxyErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	e := entryFromContext(r.Context())
+	e.upstreamErr = err
+	if r.Context().Err() == context.Canceled {
+		// The client went away, so nobody reads the response.
+		w.WriteHeader(499)
+		return
+	}
+	gw.metrics.upstreamErrors.WithLabelValues(routeOf(r, e)).Inc()
+	gw.l.Error("failed to proxy a request to the upstream", append(telemetry.LogFields(r.Context()), zap.String("path", r.URL.Path), zap.Error(err))...)
+	w.WriteHeader(http.StatusBadGateway)
+}
@@ -0,0 +1,118 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kei6u/dogfood/pkg/health"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestGateway_AccessLog(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	tests := []struct {
+		name       string
+		config     AccessLogConfig
+		path       string
+		wantStatus int
+		wantLogs   int
+		wantLevel  zapcore.Level
+		wantFields map[string]interface{}
+	}{
+		{
+			name:       "proxied",
+			config:     AccessLogConfig{SampleRate: 1},
+			path:       CreateRecordRequestURI,
+			wantStatus: http.StatusOK,
+			wantLogs:   1,
+			wantLevel:  zapcore.InfoLevel,
+			wantFields: map[string]interface{}{
+				"status":    int64(http.StatusOK),
+				"bytes":     int64(2),
+				"ratelimit": rateLimitAllowed,
+				"upstream":  upstream.URL,
+				"pattern":   CreateRecordRequestURI,
+			},
+		},
+		{
+			name:       "not sampled",
+			config:     AccessLogConfig{SampleRate: 0},
+			path:       CreateRecordRequestURI,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "upstream failure is always logged",
+			config:     AccessLogConfig{SampleRate: 0},
+			path:       ListAlertsRequestURI,
+			wantStatus: http.StatusBadGateway,
+			wantLogs:   2,
+			wantLevel:  zapcore.ErrorLevel,
+			wantFields: map[string]interface{}{"status": int64(http.StatusBadGateway), "upstream": down.URL},
+		},
+		{
+			name:       "health check is skipped",
+			config:     AccessLogConfig{SampleRate: 1},
+			path:       LivenessProbeRequestURI,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "health check",
+			config:     AccessLogConfig{SampleRate: 1, HealthChecks: true},
+			path:       LivenessProbeRequestURI,
+			wantStatus: http.StatusOK,
+			wantLogs:   1,
+			wantLevel:  zapcore.InfoLevel,
+			wantFields: map[string]interface{}{"status": int64(http.StatusOK), "ratelimit": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			gw := New(NewMemoryLimiter(), zap.New(core), WithAccessLog(tt.config))
+			if err := gw.RegisterReverseProxy(upstream.URL, http.DefaultTransport, []string{CreateRecordRequestURI}); err != nil {
+				t.Fatal(err)
+			}
+			if err := gw.RegisterReverseProxy(down.URL, http.DefaultTransport, []string{ListAlertsRequestURI}); err != nil {
+				t.Fatal(err)
+			}
+			h := gw.Handler(health.NewChecker(), func() bool { return false })
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if logs.Len() != tt.wantLogs {
+				t.Fatalf("logged %d entries, want %d: %v", logs.Len(), tt.wantLogs, logs.All())
+			}
+			if tt.wantLogs == 0 {
+				return
+			}
+			access := logs.FilterMessage("access").All()
+			if len(access) != 1 {
+				t.Fatalf("access logs = %v, want one", access)
+			}
+			if access[0].Level != tt.wantLevel {
+				t.Errorf("level = %s, want %s", access[0].Level, tt.wantLevel)
+			}
+			fields := access[0].ContextMap()
+			for k, want := range tt.wantFields {
+				if fields[k] != want {
+					t.Errorf("%s = %v, want %v", k, fields[k], want)
+				}
+			}
+			if fields["request_id"] == "" || fields["client_ip"] != "192.0.2.1" || fields["method"] != http.MethodGet {
+				t.Errorf("fields = %v, want request_id, client_ip and method", fields)
+			}
+		})
+	}
+}
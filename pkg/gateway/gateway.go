@@ -0,0 +1,283 @@
+// Package gateway is a reverse proxy to the dogfood backend which limits rate of requests per client IP.
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	redisrate "github.com/go-redis/redis_rate/v9"
+	"github.com/kei6u/dogfood/pkg/ddconfig"
+	"github.com/kei6u/dogfood/pkg/health"
+	"github.com/kei6u/dogfood/pkg/httplib"
+	"github.com/kei6u/dogfood/pkg/requestid"
+	"github.com/kei6u/dogfood/pkg/telemetry"
+	"go.uber.org/zap"
+)
+
+const (
+	CreateRecordRequestURI   = "/v1/dogfood/record"
+	RecordRequestURIPrefix   = "/v1/dogfood/record/"
+	AuditEventsRequestURI    = "/v1/dogfood/audit_events"
+	ListRecordsRequestURI    = "/v1/dogfood/records"
+	ExportRecordsRequestURI  = "/v1/dogfood/records/export"
+	ListAlertsRequestURI     = "/v1/dogfood/alerts"
+	WebhooksRequestURI       = "/v1/dogfood/webhooks"
+	WebhookRequestURIPrefix  = "/v1/dogfood/webhooks/"
+	LivenessProbeRequestURI  = "/v1/healthcheck/livenessProbe"
+	ReadinessProbeRequestURI = "/v1/healthcheck/readinessProbe"
+	StartupProbeRequestURI   = "/v1/healthcheck/startupProbe"
+	HealthReportRequestURI   = "/v1/healthcheck/report"
+)
+
+// BackendPatterns are patterns proxied to the backend. Requests to correct records, list audit events
+// and manage webhooks are rejected by the backend unless their X-Api-Key header is valid.
+var BackendPatterns = []string{
+	CreateRecordRequestURI,
+	RecordRequestURIPrefix,
+	AuditEventsRequestURI,
+	ListRecordsRequestURI,
+	ExportRecordsRequestURI,
+	ListAlertsRequestURI,
+	WebhooksRequestURI,
+	WebhookRequestURIPrefix,
+}
+
+// Limiter limits rate of requests by key. *redis_rate.Limiter implements it.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit redisrate.Limit) (*redisrate.Result, error)
+}
+
+type Gateway struct {
+	limiter    Limiter
+	limit      redisrate.Limit
+	rpLookup   map[string]*httputil.ReverseProxy // key: addr, e.g. /v1/dogfood/record
+	addrLookup map[string]string                 // key: addr, value: pattern
+	patterns   []string                          // in order of registration
+	accessLog  AccessLogConfig
+	metrics    *metrics
+	l          *zap.Logger
+}
+
+type Option func(*Gateway)
+
+// WithLimit sets a rate limit per client IP and pattern. It defaults to LimitFromEnv.
+func WithLimit(limit redisrate.Limit) Option {
+	return func(gw *Gateway) {
+		gw.limit = limit
+	}
+}
+
+// New returns a gateway which limits rate of requests by limiter.
+func New(limiter Limiter, l *zap.Logger, opts ...Option) *Gateway {
+	gw := &Gateway{
+		limiter:    limiter,
+		limit:      LimitFromEnv(),
+		accessLog:  AccessLogConfigFromEnv(),
+		metrics:    newMetrics(),
+		rpLookup:   make(map[string]*httputil.ReverseProxy),
+		addrLookup: map[string]string{},
+		l:          l,
+	}
+	for _, opt := range opts {
+		opt(gw)
+	}
+	return gw
+}
+
+// NewHealthChecker returns a checker of the backend and disk space.
+func NewHealthChecker(backend *http.Client, backendAddr string) *health.Checker {
+	hc := health.NewChecker()
+	hc.Register("backend", health.HTTPGet(backend, strings.TrimSuffix(backendAddr, "/")+LivenessProbeRequestURI))
+	hc.Register("disk", health.DiskSpace(os.TempDir(), health.DefaultDiskMinFreeBytes), health.WithNonCritical())
+	return hc
+}
+
+// RegisterReverseProxy proxies requests matching patterns to addr via transport.
+func (gw *Gateway) RegisterReverseProxy(addr string, transport http.RoundTripper, patterns []string) error {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return fmt.Errorf("target host is invalid: %w", err)
+	}
+	rp := httputil.NewSingleHostReverseProxy(u)
+	rp.Transport = transport
+	rp.ErrorHandler = gw.proxyErrorHandler
+	rp.ModifyResponse = func(res *http.Response) error {
+		// The request ID is echoed by the gateway, so the upstream's one is dropped not to be duplicated.
+		res.Header.Del(requestid.Header)
+		return nil
+	}
+	for _, p := range patterns {
+		if _, ok := gw.addrLookup[p]; !ok {
+			gw.patterns = append(gw.patterns, p)
+		}
+		gw.addrLookup[p] = addr
+	}
+	gw.rpLookup[addr] = rp
+	return nil
+}
+
+// Handler returns a handler of registered patterns and health checks by hc.
+// Readiness probes fail once shuttingDown returns true.
+func (gw *Gateway) Handler(hc *health.Checker, shuttingDown func() bool) http.Handler {
+	mux := http.NewServeMux()
+
+	// Reverse Proxy
+	for _, p := range gw.patterns {
+		mux.HandleFunc(gw.HandleFunc(p))
+	}
+
+	// Health check
+	mux.HandleFunc(LivenessProbeRequestURI, func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	mux.HandleFunc(ReadinessProbeRequestURI, func(w http.ResponseWriter, r *http.Request) {
+		if shuttingDown() {
+			http.Error(w, "readiness probe failed: gateway is shutting down", 503)
+			return
+		}
+		if err := hc.Err(r.Context()); err != nil {
+			gw.l.Error("readiness probe failed", zap.Error(err))
+			http.Error(w, fmt.Sprintf("readiness probe failed: %s", err), 503)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc(StartupProbeRequestURI, func(w http.ResponseWriter, r *http.Request) {
+		if err := hc.Err(r.Context()); err != nil {
+			gw.l.Error("startup probe failed", zap.Error(err))
+			http.Error(w, fmt.Sprintf("startup probe failed: %s", err), 503)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc(HealthReportRequestURI, func(w http.ResponseWriter, r *http.Request) {
+		report := hc.Report(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		if report.Status != health.StatusOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			gw.l.Error("failed to write health report", zap.Error(err))
+		}
+	})
+
+	return telemetry.WrapHandler(
+		requestid.Middleware(gw.observe(mux)),
+		ddconfig.GetService(),
+		func(r *http.Request) bool {
+			return strings.Contains(strings.ToLower(r.RequestURI), "healthcheck")
+		},
+	)
+}
+
+func (gw *Gateway) HandleFunc(pattern string) (string, http.HandlerFunc) {
+	return pattern, func(w http.ResponseWriter, r *http.Request) {
+		span, ctx := telemetry.StartSpanFromContext(r.Context(), ddconfig.GetService(), pattern)
+		fields := telemetry.LogFields(ctx)
+		defer span.Finish()
+		r = r.WithContext(ctx)
+		// Propagate trace context to the backend, e.g. x-datadog-trace-id or traceparent.
+		telemetry.Inject(ctx, r.Header)
+		e := entryFromContext(ctx)
+		e.pattern = pattern
+
+		addr, ok := gw.addrLookup[pattern]
+		if !ok {
+			gw.l.Error("requested pattern is not found", append(fields, zap.String("pattern", pattern))...)
+			http.Error(w, fmt.Sprintf("%s is not supported", pattern), 400)
+			return
+		}
+
+		ip := httplib.GetIP(r)
+		if ip == nil {
+			gw.l.Error(fmt.Sprintf("ip address: %s is invalid format", ip.String()), fields...)
+			http.Error(w, "ip address is missing", 400)
+			return
+		}
+
+		statuscode, err := gw.ratelimit(w, r, pattern, ip)
+		switch statuscode {
+		case http.StatusOK:
+			e.rateLimit = rateLimitAllowed
+		case http.StatusTooManyRequests:
+			e.rateLimit = rateLimitDenied
+		default:
+			e.rateLimit = rateLimitError
+			gw.l.Error("request failed", append(fields, zap.Error(err))...)
+		}
+		gw.metrics.rateLimit.WithLabelValues(pattern, e.rateLimit).Inc()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("request failed: %s", err), statuscode)
+			return
+		}
+
+		e.upstream = addr
+		gw.rpLookup[addr].ServeHTTP(w, r)
+	}
+}
+
+func (gw *Gateway) ratelimit(w http.ResponseWriter, r *http.Request, pattern string, ip net.IP) (int, error) {
+	span, _ := telemetry.StartSpanFromContext(r.Context(), ddconfig.GetService(ddconfig.WithServiceSuffix(".ratelimit")), pattern)
+	defer span.Finish()
+
+	key := fmt.Sprintf("%s %s", ip.String(), pattern)
+	start := time.Now()
+	res, err := gw.limiter.Allow(r.Context(), key, gw.limit)
+	gw.metrics.limiterDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		gw.metrics.limiterErrors.Inc()
+		return http.StatusInternalServerError, fmt.Errorf("failed to allow request to %s from %s: %v", ip.String(), pattern, err)
+	}
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	if res.Allowed == 0 {
+		retryAfter := strconv.Itoa(int(res.RetryAfter / time.Second))
+		w.Header().Set("X-RateLimit-Reset", retryAfter)
+		return http.StatusTooManyRequests, fmt.Errorf("exceeds rate limit, retry in %s second(s", retryAfter)
+	}
+	return http.StatusOK, nil
+}
+
+// For dynamic rate limit configuration.
+var (
+	defaultLimit    redisrate.Limit                      = redisrate.PerHour(60)
+	limitByTimeUnit map[string]func(int) redisrate.Limit = map[string]func(int) redisrate.Limit{
+		"second": redisrate.PerSecond,
+		"Second": redisrate.PerSecond,
+		"SECOND": redisrate.PerSecond,
+		"minute": redisrate.PerMinute,
+		"Minute": redisrate.PerMinute,
+		"MINUTE": redisrate.PerMinute,
+		"hour":   redisrate.PerHour,
+		"Hour":   redisrate.PerHour,
+		"HOUR":   redisrate.PerHour,
+	}
+)
+
+// LimitFromEnv returns a rate limit by RATELIMIT_TIME_UNIT and RATELIMIT_LIMIT, or 60 per hour by default.
+// RATELIMIT_LIMIT must be positive, or the default is used.
+func LimitFromEnv() redisrate.Limit {
+	var unit string
+	var limit string
+	if unit = os.Getenv("RATELIMIT_TIME_UNIT"); unit == "" {
+		return defaultLimit
+	}
+	if limit = os.Getenv("RATELIMIT_LIMIT"); limit == "" {
+		return defaultLimit
+	}
+	l, ok := limitByTimeUnit[unit]
+	if !ok {
+		return defaultLimit
+	}
+	v, err := strconv.Atoi(limit)
+	if err != nil || v <= 0 {
+		return defaultLimit
+	}
+	return l(v)
+}
@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	redisrate "github.com/go-redis/redis_rate/v9"
+)
+
+// memoryLimiterSweepInterval is how often keys which have no effect on limits are removed.
+const memoryLimiterSweepInterval = time.Minute
+
+// MemoryLimiter is Limiter in memory for a single gateway, e.g. in tests and demos.
+// It implements GCRA like redis_rate, so that limits behave the same as with Redis.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	tats      map[string]time.Time // key: key, value: theoretical arrival time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+var _ Limiter = (*MemoryLimiter)(nil)
+
+// NewMemoryLimiter returns an empty MemoryLimiter.
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		tats: make(map[string]time.Time),
+		now:  time.Now,
+	}
+}
+
+// Allow fails if limit has no rate or period, which can't space requests out.
+func (l *MemoryLimiter) Allow(_ context.Context, key string, limit redisrate.Limit) (*redisrate.Result, error) {
+	if limit.Rate <= 0 || limit.Period <= 0 {
+		return nil, fmt.Errorf("limit is invalid: %s", limit)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	emission := limit.Period / time.Duration(limit.Rate)
+	burstOffset := emission * time.Duration(limit.Burst)
+	tat, ok := l.tats[key]
+	if !ok || tat.Before(now) {
+		tat = now
+	}
+	newTat := tat.Add(emission)
+	diff := now.Sub(newTat.Add(-burstOffset))
+	if diff < 0 {
+		return &redisrate.Result{
+			Limit:      limit,
+			Allowed:    0,
+			Remaining:  0,
+			RetryAfter: -diff,
+			ResetAfter: tat.Sub(now),
+		}, nil
+	}
+	l.tats[key] = newTat
+	return &redisrate.Result{
+		Limit:      limit,
+		Allowed:    1,
+		Remaining:  int(diff / emission),
+		RetryAfter: -1,
+		ResetAfter: newTat.Sub(now),
+	}, nil
+}
+
+// sweep removes keys whose theoretical arrival time has passed. mu must be locked.
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < memoryLimiterSweepInterval {
+		return
+	}
+	for k, tat := range l.tats {
+		if tat.Before(now) {
+			delete(l.tats, k)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,114 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	redisrate "github.com/go-redis/redis_rate/v9"
+)
+
+func TestMemoryLimiter_Allow(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to run miniredis: %v", err)
+	}
+	defer s.Close()
+	r := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer r.Close()
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		limit redisrate.Limit
+		n     int
+	}{
+		{
+			name:  "per hour",
+			limit: redisrate.PerHour(3),
+			n:     5,
+		},
+		{
+			name:  "per minute",
+			limit: redisrate.PerMinute(4),
+			n:     6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := redisrate.NewLimiter(r)
+			got := NewMemoryLimiter()
+			// Requests are sent in the same instant, so that results don't depend on how long they take.
+			now := time.Now()
+			got.now = func() time.Time { return now }
+			for i := 0; i < tt.n; i++ {
+				w, err := want.Allow(ctx, tt.name, tt.limit)
+				if err != nil {
+					t.Fatalf("redis_rate Allow() error = %v", err)
+				}
+				g, err := got.Allow(ctx, tt.name, tt.limit)
+				if err != nil {
+					t.Fatalf("Allow() error = %v", err)
+				}
+				if g.Allowed != w.Allowed || g.Remaining != w.Remaining {
+					t.Errorf("Allow() of request %d = allowed %d remaining %d, want allowed %d remaining %d", i, g.Allowed, g.Remaining, w.Allowed, w.Remaining)
+				}
+				if (g.RetryAfter > 0) != (w.RetryAfter > 0) {
+					t.Errorf("RetryAfter of request %d = %s, want %s", i, g.RetryAfter, w.RetryAfter)
+				}
+			}
+		})
+	}
+
+	l := NewMemoryLimiter()
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	limit := redisrate.PerMinute(1)
+	if res, _ := l.Allow(ctx, "key", limit); res.Allowed != 1 {
+		t.Fatal("the first request must be allowed")
+	}
+	if res, _ := l.Allow(ctx, "key", limit); res.Allowed != 0 || res.RetryAfter != time.Minute {
+		t.Errorf("Allow() = allowed %d retry after %s, want denied for a minute", res.Allowed, res.RetryAfter)
+	}
+	now = now.Add(2 * memoryLimiterSweepInterval)
+	if res, _ := l.Allow(ctx, "other", limit); res.Allowed != 1 || len(l.tats) != 1 {
+		t.Errorf("expired keys must be swept, got %d keys", len(l.tats))
+	}
+	if res, _ := l.Allow(ctx, "key", limit); res.Allowed != 1 {
+		t.Error("requests must be allowed after retry")
+	}
+}
+
+func TestMemoryLimiter_InvalidLimit(t *testing.T) {
+	l := NewMemoryLimiter()
+	for _, limit := range []redisrate.Limit{redisrate.PerHour(0), {Rate: 1, Burst: 1}} {
+		if _, err := l.Allow(context.Background(), "k", limit); err == nil {
+			t.Errorf("Allow() with %+v error = nil, want error", limit)
+		}
+	}
+}
+
+func TestLimitFromEnv(t *testing.T) {
+	tests := []struct {
+		name  string
+		unit  string
+		limit string
+		want  redisrate.Limit
+	}{
+		{name: "default", want: defaultLimit},
+		{name: "per minute", unit: "minute", limit: "10", want: redisrate.PerMinute(10)},
+		{name: "zero falls back to default", unit: "minute", limit: "0", want: defaultLimit},
+		{name: "negative falls back to default", unit: "second", limit: "-1", want: defaultLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("RATELIMIT_TIME_UNIT", tt.unit)
+			t.Setenv("RATELIMIT_LIMIT", tt.limit)
+			if got := LimitFromEnv(); got != tt.want {
+				t.Errorf("LimitFromEnv() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// otherLabelValue buckets routes of requests which match no pattern and methods which are not standard,
+// so that label values from clients never explode cardinality.
+const otherLabelValue = "other"
+
+// metrics are Prometheus metrics of the gateway. Routes are registered patterns or probes.
+type metrics struct {
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	rateLimit       *prometheus.CounterVec
+	limiterDuration prometheus.Histogram
+	limiterErrors   prometheus.Counter
+	upstreamErrors  *prometheus.CounterVec
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "dogfood_gateway_requests_total",
+				Help: "the number of requests handled by the gateway",
+			},
+			[]string{"route", "method", "status"},
+		),
+		requestDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "dogfood_gateway_request_duration_seconds",
+				Help:    "latency of requests handled by the gateway",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"route", "status"},
+		),
+		rateLimit: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "dogfood_gateway_ratelimit_total",
+				Help: "the number of requests by outcome of rate limiting, either allowed, denied or error",
+			},
+			[]string{"route", "outcome"},
+		),
+		limiterDuration: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "dogfood_gateway_limiter_duration_seconds",
+				Help:    "latency of the rate limiter, e.g. Redis",
+				Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+			},
+		),
+		limiterErrors: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "dogfood_gateway_limiter_errors_total",
+				Help: "the number of failures of the rate limiter",
+			},
+		),
+		upstreamErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "dogfood_gateway_upstream_errors_total",
+				Help: "the number of requests failed to be proxied to upstreams",
+			},
+			[]string{"route"},
+		),
+	}
+}
+
+// RegisterMetrics registers metrics of the gateway to r. They are recorded but not exported unless registered.
+func (gw *Gateway) RegisterMetrics(r prometheus.Registerer) error {
+	m := gw.metrics
+	for _, c := range []prometheus.Collector{m.requests, m.requestDuration, m.rateLimit, m.limiterDuration, m.limiterErrors, m.upstreamErrors} {
+		if err := r.Register(c); err != nil {
+			return fmt.Errorf("failed to initialize gateway metrics to prometheus: %w", err)
+		}
+	}
+	return nil
+}
+
+// methodOf returns a method of r, or other if it's not standard, since methods come from clients.
+func methodOf(r *http.Request) string {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return r.Method
+	}
+	return otherLabelValue
+}
+
+// routeOf returns a route of r, which is a pattern matched by r or a probe.
+func routeOf(r *http.Request, e *accessEntry) string {
+	if e.pattern != "" {
+		return e.pattern
+	}
+	switch r.URL.Path {
+	case LivenessProbeRequestURI, ReadinessProbeRequestURI, StartupProbeRequestURI, HealthReportRequestURI:
+		return r.URL.Path
+	}
+	return otherLabelValue
+}
@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	redisrate "github.com/go-redis/redis_rate/v9"
+	"github.com/kei6u/dogfood/pkg/health"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, string, redisrate.Limit) (*redisrate.Result, error) {
+	return nil, errors.New("redis is down")
+}
+
+func TestGateway_Metrics(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer upstream.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	newHandler := func(t *testing.T, limiter Limiter) (http.Handler, *Gateway) {
+		gw := New(limiter, zap.NewNop(), WithLimit(redisrate.PerMinute(1)))
+		if err := gw.RegisterReverseProxy(upstream.URL, http.DefaultTransport, []string{CreateRecordRequestURI}); err != nil {
+			t.Fatal(err)
+		}
+		if err := gw.RegisterReverseProxy(down.URL, http.DefaultTransport, []string{ListAlertsRequestURI}); err != nil {
+			t.Fatal(err)
+		}
+		if err := gw.RegisterMetrics(prometheus.NewRegistry()); err != nil {
+			t.Fatal(err)
+		}
+		return gw.Handler(health.NewChecker(), func() bool { return false }), gw
+	}
+	serve := func(h http.Handler, method, path string) {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, path, nil))
+	}
+
+	h, gw := newHandler(t, NewMemoryLimiter())
+	serve(h, http.MethodPost, CreateRecordRequestURI)
+	serve(h, http.MethodPost, CreateRecordRequestURI)
+	serve(h, http.MethodGet, ListAlertsRequestURI)
+	serve(h, http.MethodGet, LivenessProbeRequestURI)
+	serve(h, "BREW", "/coffee")
+
+	m := gw.metrics
+	tests := []struct {
+		name string
+		c    prometheus.Collector
+		want float64
+	}{
+		{"allowed", m.requests.WithLabelValues(CreateRecordRequestURI, http.MethodPost, "200"), 1},
+		{"denied", m.requests.WithLabelValues(CreateRecordRequestURI, http.MethodPost, "429"), 1},
+		{"bad gateway", m.requests.WithLabelValues(ListAlertsRequestURI, http.MethodGet, "502"), 1},
+		{"probe", m.requests.WithLabelValues(LivenessProbeRequestURI, http.MethodGet, "200"), 1},
+		{"unknown", m.requests.WithLabelValues(otherLabelValue, otherLabelValue, "404"), 1},
+		{"ratelimit allowed", m.rateLimit.WithLabelValues(CreateRecordRequestURI, rateLimitAllowed), 1},
+		{"ratelimit denied", m.rateLimit.WithLabelValues(CreateRecordRequestURI, rateLimitDenied), 1},
+		{"upstream errors", m.upstreamErrors.WithLabelValues(ListAlertsRequestURI), 1},
+		{"limiter errors", m.limiterErrors, 0},
+	}
+	for _, tt := range tests {
+		if got := testutil.ToFloat64(tt.c); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if n := testutil.CollectAndCount(m.requestDuration); n != 5 {
+		t.Errorf("request duration has %d series, want 5", n)
+	}
+
+	h, gw = newHandler(t, failingLimiter{})
+	serve(h, http.MethodPost, CreateRecordRequestURI)
+	if got := testutil.ToFloat64(gw.metrics.limiterErrors); got != 1 {
+		t.Errorf("limiter errors = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(gw.metrics.rateLimit.WithLabelValues(CreateRecordRequestURI, rateLimitError)); got != 1 {
+		t.Errorf("ratelimit error = %v, want 1", got)
+	}
+}
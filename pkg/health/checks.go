@@ -0,0 +1,64 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultDiskMinFreeBytes is free space of 100MiB, which DiskSpace is registered with by default.
+const DefaultDiskMinFreeBytes = 100 << 20
+
+// SQLPing checks a database is reachable.
+func SQLPing(db *sql.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// SQLColumnsExist checks columns exist, which means migrations are applied.
+// Columns are table.column, e.g. record.deleted_at.
+func SQLColumnsExist(db *sql.DB, columns ...string) CheckFunc {
+	return func(ctx context.Context) error {
+		for _, c := range columns {
+			i := strings.Index(c, ".")
+			if i < 0 {
+				return fmt.Errorf("column %s is not table.column", c)
+			}
+			var exists bool
+			if err := db.QueryRowContext(
+				ctx,
+				`SELECT EXISTS (SELECT 1 FROM information_schema.columns
+				WHERE table_schema = current_schema() AND table_name = $1 AND column_name = $2)`,
+				c[:i], c[i+1:],
+			).Scan(&exists); err != nil {
+				return fmt.Errorf("failed to look up column %s: %w", c, err)
+			}
+			if !exists {
+				return fmt.Errorf("column %s does not exist", c)
+			}
+		}
+		return nil
+	}
+}
+
+// HTTPGet checks url responds 2xx.
+func HTTPGet(c *http.Client, url string) CheckFunc {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		res, err := c.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if res.StatusCode < 200 || res.StatusCode >= 300 {
+			return fmt.Errorf("%s responded %s", url, res.Status)
+		}
+		return nil
+	}
+}
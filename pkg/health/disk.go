@@ -0,0 +1,23 @@
+//go:build !windows
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+)
+
+// DiskSpace checks the filesystem of path has at least minFreeBytes available.
+func DiskSpace(path string, minFreeBytes uint64) CheckFunc {
+	return func(context.Context) error {
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(path, &st); err != nil {
+			return fmt.Errorf("failed to stat filesystem of %s: %w", path, err)
+		}
+		if free := st.Bavail * uint64(st.Bsize); free < minFreeBytes {
+			return fmt.Errorf("%d bytes are available on %s, want at least %d bytes", free, path, minFreeBytes)
+		}
+		return nil
+	}
+}
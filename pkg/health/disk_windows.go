@@ -0,0 +1,8 @@
+package health
+
+import "context"
+
+// DiskSpace is not supported on windows and always passes.
+func DiskSpace(string, uint64) CheckFunc {
+	return func(context.Context) error { return nil }
+}
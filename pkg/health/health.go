@@ -0,0 +1,196 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTimeout  = 3 * time.Second
+	defaultCacheTTL = 5 * time.Second
+)
+
+// Status is a status of a check.
+type Status string
+
+const (
+	StatusUnknown Status = "unknown"
+	StatusOK      Status = "ok"
+	StatusFailing Status = "failing"
+)
+
+// CheckFunc checks a dependency and returns an error if it's unhealthy.
+type CheckFunc func(ctx context.Context) error
+
+// Result is the last result of a check.
+type Result struct {
+	Name      string
+	Status    Status
+	Critical  bool
+	Latency   time.Duration
+	LastError string
+	CheckedAt time.Time
+}
+
+// MarshalJSON renders Latency in a human readable format.
+func (r Result) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name      string    `json:"name"`
+		Status    Status    `json:"status"`
+		Critical  bool      `json:"critical"`
+		Latency   string    `json:"latency"`
+		LastError string    `json:"lastError,omitempty"`
+		CheckedAt time.Time `json:"checkedAt"`
+	}{r.Name, r.Status, r.Critical, r.Latency.String(), r.LastError, r.CheckedAt})
+}
+
+// Report is results of all checks.
+// Status is failing if any critical check is failing.
+type Report struct {
+	Status Status   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Err returns an error which describes failing critical checks.
+func (r *Report) Err() error {
+	if r.Status == StatusOK {
+		return nil
+	}
+	for _, c := range r.Checks {
+		if c.Critical && c.Status != StatusOK {
+			return fmt.Errorf("%s check is %s: %s", c.Name, c.Status, c.LastError)
+		}
+	}
+	return fmt.Errorf("health status is %s", r.Status)
+}
+
+// Checker runs registered checks with timeouts and caches their results.
+type Checker struct {
+	cacheTTL time.Duration
+
+	mu     sync.RWMutex
+	checks []*check
+}
+
+// Option is a NewChecker() option.
+type Option func(*Checker)
+
+// WithCacheTTL sets how long results are reused without running checks again.
+func WithCacheTTL(d time.Duration) Option {
+	return func(c *Checker) {
+		c.cacheTTL = d
+	}
+}
+
+// NewChecker returns Checker without any checks.
+func NewChecker(opts ...Option) *Checker {
+	c := &Checker{cacheTTL: defaultCacheTTL}
+	for _, f := range opts {
+		f(c)
+	}
+	return c
+}
+
+type check struct {
+	name     string
+	fn       CheckFunc
+	timeout  time.Duration
+	critical bool
+
+	mu     sync.Mutex // serializes runs of the same check.
+	result Result
+}
+
+// CheckOption is a Register() option.
+type CheckOption func(*check)
+
+// WithTimeout sets a timeout of a check.
+func WithTimeout(d time.Duration) CheckOption {
+	return func(c *check) {
+		c.timeout = d
+	}
+}
+
+// WithNonCritical makes a check not affect the overall status.
+func WithNonCritical() CheckOption {
+	return func(c *check) {
+		c.critical = false
+	}
+}
+
+// Register registers a check by name.
+func (c *Checker) Register(name string, fn CheckFunc, opts ...CheckOption) {
+	ch := &check{
+		name:     name,
+		fn:       fn,
+		timeout:  defaultTimeout,
+		critical: true,
+	}
+	for _, f := range opts {
+		f(ch)
+	}
+	ch.result = Result{Name: name, Status: StatusUnknown, Critical: ch.critical}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, ch)
+}
+
+// Report runs checks whose cached results are expired concurrently and returns all results.
+// Checks run on their own contexts bounded by their timeouts rather than ctx, so that a cancelled request,
+// e.g. of a client which hung up, doesn't cache a failure for other callers.
+func (c *Checker) Report(_ context.Context) *Report {
+	c.mu.RLock()
+	checks := make([]*check, len(c.checks))
+	copy(checks, c.checks)
+	c.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, ch := range checks {
+		wg.Add(1)
+		go func(i int, ch *check) {
+			defer wg.Done()
+			results[i] = ch.run(c.cacheTTL)
+		}(i, ch)
+	}
+	wg.Wait()
+
+	r := &Report{Status: StatusOK, Checks: results}
+	for _, res := range results {
+		if res.Critical && res.Status != StatusOK {
+			r.Status = StatusFailing
+		}
+	}
+	return r
+}
+
+// Err runs checks and returns an error if any critical check is failing.
+func (c *Checker) Err(ctx context.Context) error {
+	return c.Report(ctx).Err()
+}
+
+func (ch *check) run(ttl time.Duration) Result {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.result.Status != StatusUnknown && time.Since(ch.result.CheckedAt) < ttl {
+		return ch.result
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ch.timeout)
+	defer cancel()
+	start := time.Now()
+	err := ch.fn(ctx)
+	ch.result.Latency = time.Since(start)
+	ch.result.CheckedAt = start
+	if err != nil {
+		ch.result.Status = StatusFailing
+		ch.result.LastError = err.Error()
+	} else {
+		ch.result.Status = StatusOK
+	}
+	return ch.result
+}
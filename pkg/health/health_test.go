@@ -0,0 +1,98 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestChecker_Report(t *testing.T) {
+	tests := []struct {
+		name       string
+		register   func(c *Checker)
+		wantStatus Status
+	}{
+		{
+			name: "all checks pass",
+			register: func(c *Checker) {
+				c.Register("a", func(context.Context) error { return nil })
+				c.Register("b", func(context.Context) error { return nil })
+			},
+			wantStatus: StatusOK,
+		},
+		{
+			name: "critical check fails",
+			register: func(c *Checker) {
+				c.Register("a", func(context.Context) error { return nil })
+				c.Register("b", func(context.Context) error { return errors.New("b is down") })
+			},
+			wantStatus: StatusFailing,
+		},
+		{
+			name: "non-critical check fails",
+			register: func(c *Checker) {
+				c.Register("a", func(context.Context) error { return nil })
+				c.Register("b", func(context.Context) error { return errors.New("b is down") }, WithNonCritical())
+			},
+			wantStatus: StatusOK,
+		},
+		{
+			name: "check times out",
+			register: func(c *Checker) {
+				c.Register("a", func(ctx context.Context) error {
+					<-ctx.Done()
+					return ctx.Err()
+				}, WithTimeout(10*time.Millisecond))
+			},
+			wantStatus: StatusFailing,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker()
+			tt.register(c)
+			r := c.Report(context.Background())
+			if r.Status != tt.wantStatus {
+				t.Errorf("Report().Status = %v, want %v", r.Status, tt.wantStatus)
+			}
+			if (r.Err() != nil) != (tt.wantStatus != StatusOK) {
+				t.Errorf("Report().Err() = %v", r.Err())
+			}
+		})
+	}
+}
+
+func TestChecker_Cache(t *testing.T) {
+	var calls int32
+	c := NewChecker(WithCacheTTL(time.Hour))
+	c.Register("a", func(context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return errors.New("a is down")
+	})
+
+	for i := 0; i < 3; i++ {
+		r := c.Report(context.Background())
+		if got := r.Checks[0].LastError; got != "a is down" {
+			t.Errorf("LastError = %q, want %q", got, "a is down")
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("check is called %d times, want 1", got)
+	}
+}
+
+func TestChecker_CancelledContext(t *testing.T) {
+	c := NewChecker(WithCacheTTL(time.Hour))
+	c.Register("a", func(ctx context.Context) error { return ctx.Err() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.Err(ctx); err != nil {
+		t.Errorf("Err() of a cancelled request = %v, want nil", err)
+	}
+	if err := c.Err(context.Background()); err != nil {
+		t.Errorf("Err() after a cancelled request = %v, want nil", err)
+	}
+}
@@ -0,0 +1,195 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+const (
+	defaultPreStopDelay = 0
+	defaultDrainTimeout = 30 * time.Second
+)
+
+// Manager coordinates a graceful shutdown in the following order.
+//  1. Mark the process not ready, so that load balancers stop routing new requests.
+//  2. Wait for the pre-stop delay, so that the readiness change is propagated.
+//  3. Drain in-flight requests of servers within the drain timeout.
+//  4. Close dependencies such as databases and flush the tracer.
+type Manager struct {
+	logger       *zap.Logger
+	preStopDelay time.Duration
+	drainTimeout time.Duration
+	shuttingDown int32
+
+	mu       sync.Mutex
+	notReady []func()
+	drainers []hook
+	closers  []hook
+}
+
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Option is a New() option.
+type Option func(*Manager)
+
+// WithPreStopDelay sets how long to wait after marking not ready before draining.
+func WithPreStopDelay(d time.Duration) Option {
+	return func(m *Manager) {
+		m.preStopDelay = d
+	}
+}
+
+// WithDrainTimeout sets the deadline to drain in-flight requests.
+func WithDrainTimeout(d time.Duration) Option {
+	return func(m *Manager) {
+		m.drainTimeout = d
+	}
+}
+
+// OptionsFromEnv loads options from SHUTDOWN_PRESTOP_DELAY and SHUTDOWN_DRAIN_TIMEOUT, e.g. 5s.
+func OptionsFromEnv() ([]Option, error) {
+	var opts []Option
+	if v := os.Getenv("SHUTDOWN_PRESTOP_DELAY"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("SHUTDOWN_PRESTOP_DELAY is invalid: %w", err)
+		}
+		opts = append(opts, WithPreStopDelay(d))
+	}
+	if v := os.Getenv("SHUTDOWN_DRAIN_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("SHUTDOWN_DRAIN_TIMEOUT is invalid: %w", err)
+		}
+		opts = append(opts, WithDrainTimeout(d))
+	}
+	return opts, nil
+}
+
+// New returns Manager.
+func New(logger *zap.Logger, opts ...Option) *Manager {
+	m := &Manager{
+		logger:       logger,
+		preStopDelay: defaultPreStopDelay,
+		drainTimeout: defaultDrainTimeout,
+	}
+	for _, f := range opts {
+		f(m)
+	}
+	return m
+}
+
+// OnNotReady registers f which is called first on shutdown.
+func (m *Manager) OnNotReady(f func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.notReady = append(m.notReady, f)
+}
+
+// Drain registers a server which drains in-flight requests until ctx is done.
+// Servers are drained concurrently.
+func (m *Manager) Drain(name string, fn func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.drainers = append(m.drainers, hook{name, fn})
+}
+
+// Close registers a dependency which is closed after all servers are drained.
+// Dependencies are closed in registration order.
+func (m *Manager) Close(name string, fn func() error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closers = append(m.closers, hook{name, func(context.Context) error { return fn() }})
+}
+
+// ShuttingDown reports whether Shutdown has been called.
+func (m *Manager) ShuttingDown() bool {
+	return atomic.LoadInt32(&m.shuttingDown) == 1
+}
+
+// Shutdown runs a graceful shutdown. It's safe to call only once.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&m.shuttingDown, 0, 1) {
+		return nil
+	}
+	m.mu.Lock()
+	notReady, drainers, closers := m.notReady, m.drainers, m.closers
+	m.mu.Unlock()
+
+	m.logger.Info("marking not ready")
+	for _, f := range notReady {
+		f()
+	}
+
+	if m.preStopDelay > 0 {
+		m.logger.Info("waiting for pre-stop delay", zap.Duration("delay", m.preStopDelay))
+		t := time.NewTimer(m.preStopDelay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+		case <-t.C:
+		}
+	}
+
+	m.logger.Info("draining in-flight requests", zap.Duration("timeout", m.drainTimeout))
+	dctx, cancel := context.WithTimeout(ctx, m.drainTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	errs := make([]error, len(drainers))
+	for i, d := range drainers {
+		wg.Add(1)
+		go func(i int, d hook) {
+			defer wg.Done()
+			if err := d.fn(dctx); err != nil {
+				m.logger.Error("failed to drain", zap.String("name", d.name), zap.Error(err))
+				errs[i] = fmt.Errorf("failed to drain %s: %w", d.name, err)
+			}
+		}(i, d)
+	}
+	wg.Wait()
+	err := multierr.Combine(errs...)
+
+	for _, c := range closers {
+		if cerr := c.fn(ctx); cerr != nil {
+			m.logger.Error("failed to close", zap.String("name", c.name), zap.Error(cerr))
+			err = multierr.Append(err, fmt.Errorf("failed to close %s: %w", c.name, cerr))
+		}
+	}
+	return err
+}
+
+// HTTPServer drains s by http.Server.Shutdown.
+func HTTPServer(s *http.Server) func(ctx context.Context) error {
+	return s.Shutdown
+}
+
+// GRPCServer drains s by grpc.Server.GracefulStop, and stops it forcibly when ctx is done.
+func GRPCServer(s *grpc.Server) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			s.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			s.Stop()
+			<-done
+			return ctx.Err()
+		}
+	}
+}
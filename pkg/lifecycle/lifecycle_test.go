@@ -0,0 +1,111 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestManager_Shutdown_InFlightRequests(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(entered)
+		<-release
+		io.WriteString(w, "done")
+	}))
+	ts.Start()
+	defer ts.Close()
+
+	var mu sync.Mutex
+	var events []string
+	record := func(e string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}
+
+	m := New(zap.NewNop(), WithPreStopDelay(10*time.Millisecond), WithDrainTimeout(5*time.Second))
+	m.OnNotReady(func() { record("not ready") })
+	m.Drain("http", func(ctx context.Context) error {
+		err := HTTPServer(ts.Config)(ctx)
+		record("drained")
+		return err
+	})
+	m.Close("db", func() error {
+		record("closed")
+		return nil
+	})
+
+	type response struct {
+		body string
+		err  error
+	}
+	resc := make(chan response, 1)
+	go func() {
+		res, err := http.Get(ts.URL)
+		if err != nil {
+			resc <- response{err: err}
+			return
+		}
+		defer res.Body.Close()
+		b, err := io.ReadAll(res.Body)
+		resc <- response{string(b), err}
+	}()
+	<-entered
+
+	shutdownc := make(chan error, 1)
+	go func() { shutdownc <- m.Shutdown(context.Background()) }()
+
+	// The in-flight request must be kept while draining.
+	time.Sleep(50 * time.Millisecond)
+	if !m.ShuttingDown() {
+		t.Error("ShuttingDown() = false, want true")
+	}
+	select {
+	case err := <-shutdownc:
+		t.Fatalf("Shutdown() returned before in-flight request completed: %v", err)
+	default:
+	}
+
+	close(release)
+	res := <-resc
+	if res.err != nil || res.body != "done" {
+		t.Fatalf("in-flight request = %q, %v, want done, nil", res.body, res.err)
+	}
+	if err := <-shutdownc; err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if want := []string{"not ready", "drained", "closed"}; !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+}
+
+func TestManager_Shutdown_DrainTimeout(t *testing.T) {
+	m := New(zap.NewNop(), WithDrainTimeout(10*time.Millisecond))
+	m.Drain("stuck", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	closed := false
+	m.Close("db", func() error {
+		closed = true
+		return nil
+	})
+
+	err := m.Shutdown(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if !closed {
+		t.Error("dependencies must be closed even if draining times out")
+	}
+}
@@ -0,0 +1,112 @@
+// Package logging builds zap loggers from configuration, whose level can be changed at runtime.
+package logging
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	EncodingJSON    = "json"
+	EncodingConsole = "console"
+)
+
+// Config configures a logger.
+type Config struct {
+	// Level is the initial level, which can be changed by zap.AtomicLevel returned by New.
+	Level zapcore.Level
+	// Encoding is either json or console.
+	Encoding string
+	// Sampling samples repeated entries in each second, as zap.NewProduction does.
+	Sampling bool
+	// OutputPaths are paths or URLs to write logs to, e.g. stderr or /var/log/dogfood.log.
+	OutputPaths []string
+}
+
+// DefaultConfig is equivalent to zap.NewProduction.
+var DefaultConfig = Config{
+	Level:       zapcore.InfoLevel,
+	Encoding:    EncodingJSON,
+	Sampling:    true,
+	OutputPaths: []string{"stderr"},
+}
+
+// ConfigFromEnv loads a config from LOG_LEVEL, LOG_ENCODING, LOG_SAMPLING and LOG_OUTPUT_PATHS,
+// a comma separated list. Unspecified ones are DefaultConfig.
+func ConfigFromEnv() (Config, error) {
+	c := DefaultConfig
+	c.OutputPaths = append([]string(nil), DefaultConfig.OutputPaths...)
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		if err := c.Level.UnmarshalText([]byte(v)); err != nil {
+			return c, fmt.Errorf("LOG_LEVEL is invalid: %w", err)
+		}
+	}
+	if v := os.Getenv("LOG_ENCODING"); v != "" {
+		if v != EncodingJSON && v != EncodingConsole {
+			return c, fmt.Errorf("LOG_ENCODING is invalid: %s is neither %s nor %s", v, EncodingJSON, EncodingConsole)
+		}
+		c.Encoding = v
+	}
+	if v := os.Getenv("LOG_SAMPLING"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return c, fmt.Errorf("LOG_SAMPLING is invalid: %w", err)
+		}
+		c.Sampling = b
+	}
+	if v := os.Getenv("LOG_OUTPUT_PATHS"); v != "" {
+		c.OutputPaths = nil
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				c.OutputPaths = append(c.OutputPaths, p)
+			}
+		}
+		if len(c.OutputPaths) == 0 {
+			return c, fmt.Errorf("LOG_OUTPUT_PATHS is invalid: %q has no path", v)
+		}
+	}
+	return c, nil
+}
+
+// New returns a logger configured by c, and its level to change at runtime.
+func New(c Config) (*zap.Logger, zap.AtomicLevel, error) {
+	zc := zap.NewProductionConfig()
+	zc.Level = zap.NewAtomicLevelAt(c.Level)
+	zc.Encoding = c.Encoding
+	if c.Encoding == EncodingConsole {
+		zc.EncoderConfig = zap.NewDevelopmentEncoderConfig()
+	}
+	if !c.Sampling {
+		zc.Sampling = nil
+	}
+	zc.OutputPaths = c.OutputPaths
+	l, err := zc.Build()
+	if err != nil {
+		return nil, zc.Level, fmt.Errorf("failed to build a logger: %w", err)
+	}
+	return l, zc.Level, nil
+}
+
+// NewFromEnv returns a logger configured by ConfigFromEnv.
+// If it fails, it returns a logger of DefaultConfig with the error, so that the error can be logged.
+func NewFromEnv() (*zap.Logger, zap.AtomicLevel, error) {
+	c, err := ConfigFromEnv()
+	if err == nil {
+		var l *zap.Logger
+		var level zap.AtomicLevel
+		if l, level, err = New(c); err == nil {
+			return l, level, nil
+		}
+	}
+	l, level, defaultErr := New(DefaultConfig)
+	if defaultErr != nil {
+		// stderr is always writable, so it never happens in practice.
+		l = zap.NewNop()
+	}
+	return l, level, err
+}
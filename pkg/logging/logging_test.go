@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    Config
+		wantErr bool
+	}{
+		{
+			name: "default",
+			want: DefaultConfig,
+		},
+		{
+			name: "configured",
+			env: map[string]string{
+				"LOG_LEVEL":        "debug",
+				"LOG_ENCODING":     "console",
+				"LOG_SAMPLING":     "false",
+				"LOG_OUTPUT_PATHS": "stdout, /tmp/dogfood.log",
+			},
+			want: Config{Level: zapcore.DebugLevel, Encoding: EncodingConsole, OutputPaths: []string{"stdout", "/tmp/dogfood.log"}},
+		},
+		{name: "invalid level", env: map[string]string{"LOG_LEVEL": "verbose"}, wantErr: true},
+		{name: "invalid encoding", env: map[string]string{"LOG_ENCODING": "xml"}, wantErr: true},
+		{name: "invalid sampling", env: map[string]string{"LOG_SAMPLING": "sometimes"}, wantErr: true},
+		{name: "no output path", env: map[string]string{"LOG_OUTPUT_PATHS": " , "}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range []string{"LOG_LEVEL", "LOG_ENCODING", "LOG_SAMPLING", "LOG_OUTPUT_PATHS"} {
+				t.Setenv(k, tt.env[k])
+			}
+			got, err := ConfigFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConfigFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ConfigFromEnv() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	l, level, err := New(DefaultConfig)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if l.Core().Enabled(zapcore.DebugLevel) {
+		t.Error("debug logs are enabled, want info")
+	}
+	level.SetLevel(zapcore.DebugLevel)
+	if !l.Core().Enabled(zapcore.DebugLevel) {
+		t.Error("debug logs are disabled after the level is changed")
+	}
+
+	if _, _, err := New(Config{Level: zapcore.InfoLevel, Encoding: EncodingJSON, OutputPaths: []string{"/nonexistent/dogfood.log"}}); err == nil {
+		t.Error("New() error = nil, want error for an unwritable path")
+	}
+}
@@ -0,0 +1,64 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type redisStreamsBroker struct {
+	client redis.UniversalClient
+	maxLen int64
+}
+
+// NewRedisStreamsBroker returns Broker which appends messages to a Redis stream named after the topic.
+// Streams are trimmed to about maxLen entries if maxLen is positive.
+func NewRedisStreamsBroker(client redis.UniversalClient, maxLen int64) Broker {
+	return &redisStreamsBroker{client, maxLen}
+}
+
+func (b *redisStreamsBroker) Publish(ctx context.Context, m *Message) error {
+	args := &redis.XAddArgs{
+		Stream: m.Topic,
+		Values: map[string]interface{}{
+			"event_id": m.EventID,
+			"key":      m.Key,
+			"payload":  m.Payload,
+		},
+	}
+	if b.maxLen > 0 {
+		args.MaxLen = b.maxLen
+		args.Approx = true
+	}
+	if err := b.client.XAdd(ctx, args).Err(); err != nil {
+		return fmt.Errorf("failed to publish %s to redis stream %s: %w", m.EventID, m.Topic, err)
+	}
+	return nil
+}
+
+// MemoryBroker keeps published messages in memory. It's for tests and a single process.
+type MemoryBroker struct {
+	mu       sync.Mutex
+	messages map[string][]*Message
+}
+
+// NewMemoryBroker returns MemoryBroker.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{messages: make(map[string][]*Message)}
+}
+
+func (b *MemoryBroker) Publish(_ context.Context, m *Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.messages[m.Topic] = append(b.messages[m.Topic], m)
+	return nil
+}
+
+// Messages returns messages published to topic in order.
+func (b *MemoryBroker) Messages(topic string) []*Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*Message(nil), b.messages[topic]...)
+}
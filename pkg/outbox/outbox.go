@@ -0,0 +1,133 @@
+// Package outbox publishes events to a message broker with the transactional outbox pattern.
+// Events are written to an outbox table in the same transaction as the change which emits them,
+// and Relay publishes them afterwards. An event is published at least once, so consumers must
+// deduplicate events by their IDs.
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Message is an event in the outbox.
+type Message struct {
+	// ID is a sequence number in the outbox, which orders messages.
+	ID      int64
+	EventID string
+	Topic   string
+	// Key partitions messages, e.g. a name of dog, if the broker supports it.
+	Key       string
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+// Broker publishes messages. NATS, Kafka and others are plugged in by implementing it.
+type Broker interface {
+	Publish(ctx context.Context, m *Message) error
+}
+
+// Store persists messages in the outbox.
+type Store interface {
+	// Add adds a message within tx, so that it's published if and only if tx is committed.
+	Add(ctx context.Context, tx *sql.Tx, m *Message) error
+	// Claim claims up to limit unpublished messages in order of ID, passes them to publish, which
+	// returns IDs of published messages, and removes the published ones from the outbox.
+	// Only one relay claims messages at once, so that they are published in order even if relays run
+	// on multiple replicas. publish is not called while another relay holds the claim.
+	// If removing fails, the messages are claimed and published again.
+	Claim(ctx context.Context, limit int, publish func(ms []*Message) []int64) error
+}
+
+// NewEventID returns a random ID of an event.
+func NewEventID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate an event ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// relayLockKey is a key of an advisory lock to claim messages.
+const relayLockKey int64 = 0x6f7574626f78 // outbox
+
+type psqlStore struct {
+	db *sql.DB
+}
+
+// NewPsqlStore returns Store backed by the outbox table of Postgres.
+func NewPsqlStore(db *sql.DB) Store {
+	return &psqlStore{db}
+}
+
+func (s *psqlStore) Add(ctx context.Context, tx *sql.Tx, m *Message) error {
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+	if err := tx.QueryRowContext(
+		ctx,
+		"INSERT INTO outbox (event_id, topic, key, payload, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		m.EventID, m.Topic, m.Key, m.Payload, m.CreatedAt,
+	).Scan(&m.ID); err != nil {
+		return fmt.Errorf("failed to add a message to outbox: %w", err)
+	}
+	return nil
+}
+
+// Claim holds a transaction-level advisory lock while messages are published, which is released when
+// the transaction ends, even if the replica dies. Rows are not claimed by FOR UPDATE SKIP LOCKED,
+// because another relay would skip the locked rows and publish later messages first.
+func (s *psqlStore) Claim(ctx context.Context, limit int, publish func(ms []*Message) []int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin a transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var claimed bool
+	if err := tx.QueryRowContext(ctx, "SELECT pg_try_advisory_xact_lock($1)", relayLockKey).Scan(&claimed); err != nil {
+		return fmt.Errorf("failed to claim outbox: %w", err)
+	}
+	if !claimed {
+		return nil
+	}
+	ms, err := fetch(ctx, tx, limit)
+	if err != nil {
+		return err
+	}
+	if ids := publish(ms); len(ids) > 0 {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM outbox WHERE id = ANY($1)", pq.Array(ids)); err != nil {
+			return fmt.Errorf("failed to ack messages: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to ack messages: %w", err)
+	}
+	return nil
+}
+
+func fetch(ctx context.Context, tx *sql.Tx, limit int) ([]*Message, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT id, event_id, topic, key, payload, created_at FROM outbox ORDER BY id LIMIT $1",
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch messages from outbox: %w", err)
+	}
+	defer rows.Close()
+	var ms []*Message
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(&m.ID, &m.EventID, &m.Topic, &m.Key, &m.Payload, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan messages: %w", err)
+		}
+		ms = append(ms, &m)
+	}
+	return ms, rows.Err()
+}
@@ -0,0 +1,161 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+type fakeStore struct {
+	messages []*Message
+	ackErr   error
+	// claimed means a relay of another replica holds the claim.
+	claimed bool
+}
+
+func (s *fakeStore) Add(context.Context, *sql.Tx, *Message) error {
+	return errors.New("not implemented")
+}
+
+func (s *fakeStore) Claim(_ context.Context, limit int, publish func(ms []*Message) []int64) error {
+	if s.claimed {
+		return nil
+	}
+	ms := s.messages
+	if len(ms) > limit {
+		ms = ms[:limit]
+	}
+	ids := publish(ms)
+	if s.ackErr != nil {
+		return s.ackErr
+	}
+	acked := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		acked[id] = true
+	}
+	var rest []*Message
+	for _, m := range s.messages {
+		if !acked[m.ID] {
+			rest = append(rest, m)
+		}
+	}
+	s.messages = rest
+	return nil
+}
+
+// flakyBroker fails to publish a message of failID once.
+type flakyBroker struct {
+	*MemoryBroker
+	failID int64
+}
+
+func (b *flakyBroker) Publish(ctx context.Context, m *Message) error {
+	if m.ID == b.failID {
+		b.failID = 0
+		return errors.New("broker is unavailable")
+	}
+	return b.MemoryBroker.Publish(ctx, m)
+}
+
+func newMessages(n int) []*Message {
+	ms := make([]*Message, n)
+	for i := range ms {
+		ms[i] = &Message{ID: int64(i + 1), EventID: string(rune('a' + i)), Topic: "t"}
+	}
+	return ms
+}
+
+func eventIDs(ms []*Message) string {
+	var ids string
+	for _, m := range ms {
+		ids += m.EventID
+	}
+	return ids
+}
+
+func TestRelay_RelayOnce(t *testing.T) {
+	store := &fakeStore{messages: newMessages(5)}
+	broker := &flakyBroker{MemoryBroker: NewMemoryBroker(), failID: 3}
+	r := NewRelay(store, broker, zap.NewNop(), WithBatchSize(10))
+
+	// Publishing stops at the failure to keep the order.
+	if n, err := r.RelayOnce(context.Background()); n != 2 || err == nil {
+		t.Fatalf("RelayOnce() = %d, %v, want 2, error", n, err)
+	}
+	if n, err := r.RelayOnce(context.Background()); n != 3 || err != nil {
+		t.Fatalf("RelayOnce() = %d, %v, want 3, nil", n, err)
+	}
+	if got := eventIDs(broker.Messages("t")); got != "abcde" {
+		t.Errorf("published %s, want abcde", got)
+	}
+	if len(store.messages) != 0 {
+		t.Errorf("%d messages are left in outbox, want 0", len(store.messages))
+	}
+}
+
+func TestRelay_AtLeastOnce(t *testing.T) {
+	store := &fakeStore{messages: newMessages(2), ackErr: errors.New("database is unavailable")}
+	broker := NewMemoryBroker()
+	r := NewRelay(store, broker, zap.NewNop())
+
+	if _, err := r.RelayOnce(context.Background()); err == nil {
+		t.Fatal("RelayOnce() error = nil, want error")
+	}
+	store.ackErr = nil
+	if _, err := r.RelayOnce(context.Background()); err != nil {
+		t.Fatalf("RelayOnce() error = %v", err)
+	}
+	// Messages which failed to be acked are published again.
+	if got := eventIDs(broker.Messages("t")); got != "abab" {
+		t.Errorf("published %s, want abab", got)
+	}
+}
+
+func TestRelay_Claimed(t *testing.T) {
+	store := &fakeStore{messages: newMessages(2), claimed: true}
+	broker := NewMemoryBroker()
+	r := NewRelay(store, broker, zap.NewNop())
+
+	if n, err := r.RelayOnce(context.Background()); n != 0 || err != nil {
+		t.Fatalf("RelayOnce() = %d, %v, want 0, nil", n, err)
+	}
+	if got := broker.Messages("t"); len(got) != 0 {
+		t.Errorf("published %s while another relay holds the claim, want nothing", eventIDs(got))
+	}
+	store.claimed = false
+	if n, err := r.RelayOnce(context.Background()); n != 2 || err != nil {
+		t.Fatalf("RelayOnce() = %d, %v, want 2, nil", n, err)
+	}
+}
+
+func TestRedisStreamsBroker(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer c.Close()
+
+	b := NewRedisStreamsBroker(c, 100)
+	for _, m := range newMessages(2) {
+		m.Key = "pochi"
+		m.Payload = []byte{0x0a, 0x01}
+		if err := b.Publish(context.Background(), m); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+
+	entries, err := c.XRange(context.Background(), "t", "-", "+").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("stream has %d entries, want 2", len(entries))
+	}
+	v := entries[1].Values
+	if v["event_id"] != "b" || v["key"] != "pochi" || v["payload"] != "\x0a\x01" {
+		t.Errorf("entry = %v", v)
+	}
+}
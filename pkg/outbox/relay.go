@@ -0,0 +1,99 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPollInterval = time.Second
+	defaultBatchSize    = 100
+)
+
+// Relay publishes messages in the outbox to a broker.
+type Relay struct {
+	store        Store
+	broker       Broker
+	logger       *zap.Logger
+	pollInterval time.Duration
+	batchSize    int
+}
+
+// RelayOption configures Relay.
+type RelayOption func(r *Relay)
+
+// WithPollInterval sets an interval to look for unpublished messages.
+func WithPollInterval(d time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.pollInterval = d
+	}
+}
+
+// WithBatchSize sets the maximum number of messages published at once.
+func WithBatchSize(n int) RelayOption {
+	return func(r *Relay) {
+		r.batchSize = n
+	}
+}
+
+// NewRelay returns Relay.
+func NewRelay(store Store, broker Broker, logger *zap.Logger, opts ...RelayOption) *Relay {
+	r := &Relay{
+		store:        store,
+		broker:       broker,
+		logger:       logger,
+		pollInterval: defaultPollInterval,
+		batchSize:    defaultBatchSize,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run publishes messages every poll interval until ctx is done.
+// It keeps publishing without waiting while the outbox has a backlog.
+func (r *Relay) Run(ctx context.Context) {
+	t := time.NewTicker(r.pollInterval)
+	defer t.Stop()
+	for {
+		n, err := r.RelayOnce(ctx)
+		if err != nil {
+			r.logger.Error("failed to relay messages in outbox", zap.Error(err))
+		}
+		if err == nil && n == r.batchSize {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// RelayOnce publishes a batch of messages in order, and returns how many of them were published.
+// It stops at the first failure to keep the order, and the rest are published next time.
+// Messages are acked after they are published, so they are published again if acking fails.
+// It publishes nothing while a relay of another replica is publishing.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	var published []int64
+	var perr error
+	if err := r.store.Claim(ctx, r.batchSize, func(ms []*Message) []int64 {
+		for _, m := range ms {
+			if perr = r.broker.Publish(ctx, m); perr != nil {
+				break
+			}
+			published = append(published, m.ID)
+		}
+		return published
+	}); err != nil {
+		return 0, err
+	}
+	return len(published), perr
+}
@@ -0,0 +1,229 @@
+// Package recordio reads and writes records as CSV or NDJSON.
+//
+// CSV has a header of dog_name, dogfood_name, gram and eaten_at in any order,
+// and NDJSON has a record in JSON per line. eaten_at is in RFC 3339.
+package recordio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	ContentTypeCSV    = "text/csv"
+	ContentTypeNDJSON = "application/x-ndjson"
+)
+
+var csvHeader = []string{"dog_name", "dogfood_name", "gram", "eaten_at"}
+
+// Negotiate returns a content type by Accept, which defaults to CSV.
+// It returns false if neither CSV nor NDJSON is acceptable.
+func Negotiate(accept string) (string, bool) {
+	if accept == "" {
+		return ContentTypeCSV, true
+	}
+	for _, v := range strings.Split(accept, ",") {
+		mt, _, err := mime.ParseMediaType(strings.TrimSpace(v))
+		if err != nil {
+			continue
+		}
+		switch mt {
+		case ContentTypeCSV, "*/*", "text/*":
+			return ContentTypeCSV, true
+		case ContentTypeNDJSON, "application/ndjson", "application/jsonl":
+			return ContentTypeNDJSON, true
+		}
+	}
+	return "", false
+}
+
+// Writer writes records.
+type Writer interface {
+	Write(r *dogfoodpb.Record) error
+	// Flush writes buffered records.
+	Flush() error
+}
+
+// NewWriter returns Writer of contentType, which is ContentTypeCSV or ContentTypeNDJSON.
+func NewWriter(contentType string, w io.Writer) Writer {
+	if contentType == ContentTypeNDJSON {
+		return &ndjsonWriter{w}
+	}
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+type csvWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func (w *csvWriter) writeHeader() error {
+	if w.wroteHeader {
+		return nil
+	}
+	w.wroteHeader = true
+	return w.w.Write(csvHeader)
+}
+
+func (w *csvWriter) Write(r *dogfoodpb.Record) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	return w.w.Write([]string{
+		r.GetDogName(),
+		r.GetDogfoodName(),
+		strconv.Itoa(int(r.GetGram())),
+		r.GetEatenAt().AsTime().Format(time.RFC3339Nano),
+	})
+}
+
+func (w *csvWriter) Flush() error {
+	// The header is written even if there are no records.
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type ndjsonWriter struct {
+	w io.Writer
+}
+
+func (w *ndjsonWriter) Write(r *dogfoodpb.Record) error {
+	b, err := protojson.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = w.w.Write(append(b, '\n'))
+	return err
+}
+
+func (w *ndjsonWriter) Flush() error { return nil }
+
+// Row is a row read by Reader.
+type Row struct {
+	Line   int64
+	Record *dogfoodpb.Record
+	// Err is an error of the row, e.g. gram is not a number. The other rows are still readable.
+	Err error
+}
+
+// Reader reads rows. Read returns io.EOF when there are no more rows.
+type Reader interface {
+	Read() (*Row, error)
+}
+
+// NewReader returns Reader of contentType, which is ContentTypeCSV or ContentTypeNDJSON.
+func NewReader(contentType string, r io.Reader) (Reader, error) {
+	switch contentType {
+	case ContentTypeCSV:
+		return newCSVReader(r)
+	case ContentTypeNDJSON:
+		return &ndjsonReader{s: bufio.NewScanner(r)}, nil
+	}
+	return nil, fmt.Errorf("content type %s is not supported", contentType)
+}
+
+type csvReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read a header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, h := range header {
+		columns[strings.TrimSpace(h)] = i
+	}
+	for _, h := range csvHeader {
+		if _, ok := columns[h]; !ok {
+			return nil, fmt.Errorf("column %s is missing in a header", h)
+		}
+	}
+	return &csvReader{cr, columns}, nil
+}
+
+func (r *csvReader) Read() (*Row, error) {
+	fields, err := r.r.Read()
+	line, _ := r.r.FieldPos(0)
+	var perr *csv.ParseError
+	if errors.As(err, &perr) {
+		return &Row{Line: int64(perr.StartLine), Err: perr.Err}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	row := &Row{Line: int64(line)}
+	if len(fields) < len(r.columns) {
+		row.Err = fmt.Errorf("row has %d columns, want %d", len(fields), len(r.columns))
+		return row, nil
+	}
+	row.Record, row.Err = parseRecord(
+		fields[r.columns["dog_name"]],
+		fields[r.columns["dogfood_name"]],
+		fields[r.columns["gram"]],
+		fields[r.columns["eaten_at"]],
+	)
+	return row, nil
+}
+
+func parseRecord(dogName, dogfoodName, gram, eatenAt string) (*dogfoodpb.Record, error) {
+	g, err := strconv.ParseInt(strings.TrimSpace(gram), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("gram %q is not a number", gram)
+	}
+	t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(eatenAt))
+	if err != nil {
+		return nil, fmt.Errorf("eaten_at %q is not RFC 3339", eatenAt)
+	}
+	return &dogfoodpb.Record{
+		DogName:     dogName,
+		DogfoodName: dogfoodName,
+		Gram:        int32(g),
+		EatenAt:     timestamppb.New(t),
+	}, nil
+}
+
+type ndjsonReader struct {
+	s    *bufio.Scanner
+	line int64
+}
+
+func (r *ndjsonReader) Read() (*Row, error) {
+	for r.s.Scan() {
+		r.line++
+		b := bytes.TrimSpace(r.s.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		row := &Row{Line: r.line, Record: &dogfoodpb.Record{}}
+		if err := protojson.Unmarshal(b, row.Record); err != nil {
+			row.Record = nil
+			row.Err = fmt.Errorf("line is not a record: %w", err)
+		}
+		return row, nil
+	}
+	if err := r.s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
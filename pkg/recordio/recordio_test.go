@@ -0,0 +1,155 @@
+package recordio
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type wantRow struct {
+	line    int64
+	dogName string
+	wantErr bool
+}
+
+func readAll(t *testing.T, r Reader) []wantRow {
+	t.Helper()
+	var rows []wantRow
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return rows
+		}
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		rows = append(rows, wantRow{row.Line, row.Record.GetDogName(), row.Err != nil})
+	}
+}
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		in          string
+		want        []wantRow
+		wantErr     bool
+	}{
+		{
+			name:        "csv",
+			contentType: ContentTypeCSV,
+			in: "eaten_at,dog_name,dogfood_name,gram\n" +
+				"2021-12-01T08:00:00Z,pochi,a,100\n" +
+				"2021-12-01T09:00:00Z,hachi,a,abc\n" +
+				"2021-12-01,hachi,a,100\n" +
+				"2021-12-01T10:00:00Z,hachi,\"b\nc\",100\n" +
+				"2021-12-01T11:00:00Z,hachi\n",
+			want: []wantRow{
+				{line: 2, dogName: "pochi"},
+				{line: 3, wantErr: true},
+				{line: 4, wantErr: true},
+				{line: 5, dogName: "hachi"},
+				{line: 7, wantErr: true},
+			},
+		},
+		{
+			name:        "csv without a column",
+			contentType: ContentTypeCSV,
+			in:          "dog_name,dogfood_name,gram\n",
+			wantErr:     true,
+		},
+		{
+			name:        "ndjson",
+			contentType: ContentTypeNDJSON,
+			in: `{"dogName":"pochi","dogfoodName":"a","gram":100,"eatenAt":"2021-12-01T08:00:00Z"}` + "\n" +
+				"\n" +
+				`{"dogName":"hachi","gram":"abc"}` + "\n" +
+				`{"dog_name":"hachi","dogfood_name":"a","gram":100,"eaten_at":"2021-12-01T08:00:00Z"}`,
+			want: []wantRow{
+				{line: 1, dogName: "pochi"},
+				{line: 3, wantErr: true},
+				{line: 4, dogName: "hachi"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReader(tt.contentType, strings.NewReader(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewReader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got := readAll(t, r)
+			if len(got) != len(tt.want) {
+				t.Fatalf("rows = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("row[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	records := []*dogfoodpb.Record{
+		{DogName: "pochi", DogfoodName: "a", Gram: 100, EatenAt: timestamppb.New(time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC))},
+		{DogName: "hachi", DogfoodName: "b,c", Gram: 50, EatenAt: timestamppb.New(time.Date(2021, 12, 1, 9, 0, 0, 1, time.UTC))},
+	}
+	for _, ct := range []string{ContentTypeCSV, ContentTypeNDJSON} {
+		t.Run(ct, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(ct, &buf)
+			for _, r := range records {
+				if err := w.Write(r); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+
+			r, err := NewReader(ct, &buf)
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+			for i, want := range records {
+				row, err := r.Read()
+				if err != nil || row.Err != nil {
+					t.Fatalf("Read() = %v, %v", row, err)
+				}
+				if !proto.Equal(row.Record, want) {
+					t.Errorf("record[%d] = %v, want %v", i, row.Record, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+		wantOK bool
+	}{
+		{"", ContentTypeCSV, true},
+		{"*/*", ContentTypeCSV, true},
+		{"application/x-ndjson", ContentTypeNDJSON, true},
+		{"application/xml, text/csv; charset=utf-8", ContentTypeCSV, true},
+		{"application/xml", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := Negotiate(tt.accept); got != tt.want || ok != tt.wantOK {
+			t.Errorf("Negotiate(%q) = %s, %v, want %s, %v", tt.accept, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Package requestid propagates request IDs across the gateway, the gRPC gateway and the backend,
+// so that their logs are correlated even without traces.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Header is a header of request IDs, which is accepted from clients and echoed in responses.
+	Header = "X-Request-Id"
+	// MetadataKey is gRPC metadata of request IDs.
+	MetadataKey = "x-request-id"
+	// maxLength is the max length of accepted request IDs, so that logs are not flooded by clients.
+	maxLength = 128
+)
+
+type contextKey struct{}
+
+// New returns a random request ID.
+func New() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// valid reports whether id is accepted, which consists of at most maxLength printable ASCII characters.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// orNew returns id if it's valid, or a new one otherwise.
+func orNew(id string) string {
+	if valid(id) {
+		return id
+	}
+	return New()
+}
+
+// WithContext returns a context with a request ID.
+func WithContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns a request ID in ctx, or an empty string if it's not set.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// LogFields returns zap fields of a request ID in ctx if any.
+func LogFields(ctx context.Context) []zap.Field {
+	if id := FromContext(ctx); id != "" {
+		return []zap.Field{zap.String("request_id", id)}
+	}
+	return nil
+}
+
+// Metadata returns gRPC metadata to forward a request ID in ctx.
+func Metadata(ctx context.Context) metadata.MD {
+	if id := FromContext(ctx); id != "" {
+		return metadata.Pairs(MetadataKey, id)
+	}
+	return metadata.MD{}
+}
+
+// Middleware accepts a request ID from the header, or generates one if it's missing or invalid.
+// The ID is set to the context and the header of requests, so that it's forwarded to upstreams,
+// and echoed in responses.
+func Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := orNew(r.Header.Get(Header))
+		r.Header.Set(Header, id)
+		w.Header().Set(Header, id)
+		h.ServeHTTP(w, r.WithContext(WithContext(r.Context(), id)))
+	})
+}
+
+// fromIncomingContext accepts a request ID from incoming metadata, or generates one.
+// It's sent back as header metadata.
+func fromIncomingContext(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var id string
+	if vs := md.Get(MetadataKey); len(vs) > 0 {
+		id = vs[0]
+	}
+	id = orNew(id)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(MetadataKey, id))
+	return WithContext(ctx, id)
+}
+
+// withDetails adds a request ID to details of err if err is a status error.
+func withDetails(err error, id string) error {
+	st, ok := status.FromError(err)
+	if !ok || err == nil {
+		return err
+	}
+	withID, detailsErr := st.WithDetails(&errdetails.RequestInfo{RequestId: id})
+	if detailsErr != nil {
+		return err
+	}
+	return withID.Err()
+}
+
+// UnaryServerInterceptor propagates request IDs of unary RPCs, and adds them to details of errors.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx = fromIncomingContext(ctx)
+		resp, err := handler(ctx, req)
+		return resp, withDetails(err, FromContext(ctx))
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// StreamServerInterceptor propagates request IDs of streaming RPCs, and adds them to details of errors.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := fromIncomingContext(ss.Context())
+		return withDetails(handler(srv, &serverStream{ServerStream: ss, ctx: ctx}), FromContext(ctx))
+	}
+}
@@ -0,0 +1,76 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		generate bool
+	}{
+		{"accepted", "abc-123", false},
+		{"missing", "", true},
+		{"too long", strings.Repeat("a", maxLength+1), true},
+		{"not printable", "abc\x00", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got, forwarded string
+			h := Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				got = FromContext(r.Context())
+				forwarded = r.Header.Get(Header)
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set(Header, tt.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if tt.generate && (got == tt.header || len(got) != 32) {
+				t.Errorf("request ID = %q, want a generated one", got)
+			}
+			if !tt.generate && got != tt.header {
+				t.Errorf("request ID = %q, want %q", got, tt.header)
+			}
+			if forwarded != got || w.Header().Get(Header) != got {
+				t.Errorf("forwarded %q and echoed %q, want %q", forwarded, w.Header().Get(Header), got)
+			}
+		})
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataKey, "abc-123"))
+	var got string
+	_, err := UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		got = FromContext(ctx)
+		return nil, status.Error(codes.NotFound, "not found")
+	})
+	if got != "abc-123" {
+		t.Errorf("request ID = %q, want abc-123", got)
+	}
+	st := status.Convert(err)
+	if st.Code() != codes.NotFound {
+		t.Errorf("code = %s, want %s", st.Code(), codes.NotFound)
+	}
+	details := st.Details()
+	if len(details) != 1 {
+		t.Fatalf("details = %v, want a request info", details)
+	}
+	if info, ok := details[0].(*errdetails.RequestInfo); !ok || info.GetRequestId() != "abc-123" {
+		t.Errorf("details = %v, want request_id abc-123", details)
+	}
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+)
+
+// AuditAction is a kind of mutation of a record.
+type AuditAction string
+
+const (
+	AuditCreate  AuditAction = "create"
+	AuditImport  AuditAction = "import"
+	AuditUpdate  AuditAction = "update"
+	AuditDelete  AuditAction = "delete"
+	AuditRestore AuditAction = "restore"
+	AuditPurge   AuditAction = "purge"
+)
+
+// unknownActor is recorded when a mutation is not made on behalf of anyone.
+const unknownActor = "unknown"
+
+// Actor is who mutates records in which request, which is recorded in audit events.
+type Actor struct {
+	Name      string
+	RequestID string
+}
+
+type actorKey struct{}
+
+// WithActor returns a context in which records are mutated by a.
+func WithActor(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, a)
+}
+
+// ActorFromContext returns an actor set by WithActor. Its name is unknown if it's not set.
+func ActorFromContext(ctx context.Context) Actor {
+	a, _ := ctx.Value(actorKey{}).(Actor)
+	if a.Name == "" {
+		a.Name = unknownActor
+	}
+	return a
+}
+
+// AuditEvent is a mutation of a record. Events are append-only.
+type AuditEvent struct {
+	ID        int64
+	RecordID  int64
+	Action    AuditAction
+	Actor     string
+	RequestID string
+	// Before is nil if the record is created or restored.
+	Before *dogfoodpb.Record
+	// After is nil if the record is deleted or purged.
+	After      *dogfoodpb.Record
+	OccurredAt time.Time
+}
+
+// AuditFilter filters audit events. Zero values match any.
+type AuditFilter struct {
+	RecordID int64
+	Actor    string
+	// BeforeID matches events older than it, which paginates events.
+	BeforeID int64
+	PageSize int
+}
+
+func newAuditEvent(ctx context.Context, action AuditAction, recordID int64, before, after *dogfoodpb.Record) *AuditEvent {
+	a := ActorFromContext(ctx)
+	return &AuditEvent{
+		RecordID:   recordID,
+		Action:     action,
+		Actor:      a.Name,
+		RequestID:  a.RequestID,
+		Before:     before,
+		After:      after,
+		OccurredAt: time.Now(),
+	}
+}
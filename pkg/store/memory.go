@@ -0,0 +1,341 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type recordKey struct {
+	dogfoodName string
+	dogName     string
+	eatenAt     time.Time
+}
+
+func keyOf(r *dogfoodpb.Record) recordKey {
+	return recordKey{r.GetDogfoodName(), r.GetDogName(), r.GetEatenAt().AsTime()}
+}
+
+// MemoryStore is Store which holds records in memory. It's lost when the process exits.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	nextID  int64
+	records []*dogfoodpb.Record // which are not deleted, in order of eaten_at
+	live    map[int64]*dogfoodpb.Record
+	deleted map[int64]*dogfoodpb.Record
+	keys    map[recordKey]bool
+	audit   []*AuditEvent // in order of ID
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		live:    make(map[int64]*dogfoodpb.Record),
+		deleted: make(map[int64]*dogfoodpb.Record),
+		keys:    make(map[recordKey]bool),
+	}
+}
+
+func cloneRecord(r *dogfoodpb.Record) *dogfoodpb.Record {
+	return proto.Clone(r).(*dogfoodpb.Record)
+}
+
+// normalize copies r with eaten_at in microseconds and UTC, as Postgres stores it.
+func normalize(r *dogfoodpb.Record) *dogfoodpb.Record {
+	c := cloneRecord(r)
+	c.EatenAt = timestamppb.New(r.GetEatenAt().AsTime().UTC().Truncate(time.Microsecond))
+	return c
+}
+
+// insert inserts r after records eaten at the same time, with a new ID unless it has one.
+// It returns nil if a record identical to r exists. mu must be locked.
+func (s *MemoryStore) insert(r *dogfoodpb.Record) *dogfoodpb.Record {
+	r = normalize(r)
+	k := keyOf(r)
+	if s.keys[k] {
+		return nil
+	}
+	if r.Id == 0 {
+		s.nextID++
+		r.Id = s.nextID
+	}
+	s.keys[k] = true
+	s.live[r.Id] = r
+	i := sort.Search(len(s.records), func(i int) bool {
+		return s.records[i].GetEatenAt().AsTime().After(k.eatenAt)
+	})
+	s.records = append(s.records, nil)
+	copy(s.records[i+1:], s.records[i:])
+	s.records[i] = r
+	return r
+}
+
+// remove removes a record which is not deleted from records. mu must be locked.
+func (s *MemoryStore) remove(r *dogfoodpb.Record) {
+	delete(s.keys, keyOf(r))
+	delete(s.live, r.Id)
+	i := sort.Search(len(s.records), func(i int) bool {
+		return !s.records[i].GetEatenAt().AsTime().Before(r.GetEatenAt().AsTime())
+	})
+	for ; i < len(s.records); i++ {
+		if s.records[i].Id == r.Id {
+			s.records = append(s.records[:i], s.records[i+1:]...)
+			return
+		}
+	}
+}
+
+// addAuditEvent appends e with a new ID. mu must be locked.
+func (s *MemoryStore) addAuditEvent(e *AuditEvent) {
+	e.ID = int64(len(s.audit)) + 1
+	if e.Before != nil {
+		e.Before = cloneRecord(e.Before)
+	}
+	if e.After != nil {
+		e.After = cloneRecord(e.After)
+	}
+	s.audit = append(s.audit, e)
+}
+
+// CreateRecord creates r. onCreated is called with a nil transaction while the store is locked,
+// and r is not created if it fails.
+func (s *MemoryStore) CreateRecord(ctx context.Context, r *dogfoodpb.Record, onCreated OnCreated) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys[keyOf(normalize(r))] {
+		return ErrAlreadyExists
+	}
+	if onCreated != nil {
+		if err := onCreated(ctx, nil); err != nil {
+			return err
+		}
+	}
+	created := s.insert(r)
+	r.Id = created.Id
+	s.addAuditEvent(newAuditEvent(ctx, AuditCreate, created.Id, nil, created))
+	return nil
+}
+
+func (s *MemoryStore) UpdateRecord(ctx context.Context, id int64, update func(r *dogfoodpb.Record) error) (*dogfoodpb.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	before, ok := s.live[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	after := cloneRecord(before)
+	if err := update(after); err != nil {
+		return nil, err
+	}
+	after.Id = id
+	after = normalize(after)
+	if k := keyOf(after); k != keyOf(before) && s.keys[k] {
+		return nil, ErrAlreadyExists
+	}
+	s.remove(before)
+	s.insert(after)
+	s.addAuditEvent(newAuditEvent(ctx, AuditUpdate, id, before, after))
+	return cloneRecord(after), nil
+}
+
+func (s *MemoryStore) DeleteRecord(ctx context.Context, id int64, purge bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	action := AuditDelete
+	if purge {
+		action = AuditPurge
+	}
+	if r, ok := s.live[id]; ok {
+		s.remove(r)
+		if !purge {
+			s.deleted[id] = r
+		}
+		s.addAuditEvent(newAuditEvent(ctx, action, id, r, nil))
+		return nil
+	}
+	if r, ok := s.deleted[id]; ok && purge {
+		delete(s.deleted, id)
+		s.addAuditEvent(newAuditEvent(ctx, action, id, r, nil))
+		return nil
+	}
+	return ErrNotFound
+}
+
+func (s *MemoryStore) RestoreRecord(ctx context.Context, id int64) (*dogfoodpb.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.deleted[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if s.insert(r) == nil {
+		return nil, ErrAlreadyExists
+	}
+	delete(s.deleted, id)
+	s.addAuditEvent(newAuditEvent(ctx, AuditRestore, id, nil, r))
+	return cloneRecord(r), nil
+}
+
+func (s *MemoryStore) ListAuditEvents(_ context.Context, f AuditFilter) ([]*AuditEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var events []*AuditEvent
+	for i := len(s.audit) - 1; i >= 0 && len(events) < f.PageSize; i-- {
+		e := s.audit[i]
+		if (f.RecordID != 0 && e.RecordID != f.RecordID) || (f.Actor != "" && e.Actor != f.Actor) ||
+			(f.BeforeID != 0 && e.ID >= f.BeforeID) {
+			continue
+		}
+		c := *e
+		events = append(events, &c)
+	}
+	return events, nil
+}
+
+func (s *MemoryStore) ListRecords(_ context.Context, from, to time.Time, limit int32) ([]*dogfoodpb.Record, error) {
+	var rs []*dogfoodpb.Record
+	s.each(ExportFilter{From: from, To: to}, func(r *dogfoodpb.Record) bool {
+		if int32(len(rs)) >= limit {
+			return false
+		}
+		rs = append(rs, r)
+		return true
+	})
+	return rs, nil
+}
+
+func (s *MemoryStore) ExportRecords(_ context.Context, f ExportFilter, fn func(*dogfoodpb.Record) error) error {
+	// Records are copied first, so that fn never blocks writers.
+	var rs []*dogfoodpb.Record
+	s.each(f, func(r *dogfoodpb.Record) bool {
+		rs = append(rs, r)
+		return true
+	})
+	for _, r := range rs {
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// each calls fn with copies of records matching f in order of eaten_at until it returns false.
+func (s *MemoryStore) each(f ExportFilter, fn func(*dogfoodpb.Record) bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := sort.Search(len(s.records), func(i int) bool {
+		return !s.records[i].GetEatenAt().AsTime().Before(f.From)
+	})
+	for ; i < len(s.records); i++ {
+		r := s.records[i]
+		if !r.GetEatenAt().AsTime().Before(f.To) {
+			return
+		}
+		if (f.DogName != "" && r.GetDogName() != f.DogName) || (f.DogfoodName != "" && r.GetDogfoodName() != f.DogfoodName) {
+			continue
+		}
+		if !fn(cloneRecord(r)) {
+			return
+		}
+	}
+}
+
+func (s *MemoryStore) RecordExists(_ context.Context, r *dogfoodpb.Record) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys[keyOf(normalize(r))], nil
+}
+
+func (s *MemoryStore) BeginImport(_ context.Context, atomic bool) (Import, error) {
+	return &memoryImport{s: s, atomic: atomic, pendingKeys: make(map[recordKey]bool)}, nil
+}
+
+type memoryImport struct {
+	s      *MemoryStore
+	atomic bool
+	// pending are records to be inserted on commit in order if the import is atomic.
+	pending     []*dogfoodpb.Record
+	pendingKeys map[recordKey]bool
+	// ctx is the last context passed to Insert, whose actor audits records inserted on commit.
+	ctx context.Context
+}
+
+func (im *memoryImport) Insert(ctx context.Context, r *dogfoodpb.Record) (bool, error) {
+	im.s.mu.Lock()
+	defer im.s.mu.Unlock()
+	if !im.atomic {
+		inserted := im.s.insert(r)
+		if inserted == nil {
+			return false, nil
+		}
+		im.s.addAuditEvent(newAuditEvent(ctx, AuditImport, inserted.Id, nil, inserted))
+		return true, nil
+	}
+	r = normalize(r)
+	k := keyOf(r)
+	if im.pendingKeys[k] || im.s.keys[k] {
+		return false, nil
+	}
+	im.pendingKeys[k] = true
+	im.pending = append(im.pending, r)
+	im.ctx = ctx
+	return true, nil
+}
+
+func (im *memoryImport) Commit() error {
+	im.s.mu.Lock()
+	defer im.s.mu.Unlock()
+	for _, r := range im.pending {
+		// Records created since they are pending are skipped, as Postgres does on conflict.
+		if inserted := im.s.insert(r); inserted != nil {
+			im.s.addAuditEvent(newAuditEvent(im.ctx, AuditImport, inserted.Id, nil, inserted))
+		}
+	}
+	im.pending = nil
+	return nil
+}
+
+func (im *memoryImport) Rollback() error {
+	im.pending = nil
+	return nil
+}
+
+func (s *MemoryStore) DogStats(_ context.Context, since time.Time) ([]*DogStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	byDog := make(map[string]*DogStats)
+	var stats []*DogStats
+	for _, r := range s.records {
+		st, ok := byDog[r.GetDogName()]
+		if !ok {
+			st = &DogStats{DogName: r.GetDogName()}
+			byDog[r.GetDogName()] = st
+			stats = append(stats, st)
+		}
+		t := r.GetEatenAt().AsTime()
+		if t.After(st.LastMeal) {
+			st.LastMeal = t
+		}
+		if !t.Before(since) {
+			st.Grams += float64(r.GetGram())
+		}
+	}
+	return stats, nil
+}
+
+func (s *MemoryStore) CountRecords(context.Context) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return int64(len(s.records)), nil
+}
+
+func (s *MemoryStore) Ping(context.Context) error {
+	return nil
+}
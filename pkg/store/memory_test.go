@@ -0,0 +1,230 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func record(dog string, gram int32, eatenAt time.Time) *dogfoodpb.Record {
+	return &dogfoodpb.Record{DogfoodName: "a", Gram: gram, DogName: dog, EatenAt: timestamppb.New(eatenAt)}
+}
+
+func TestMemoryStore_Records(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	for _, r := range []*dogfoodpb.Record{
+		record("pochi", 100, start.Add(time.Hour)),
+		record("hachi", 50, start),
+		record("pochi", 30, start),
+	} {
+		if err := s.CreateRecord(ctx, r, nil); err != nil {
+			t.Fatalf("CreateRecord() error = %v", err)
+		}
+	}
+	if err := s.CreateRecord(ctx, record("pochi", 1, start.Add(time.Hour+time.Nanosecond)), nil); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("CreateRecord() of a duplicate in microseconds error = %v, want %v", err, ErrAlreadyExists)
+	}
+	failed := errors.New("failed")
+	if err := s.CreateRecord(ctx, record("kuro", 1, start), func(context.Context, *sql.Tx) error { return failed }); !errors.Is(err, failed) {
+		t.Errorf("CreateRecord() error = %v, want %v", err, failed)
+	}
+
+	rs, err := s.ListRecords(ctx, start, start.Add(2*time.Hour), 2)
+	if err != nil {
+		t.Fatalf("ListRecords() error = %v", err)
+	}
+	if len(rs) != 2 || rs[0].GetDogName() != "hachi" || rs[1].GetDogName() != "pochi" || rs[1].GetGram() != 30 {
+		t.Errorf("ListRecords() = %v, want records eaten at the same time in order of creation", rs)
+	}
+
+	var exported []*dogfoodpb.Record
+	if err := s.ExportRecords(ctx, ExportFilter{From: start, To: start.Add(2 * time.Hour), DogName: "pochi"}, func(r *dogfoodpb.Record) error {
+		exported = append(exported, r)
+		return nil
+	}); err != nil {
+		t.Fatalf("ExportRecords() error = %v", err)
+	}
+	if len(exported) != 2 || exported[0].GetGram() != 30 || exported[1].GetGram() != 100 {
+		t.Errorf("ExportRecords() = %v, want records of pochi", exported)
+	}
+
+	stats, err := s.DogStats(ctx, start.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("DogStats() error = %v", err)
+	}
+	for _, st := range stats {
+		if st.DogName == "pochi" && (st.Grams != 100 || !st.LastMeal.Equal(start.Add(time.Hour))) {
+			t.Errorf("DogStats() of pochi = %+v", st)
+		}
+	}
+	if n, _ := s.CountRecords(ctx); n != 3 {
+		t.Errorf("CountRecords() = %d, want 3", n)
+	}
+}
+
+func TestMemoryStore_Import(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		atomic    bool
+		commit    bool
+		wantCount int64
+	}{
+		{
+			name:      "non-atomic",
+			wantCount: 3,
+		},
+		{
+			name:      "atomic commit",
+			atomic:    true,
+			commit:    true,
+			wantCount: 3,
+		},
+		{
+			name:      "atomic rollback",
+			atomic:    true,
+			wantCount: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryStore()
+			if err := s.CreateRecord(ctx, record("pochi", 100, start), nil); err != nil {
+				t.Fatalf("CreateRecord() error = %v", err)
+			}
+			im, err := s.BeginImport(ctx, tt.atomic)
+			if err != nil {
+				t.Fatalf("BeginImport() error = %v", err)
+			}
+			for i, row := range []struct {
+				eatenAt time.Time
+				want    bool
+			}{
+				{start, false},
+				{start.Add(time.Minute), true},
+				{start.Add(time.Hour), true},
+				{start.Add(time.Hour), false},
+			} {
+				if got, err := im.Insert(ctx, record("pochi", 100, row.eatenAt)); err != nil || got != row.want {
+					t.Errorf("Insert() of row %d = %v, %v, want %v", i, got, err, row.want)
+				}
+			}
+			if tt.commit {
+				err = im.Commit()
+			} else if tt.atomic {
+				err = im.Rollback()
+			}
+			if err != nil {
+				t.Fatalf("failed to finish an import: %v", err)
+			}
+			if n, _ := s.CountRecords(ctx); n != tt.wantCount {
+				t.Errorf("CountRecords() = %d, want %d", n, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestMemoryStore_Mutations(t *testing.T) {
+	ctx := WithActor(context.Background(), Actor{Name: "alice", RequestID: "req-1"})
+	start := time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	first, second := record("pochi", 100, start), record("pochi", 50, start.Add(time.Hour))
+	for _, r := range []*dogfoodpb.Record{first, second} {
+		if err := s.CreateRecord(ctx, r, nil); err != nil {
+			t.Fatalf("CreateRecord() error = %v", err)
+		}
+	}
+	if first.GetId() != 1 || second.GetId() != 2 {
+		t.Fatalf("CreateRecord() assigned IDs %d and %d, want 1 and 2", first.GetId(), second.GetId())
+	}
+
+	if _, err := s.UpdateRecord(ctx, first.GetId(), func(r *dogfoodpb.Record) error {
+		r.EatenAt = second.GetEatenAt()
+		return nil
+	}); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("UpdateRecord() to a duplicate error = %v, want %v", err, ErrAlreadyExists)
+	}
+	updated, err := s.UpdateRecord(ctx, first.GetId(), func(r *dogfoodpb.Record) error {
+		r.Gram = 80
+		r.EatenAt = timestamppb.New(start.Add(2 * time.Hour))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateRecord() error = %v", err)
+	}
+	if updated.GetId() != first.GetId() || updated.GetGram() != 80 {
+		t.Errorf("UpdateRecord() = %v", updated)
+	}
+	rs, _ := s.ListRecords(ctx, start, start.Add(3*time.Hour), 10)
+	if len(rs) != 2 || rs[0].GetId() != second.GetId() || rs[1].GetId() != first.GetId() {
+		t.Errorf("ListRecords() = %v, want records reordered by eaten_at", rs)
+	}
+
+	if err := s.DeleteRecord(ctx, second.GetId(), false); err != nil {
+		t.Fatalf("DeleteRecord() error = %v", err)
+	}
+	if err := s.DeleteRecord(ctx, second.GetId(), false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteRecord() of a deleted record error = %v, want %v", err, ErrNotFound)
+	}
+	if n, _ := s.CountRecords(ctx); n != 1 {
+		t.Errorf("CountRecords() = %d, want 1", n)
+	}
+	if err := s.CreateRecord(ctx, record("pochi", 50, start.Add(time.Hour)), nil); err != nil {
+		t.Fatalf("CreateRecord() of a deleted record error = %v", err)
+	}
+	if _, err := s.RestoreRecord(ctx, second.GetId()); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("RestoreRecord() of a recreated record error = %v, want %v", err, ErrAlreadyExists)
+	}
+	if err := s.DeleteRecord(ctx, 3, true); err != nil {
+		t.Fatalf("DeleteRecord() with purge error = %v", err)
+	}
+	restored, err := s.RestoreRecord(ctx, second.GetId())
+	if err != nil {
+		t.Fatalf("RestoreRecord() error = %v", err)
+	}
+	if restored.GetGram() != 50 {
+		t.Errorf("RestoreRecord() = %v", restored)
+	}
+	if _, err := s.RestoreRecord(ctx, 3); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RestoreRecord() of a purged record error = %v, want %v", err, ErrNotFound)
+	}
+
+	tests := []struct {
+		name    string
+		filter  AuditFilter
+		actions []AuditAction
+	}{
+		{"all", AuditFilter{PageSize: 10}, []AuditAction{AuditRestore, AuditPurge, AuditCreate, AuditDelete, AuditUpdate, AuditCreate, AuditCreate}},
+		{"record", AuditFilter{RecordID: second.GetId(), PageSize: 10}, []AuditAction{AuditRestore, AuditDelete, AuditCreate}},
+		{"page", AuditFilter{BeforeID: 5, PageSize: 2}, []AuditAction{AuditDelete, AuditUpdate}},
+		{"actor", AuditFilter{Actor: "bob", PageSize: 10}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := s.ListAuditEvents(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("ListAuditEvents() error = %v", err)
+			}
+			if len(events) != len(tt.actions) {
+				t.Fatalf("ListAuditEvents() returned %d events, want %d", len(events), len(tt.actions))
+			}
+			for i, e := range events {
+				if e.Action != tt.actions[i] || e.Actor != "alice" || e.RequestID != "req-1" {
+					t.Errorf("events[%d] = %+v, want %s by alice", i, e, tt.actions[i])
+				}
+			}
+		})
+	}
+	events, _ := s.ListAuditEvents(ctx, AuditFilter{RecordID: first.GetId(), PageSize: 1})
+	if len(events) != 1 || events[0].Before.GetGram() != 100 || events[0].After.GetGram() != 80 {
+		t.Errorf("ListAuditEvents() = %+v, want an update from 100 to 80 grams", events)
+	}
+}
@@ -0,0 +1,422 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"github.com/lib/pq"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// uniqueViolation is a Postgres error code of unique_violation.
+const uniqueViolation = "23505"
+
+type psqlStore struct {
+	db *sql.DB
+}
+
+// NewPsqlStore returns Store backed by the record and audit_event tables of Postgres.
+func NewPsqlStore(db *sql.DB) SQLStore {
+	return &psqlStore{db}
+}
+
+func (s *psqlStore) DB() *sql.DB {
+	return s.db
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+// inTx runs fn in a transaction, which is committed if fn succeeds and rolled back otherwise.
+func (s *psqlStore) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin a transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit a transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *psqlStore) CreateRecord(ctx context.Context, r *dogfoodpb.Record, onCreated OnCreated) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(
+			ctx,
+			"INSERT INTO record (dogfood_name, gram, dog_name, eaten_at) VALUES ($1, $2, $3, $4) RETURNING id",
+			r.GetDogfoodName(), r.GetGram(), r.GetDogName(), r.GetEatenAt().AsTime(),
+		).Scan(&r.Id); err != nil {
+			if isUniqueViolation(err) {
+				return ErrAlreadyExists
+			}
+			return fmt.Errorf("failed to insert a record: %w", err)
+		}
+		if err := addAuditEvent(ctx, tx, newAuditEvent(ctx, AuditCreate, r.GetId(), nil, r)); err != nil {
+			return err
+		}
+		if onCreated != nil {
+			return onCreated(ctx, tx)
+		}
+		return nil
+	})
+}
+
+// selectRecordForUpdate locks a record, which is soft deleted if deleted is true.
+func selectRecordForUpdate(ctx context.Context, tx *sql.Tx, id int64, deleted bool) (*dogfoodpb.Record, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT id, dogfood_name, gram, dog_name, eaten_at FROM record WHERE id = $1 AND (deleted_at IS NOT NULL) = $2 FOR UPDATE",
+		id, deleted,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query a record: %w", err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to query a record: %w", err)
+		}
+		return nil, ErrNotFound
+	}
+	return scanRecord(rows)
+}
+
+func (s *psqlStore) UpdateRecord(ctx context.Context, id int64, update func(r *dogfoodpb.Record) error) (*dogfoodpb.Record, error) {
+	var after *dogfoodpb.Record
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		before, err := selectRecordForUpdate(ctx, tx, id, false)
+		if err != nil {
+			return err
+		}
+		after = cloneRecord(before)
+		if err := update(after); err != nil {
+			return err
+		}
+		after.Id = id
+		if _, err := tx.ExecContext(
+			ctx,
+			"UPDATE record SET dogfood_name = $2, gram = $3, dog_name = $4, eaten_at = $5 WHERE id = $1",
+			id, after.GetDogfoodName(), after.GetGram(), after.GetDogName(), after.GetEatenAt().AsTime(),
+		); err != nil {
+			if isUniqueViolation(err) {
+				return ErrAlreadyExists
+			}
+			return fmt.Errorf("failed to update a record: %w", err)
+		}
+		return addAuditEvent(ctx, tx, newAuditEvent(ctx, AuditUpdate, id, before, after))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+func (s *psqlStore) DeleteRecord(ctx context.Context, id int64, purge bool) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		before, err := selectRecordForUpdate(ctx, tx, id, false)
+		if errors.Is(err, ErrNotFound) && purge {
+			before, err = selectRecordForUpdate(ctx, tx, id, true)
+		}
+		if err != nil {
+			return err
+		}
+		action := AuditDelete
+		query := "UPDATE record SET deleted_at = $2 WHERE id = $1"
+		args := []interface{}{id, time.Now()}
+		if purge {
+			action = AuditPurge
+			query = "DELETE FROM record WHERE id = $1"
+			args = args[:1]
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to delete a record: %w", err)
+		}
+		return addAuditEvent(ctx, tx, newAuditEvent(ctx, action, id, before, nil))
+	})
+}
+
+func (s *psqlStore) RestoreRecord(ctx context.Context, id int64) (*dogfoodpb.Record, error) {
+	var r *dogfoodpb.Record
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		if r, err = selectRecordForUpdate(ctx, tx, id, true); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE record SET deleted_at = NULL WHERE id = $1", id); err != nil {
+			if isUniqueViolation(err) {
+				return ErrAlreadyExists
+			}
+			return fmt.Errorf("failed to restore a record: %w", err)
+		}
+		return addAuditEvent(ctx, tx, newAuditEvent(ctx, AuditRestore, id, nil, r))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// addAuditEvent appends e within tx, so that it's recorded if and only if the mutation is committed.
+func addAuditEvent(ctx context.Context, tx *sql.Tx, e *AuditEvent) error {
+	before, err := marshalAuditRecord(e.Before)
+	if err != nil {
+		return err
+	}
+	after, err := marshalAuditRecord(e.After)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO audit_event (record_id, action, actor, request_id, before, after, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		e.RecordID, e.Action, e.Actor, e.RequestID, before, after, e.OccurredAt,
+	); err != nil {
+		return fmt.Errorf("failed to insert an audit event: %w", err)
+	}
+	return nil
+}
+
+func marshalAuditRecord(r *dogfoodpb.Record) (interface{}, error) {
+	if r == nil {
+		return nil, nil
+	}
+	b, err := protojson.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode a record: %w", err)
+	}
+	return string(b), nil
+}
+
+func unmarshalAuditRecord(b []byte) (*dogfoodpb.Record, error) {
+	if b == nil {
+		return nil, nil
+	}
+	var r dogfoodpb.Record
+	if err := protojson.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("failed to decode a record: %w", err)
+	}
+	return &r, nil
+}
+
+func (s *psqlStore) ListAuditEvents(ctx context.Context, f AuditFilter) ([]*AuditEvent, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, record_id, action, actor, request_id, before, after, occurred_at FROM audit_event
+		WHERE ($1 = 0 OR record_id = $1) AND ($2 = '' OR actor = $2) AND ($3 = 0 OR id < $3)
+		ORDER BY id DESC LIMIT $4`,
+		f.RecordID, f.Actor, f.BeforeID, f.PageSize,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit events: %w", err)
+	}
+	defer rows.Close()
+	var events []*AuditEvent
+	for rows.Next() {
+		var e AuditEvent
+		var before, after []byte
+		if err := rows.Scan(&e.ID, &e.RecordID, &e.Action, &e.Actor, &e.RequestID, &before, &after, &e.OccurredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan an audit event: %w", err)
+		}
+		if e.Before, err = unmarshalAuditRecord(before); err != nil {
+			return nil, err
+		}
+		if e.After, err = unmarshalAuditRecord(after); err != nil {
+			return nil, err
+		}
+		events = append(events, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit events: %w", err)
+	}
+	return events, nil
+}
+
+func (s *psqlStore) ListRecords(ctx context.Context, from, to time.Time, limit int32) ([]*dogfoodpb.Record, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, dogfood_name, gram, dog_name, eaten_at FROM record
+		WHERE eaten_at >= $1 AND eaten_at < $2 AND deleted_at IS NULL ORDER BY eaten_at, id LIMIT $3`,
+		from, to, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query records: %w", err)
+	}
+	defer rows.Close()
+	var rs []*dogfoodpb.Record
+	for rows.Next() {
+		r, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read records: %w", err)
+	}
+	return rs, nil
+}
+
+func (s *psqlStore) ExportRecords(ctx context.Context, f ExportFilter, fn func(*dogfoodpb.Record) error) error {
+	// Rows are passed as they are scanned, so that the result set is never held in memory.
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, dogfood_name, gram, dog_name, eaten_at FROM record
+		WHERE eaten_at >= $1 AND eaten_at < $2 AND ($3 = '' OR dog_name = $3) AND ($4 = '' OR dogfood_name = $4)
+		AND deleted_at IS NULL
+		ORDER BY eaten_at, id`,
+		f.From, f.To, f.DogName, f.DogfoodName,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query records: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r, err := scanRecord(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read records: %w", err)
+	}
+	return nil
+}
+
+func scanRecord(rows *sql.Rows) (*dogfoodpb.Record, error) {
+	var r dogfoodpb.Record
+	var t time.Time
+	if err := rows.Scan(&r.Id, &r.DogfoodName, &r.Gram, &r.DogName, &t); err != nil {
+		return nil, fmt.Errorf("failed to scan a record: %w", err)
+	}
+	r.EatenAt = timestamppb.New(t)
+	return &r, nil
+}
+
+func (s *psqlStore) RecordExists(ctx context.Context, r *dogfoodpb.Record) (bool, error) {
+	var exists bool
+	if err := s.db.QueryRowContext(
+		ctx,
+		"SELECT EXISTS (SELECT 1 FROM record WHERE dogfood_name = $1 AND dog_name = $2 AND eaten_at = $3 AND deleted_at IS NULL)",
+		r.GetDogfoodName(), r.GetDogName(), r.GetEatenAt().AsTime(),
+	).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to query a record: %w", err)
+	}
+	return exists, nil
+}
+
+func (s *psqlStore) BeginImport(ctx context.Context, atomic bool) (Import, error) {
+	im := &psqlImport{s: s}
+	if atomic {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to begin a transaction: %w", err)
+		}
+		im.tx = tx
+	}
+	return im, nil
+}
+
+type psqlImport struct {
+	s *psqlStore
+	// tx is not nil if the import is atomic.
+	tx *sql.Tx
+}
+
+func (im *psqlImport) Insert(ctx context.Context, r *dogfoodpb.Record) (bool, error) {
+	if im.tx != nil {
+		return insertImported(ctx, im.tx, r)
+	}
+	var inserted bool
+	err := im.s.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		inserted, err = insertImported(ctx, tx, r)
+		return err
+	})
+	return inserted, err
+}
+
+func insertImported(ctx context.Context, tx *sql.Tx, r *dogfoodpb.Record) (bool, error) {
+	r = cloneRecord(r)
+	err := tx.QueryRowContext(
+		ctx,
+		`INSERT INTO record (dogfood_name, gram, dog_name, eaten_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (dogfood_name, dog_name, eaten_at) WHERE deleted_at IS NULL DO NOTHING RETURNING id`,
+		r.GetDogfoodName(), r.GetGram(), r.GetDogName(), r.GetEatenAt().AsTime(),
+	).Scan(&r.Id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to insert a record: %w", err)
+	}
+	if err := addAuditEvent(ctx, tx, newAuditEvent(ctx, AuditImport, r.GetId(), nil, r)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (im *psqlImport) Commit() error {
+	if im.tx == nil {
+		return nil
+	}
+	return im.tx.Commit()
+}
+
+func (im *psqlImport) Rollback() error {
+	if im.tx == nil {
+		return nil
+	}
+	return im.tx.Rollback()
+}
+
+func (s *psqlStore) DogStats(ctx context.Context, since time.Time) ([]*DogStats, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT dog_name, MAX(eaten_at), COALESCE(SUM(gram) FILTER (WHERE eaten_at >= $1), 0) FROM record
+		WHERE deleted_at IS NULL GROUP BY dog_name`,
+		since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stats per dog: %w", err)
+	}
+	defer rows.Close()
+	var stats []*DogStats
+	for rows.Next() {
+		var st DogStats
+		if err := rows.Scan(&st.DogName, &st.LastMeal, &st.Grams); err != nil {
+			return nil, fmt.Errorf("failed to scan stats per dog: %w", err)
+		}
+		stats = append(stats, &st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stats per dog: %w", err)
+	}
+	return stats, nil
+}
+
+func (s *psqlStore) CountRecords(ctx context.Context) (int64, error) {
+	var n int64
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM record WHERE deleted_at IS NULL").Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count records: %w", err)
+	}
+	return n, nil
+}
+
+func (s *psqlStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
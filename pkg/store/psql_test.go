@@ -0,0 +1,411 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// anyArg matches any argument of a statement.
+type anyArg struct{}
+
+// mockStep is a statement or a transaction boundary which psqlStore is expected to run.
+type mockStep struct {
+	// kind is begin, query, exec, commit or rollback.
+	kind string
+	// query is a prefix of the statement, whose whitespaces are collapsed.
+	query string
+	// args are compared with arguments if they are not nil.
+	args    []interface{}
+	columns []string
+	rows    [][]sqldriver.Value
+	err     error
+}
+
+func begin() mockStep    { return mockStep{kind: "begin"} }
+func commit() mockStep   { return mockStep{kind: "commit"} }
+func rollback() mockStep { return mockStep{kind: "rollback"} }
+
+// mockDB is a database which runs steps in order, and fails statements which are not expected.
+type mockDB struct {
+	t *testing.T
+
+	mu    sync.Mutex
+	steps []mockStep
+}
+
+func newMockDB(t *testing.T, steps ...mockStep) (*sql.DB, *mockDB) {
+	m := &mockDB{t: t, steps: steps}
+	db := sql.OpenDB(m)
+	t.Cleanup(func() { db.Close() })
+	return db, m
+}
+
+func (m *mockDB) Connect(context.Context) (sqldriver.Conn, error) { return &mockConn{m}, nil }
+func (m *mockDB) Driver() sqldriver.Driver                        { return nil }
+
+// next consumes the next step, which must be kind running query.
+func (m *mockDB) next(kind, query string, args []sqldriver.NamedValue) (mockStep, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	query = strings.Join(strings.Fields(query), " ")
+	if len(m.steps) == 0 {
+		m.t.Errorf("unexpected %s %s", kind, query)
+		return mockStep{}, fmt.Errorf("unexpected %s", kind)
+	}
+	st := m.steps[0]
+	m.steps = m.steps[1:]
+	if st.kind != kind || !strings.HasPrefix(query, st.query) {
+		m.t.Errorf("got %s %q, want %s %q", kind, query, st.kind, st.query)
+		return mockStep{}, fmt.Errorf("unexpected %s", kind)
+	}
+	if st.args != nil {
+		if len(args) != len(st.args) {
+			m.t.Errorf("%s has %d args, want %d", st.query, len(args), len(st.args))
+		}
+		for i := 0; i < len(args) && i < len(st.args); i++ {
+			if _, ok := st.args[i].(anyArg); !ok && !reflect.DeepEqual(args[i].Value, st.args[i]) {
+				m.t.Errorf("%s arg $%d = %#v, want %#v", st.query, i+1, args[i].Value, st.args[i])
+			}
+		}
+	}
+	return st, st.err
+}
+
+// verify fails t if any step is not run.
+func (m *mockDB) verify() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, st := range m.steps {
+		m.t.Errorf("%s %q is not run", st.kind, st.query)
+	}
+}
+
+type mockConn struct {
+	m *mockDB
+}
+
+func (c *mockConn) Prepare(string) (sqldriver.Stmt, error) {
+	return nil, errors.New("statements are not prepared")
+}
+
+func (c *mockConn) Close() error { return nil }
+
+func (c *mockConn) Begin() (sqldriver.Tx, error) {
+	return c.BeginTx(context.Background(), sqldriver.TxOptions{})
+}
+
+func (c *mockConn) BeginTx(context.Context, sqldriver.TxOptions) (sqldriver.Tx, error) {
+	if _, err := c.m.next("begin", "", nil); err != nil {
+		return nil, err
+	}
+	return &mockTx{c.m}, nil
+}
+
+func (c *mockConn) QueryContext(_ context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Rows, error) {
+	st, err := c.m.next("query", query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &mockRows{columns: st.columns, rows: st.rows}, nil
+}
+
+func (c *mockConn) ExecContext(_ context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Result, error) {
+	if _, err := c.m.next("exec", query, args); err != nil {
+		return nil, err
+	}
+	return sqldriver.RowsAffected(1), nil
+}
+
+type mockTx struct {
+	m *mockDB
+}
+
+func (tx *mockTx) Commit() error {
+	_, err := tx.m.next("commit", "", nil)
+	return err
+}
+
+func (tx *mockTx) Rollback() error {
+	_, err := tx.m.next("rollback", "", nil)
+	return err
+}
+
+type mockRows struct {
+	columns []string
+	rows    [][]sqldriver.Value
+}
+
+func (r *mockRows) Columns() []string { return r.columns }
+func (r *mockRows) Close() error      { return nil }
+
+func (r *mockRows) Next(dest []sqldriver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+const (
+	selectRecordQuery    = "SELECT id, dogfood_name, gram, dog_name, eaten_at FROM record WHERE id = $1 AND (deleted_at IS NOT NULL) = $2 FOR UPDATE"
+	insertRecord         = "INSERT INTO record (dogfood_name, gram, dog_name, eaten_at) VALUES ($1, $2, $3, $4)"
+	insertImportedRecord = insertRecord + " ON CONFLICT (dogfood_name, dog_name, eaten_at) WHERE deleted_at IS NULL DO NOTHING RETURNING id"
+	insertAuditEvent     = "INSERT INTO audit_event (record_id, action, actor, request_id, before, after, occurred_at)"
+)
+
+var recordColumns = []string{"id", "dogfood_name", "gram", "dog_name", "eaten_at"}
+
+func selectRecord(id int64, deleted bool, found bool, eatenAt time.Time) mockStep {
+	st := mockStep{kind: "query", query: selectRecordQuery, args: []interface{}{id, deleted}, columns: recordColumns}
+	if found {
+		st.rows = [][]sqldriver.Value{{id, "a", int64(100), "pochi", eatenAt}}
+	}
+	return st
+}
+
+func auditEvent(id int64, action AuditAction) mockStep {
+	return mockStep{
+		kind:  "exec",
+		query: insertAuditEvent,
+		args:  []interface{}{id, string(action), "alice", "req-1", anyArg{}, anyArg{}, anyArg{}},
+	}
+}
+
+func TestPsqlStore(t *testing.T) {
+	ctx := WithActor(context.Background(), Actor{Name: "alice", RequestID: "req-1"})
+	eatenAt := time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)
+	uniqueErr := &pq.Error{Code: uniqueViolation}
+	failed := errors.New("failed")
+
+	tests := []struct {
+		name    string
+		steps   []mockStep
+		run     func(s SQLStore) error
+		wantErr error
+	}{
+		{
+			name: "create a record with an audit event and a callback in a transaction",
+			steps: []mockStep{
+				begin(),
+				{kind: "query", query: insertRecord + " RETURNING id", args: []interface{}{"a", int64(100), "pochi", eatenAt}, columns: []string{"id"}, rows: [][]sqldriver.Value{{int64(1)}}},
+				auditEvent(1, AuditCreate),
+				{kind: "exec", query: "INSERT INTO outbox"},
+				commit(),
+			},
+			run: func(s SQLStore) error {
+				return s.CreateRecord(ctx, record("pochi", 100, eatenAt), func(ctx context.Context, tx *sql.Tx) error {
+					_, err := tx.ExecContext(ctx, "INSERT INTO outbox")
+					return err
+				})
+			},
+		},
+		{
+			name: "roll back creating a record if the callback fails",
+			steps: []mockStep{
+				begin(),
+				{kind: "query", query: insertRecord, columns: []string{"id"}, rows: [][]sqldriver.Value{{int64(1)}}},
+				auditEvent(1, AuditCreate),
+				rollback(),
+			},
+			run: func(s SQLStore) error {
+				return s.CreateRecord(ctx, record("pochi", 100, eatenAt), func(context.Context, *sql.Tx) error { return failed })
+			},
+			wantErr: failed,
+		},
+		{
+			name: "reject a record duplicated with a live one by the partial unique index",
+			steps: []mockStep{
+				begin(),
+				{kind: "query", query: insertRecord, err: uniqueErr},
+				rollback(),
+			},
+			run: func(s SQLStore) error {
+				return s.CreateRecord(ctx, record("pochi", 100, eatenAt), nil)
+			},
+			wantErr: ErrAlreadyExists,
+		},
+		{
+			name: "soft delete a record",
+			steps: []mockStep{
+				begin(),
+				selectRecord(1, false, true, eatenAt),
+				{kind: "exec", query: "UPDATE record SET deleted_at = $2 WHERE id = $1", args: []interface{}{int64(1), anyArg{}}},
+				auditEvent(1, AuditDelete),
+				commit(),
+			},
+			run: func(s SQLStore) error {
+				return s.DeleteRecord(ctx, 1, false)
+			},
+		},
+		{
+			name: "not soft delete a deleted record",
+			steps: []mockStep{
+				begin(),
+				selectRecord(1, false, false, eatenAt),
+				rollback(),
+			},
+			run: func(s SQLStore) error {
+				return s.DeleteRecord(ctx, 1, false)
+			},
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "purge a soft deleted record",
+			steps: []mockStep{
+				begin(),
+				selectRecord(1, false, false, eatenAt),
+				selectRecord(1, true, true, eatenAt),
+				{kind: "exec", query: "DELETE FROM record WHERE id = $1", args: []interface{}{int64(1)}},
+				auditEvent(1, AuditPurge),
+				commit(),
+			},
+			run: func(s SQLStore) error {
+				return s.DeleteRecord(ctx, 1, true)
+			},
+		},
+		{
+			name: "restore a soft deleted record",
+			steps: []mockStep{
+				begin(),
+				selectRecord(1, true, true, eatenAt),
+				{kind: "exec", query: "UPDATE record SET deleted_at = NULL WHERE id = $1", args: []interface{}{int64(1)}},
+				auditEvent(1, AuditRestore),
+				commit(),
+			},
+			run: func(s SQLStore) error {
+				_, err := s.RestoreRecord(ctx, 1)
+				return err
+			},
+		},
+		{
+			name: "not restore a record duplicated with a live one by the partial unique index",
+			steps: []mockStep{
+				begin(),
+				selectRecord(1, true, true, eatenAt),
+				{kind: "exec", query: "UPDATE record SET deleted_at = NULL WHERE id = $1", err: uniqueErr},
+				rollback(),
+			},
+			run: func(s SQLStore) error {
+				_, err := s.RestoreRecord(ctx, 1)
+				return err
+			},
+			wantErr: ErrAlreadyExists,
+		},
+		{
+			name: "roll back restoring a record if its audit event fails",
+			steps: []mockStep{
+				begin(),
+				selectRecord(1, true, true, eatenAt),
+				{kind: "exec", query: "UPDATE record SET deleted_at = NULL WHERE id = $1"},
+				{kind: "exec", query: insertAuditEvent, err: failed},
+				rollback(),
+			},
+			run: func(s SQLStore) error {
+				_, err := s.RestoreRecord(ctx, 1)
+				return err
+			},
+			wantErr: failed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, m := newMockDB(t, tt.steps...)
+			if err := tt.run(NewPsqlStore(db)); !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			m.verify()
+		})
+	}
+}
+
+func TestPsqlStore_Import(t *testing.T) {
+	ctx := WithActor(context.Background(), Actor{Name: "alice", RequestID: "req-1"})
+	eatenAt := time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)
+	inserted := func(id int64) mockStep {
+		return mockStep{kind: "query", query: insertImportedRecord, args: []interface{}{"a", int64(100), "pochi", eatenAt}, columns: []string{"id"}, rows: [][]sqldriver.Value{{id}}}
+	}
+	// Nothing is returned if the record exists.
+	skipped := mockStep{kind: "query", query: insertImportedRecord, columns: []string{"id"}}
+
+	tests := []struct {
+		name         string
+		atomic       bool
+		rollback     bool
+		steps        []mockStep
+		wantInserted []bool
+	}{
+		{
+			name: "insert every record in its own transaction",
+			steps: []mockStep{
+				begin(), inserted(1), auditEvent(1, AuditImport), commit(),
+				begin(), skipped, commit(),
+			},
+			wantInserted: []bool{true, false},
+		},
+		{
+			name:   "insert all records in a transaction",
+			atomic: true,
+			steps: []mockStep{
+				begin(),
+				inserted(1), auditEvent(1, AuditImport),
+				skipped,
+				commit(),
+			},
+			wantInserted: []bool{true, false},
+		},
+		{
+			name:     "roll back all records",
+			atomic:   true,
+			rollback: true,
+			steps: []mockStep{
+				begin(),
+				inserted(1), auditEvent(1, AuditImport),
+				inserted(2), auditEvent(2, AuditImport),
+				rollback(),
+			},
+			wantInserted: []bool{true, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, m := newMockDB(t, tt.steps...)
+			im, err := NewPsqlStore(db).BeginImport(ctx, tt.atomic)
+			if err != nil {
+				t.Fatalf("BeginImport() error = %v", err)
+			}
+			for i, want := range tt.wantInserted {
+				r := record("pochi", 100, eatenAt)
+				got, err := im.Insert(ctx, r)
+				if err != nil {
+					t.Fatalf("Insert() error = %v", err)
+				}
+				if got != want {
+					t.Errorf("Insert() #%d = %v, want %v", i, got, want)
+				}
+				if r.GetId() != 0 {
+					t.Errorf("Insert() modified the record: %v", r)
+				}
+			}
+			end := im.Commit
+			if tt.rollback {
+				end = im.Rollback
+			}
+			if err := end(); err != nil {
+				t.Errorf("Commit() or Rollback() error = %v", err)
+			}
+			m.verify()
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// Package store persists records of the dogfood backend.
+// Postgres is used in production, and the in-memory store is used for tests and demos
+// where Postgres is not available.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+)
+
+var (
+	// ErrAlreadyExists is returned when a record identical to the created, updated or restored one already exists.
+	ErrAlreadyExists = errors.New("record already exists")
+	// ErrNotFound is returned when a record to mutate is not found.
+	ErrNotFound = errors.New("record is not found")
+)
+
+// OnCreated is called with a transaction in which a record is created, so that side effects,
+// e.g. events, happen if and only if the record is created.
+// tx is nil if the store is not backed by database/sql.
+type OnCreated func(ctx context.Context, tx *sql.Tx) error
+
+// ExportFilter filters exported records. Empty names match any.
+type ExportFilter struct {
+	From        time.Time
+	To          time.Time
+	DogName     string
+	DogfoodName string
+}
+
+// DogStats are stats of records of a dog.
+type DogStats struct {
+	DogName  string
+	LastMeal time.Time
+	// Grams is how much grams the dog ate since the requested time.
+	Grams float64
+}
+
+// Store persists records. Records are identified by IDs, and dogfood_name, dog_name and eaten_at are unique
+// among records which are not deleted. Deleted records are soft deleted unless they are purged, and are
+// invisible except to RestoreRecord and DeleteRecord with purge.
+// Every mutation is recorded as an audit event in the same transaction, by an actor in the context.
+type Store interface {
+	// CreateRecord creates r with a new ID, and calls onCreated in the same transaction if it's not nil.
+	CreateRecord(ctx context.Context, r *dogfoodpb.Record, onCreated OnCreated) error
+	// UpdateRecord updates a record by update, which is called with the current record.
+	UpdateRecord(ctx context.Context, id int64, update func(r *dogfoodpb.Record) error) (*dogfoodpb.Record, error)
+	// DeleteRecord deletes a record softly, or permanently if purge is true.
+	DeleteRecord(ctx context.Context, id int64, purge bool) error
+	// RestoreRecord restores a soft deleted record.
+	RestoreRecord(ctx context.Context, id int64) (*dogfoodpb.Record, error)
+	// ListAuditEvents returns audit events matching f in descending order of ID.
+	ListAuditEvents(ctx context.Context, f AuditFilter) ([]*AuditEvent, error)
+	// ListRecords returns up to limit records eaten in [from, to) in order of eaten_at.
+	ListRecords(ctx context.Context, from, to time.Time, limit int32) ([]*dogfoodpb.Record, error)
+	// ExportRecords calls fn with records matching f in order of eaten_at until fn fails.
+	ExportRecords(ctx context.Context, f ExportFilter, fn func(*dogfoodpb.Record) error) error
+	// RecordExists reports whether a record identical to r exists.
+	RecordExists(ctx context.Context, r *dogfoodpb.Record) (bool, error)
+	// BeginImport begins to import records by an actor in ctx. All records are imported or none if atomic is true.
+	BeginImport(ctx context.Context, atomic bool) (Import, error)
+	// DogStats returns stats of every dog.
+	DogStats(ctx context.Context, since time.Time) ([]*DogStats, error)
+	// CountRecords returns the number of records.
+	CountRecords(ctx context.Context) (int64, error)
+	// Ping reports whether the store is available.
+	Ping(ctx context.Context) error
+}
+
+// SQLStore is Store backed by database/sql, which the other subsystems, e.g. webhooks, share.
+type SQLStore interface {
+	Store
+	DB() *sql.DB
+}
+
+// Import inserts records.
+type Import interface {
+	// Insert inserts r, and returns false if a record identical to r already exists.
+	Insert(ctx context.Context, r *dogfoodpb.Record) (bool, error)
+	// Commit makes inserted records visible if the import is atomic.
+	Commit() error
+	// Rollback discards inserted records if the import is atomic.
+	Rollback() error
+}
@@ -0,0 +1,92 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	grpc_dd "gopkg.in/DataDog/dd-trace-go.v1/contrib/google.golang.org/grpc"
+	http_dd "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/profiler"
+)
+
+type datadogProvider struct {
+	logger *zap.Logger
+}
+
+type datadogSpan struct {
+	tracer.Span
+}
+
+func (s datadogSpan) Finish() {
+	s.Span.Finish()
+}
+
+func newDatadogProvider(logger *zap.Logger) (*datadogProvider, error) {
+	tracer.Start(tracer.WithAnalytics(true))
+	if err := profiler.Start(
+		profiler.WithProfileTypes(
+			profiler.CPUProfile,
+			profiler.HeapProfile,
+		),
+	); err != nil {
+		tracer.Stop()
+		return nil, err
+	}
+	return &datadogProvider{logger: logger}, nil
+}
+
+func (*datadogProvider) startSpan(ctx context.Context, operation, resource string) (Span, context.Context) {
+	span, ctx := tracer.StartSpanFromContext(ctx, operation, tracer.ResourceName(resource))
+	return datadogSpan{span}, ctx
+}
+
+// inject doesn't fail callers, because a request without trace context is still served, but a broken trace is logged.
+func (p *datadogProvider) inject(ctx context.Context, h http.Header) {
+	span, ok := tracer.SpanFromContext(ctx)
+	if !ok {
+		return
+	}
+	if err := tracer.Inject(span.Context(), tracer.HTTPHeadersCarrier(h)); err != nil {
+		p.logger.Warn("failed to propagate trace context", append(p.logFields(ctx), zap.Error(err))...)
+	}
+}
+
+func (*datadogProvider) logFields(ctx context.Context) []zap.Field {
+	span, ok := tracer.SpanFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return []zap.Field{
+		zap.Uint64("dd.trace_id", span.Context().TraceID()),
+		zap.Uint64("dd.span_id", span.Context().SpanID()),
+	}
+}
+
+func (*datadogProvider) unaryServerInterceptor(ignoredMethods []string) grpc.UnaryServerInterceptor {
+	return grpc_dd.UnaryServerInterceptor(grpc_dd.WithIgnoredMethods(ignoredMethods...))
+}
+
+func (*datadogProvider) streamServerInterceptor(ignoredMethods []string) grpc.StreamServerInterceptor {
+	return grpc_dd.StreamServerInterceptor(grpc_dd.WithIgnoredMethods(ignoredMethods...))
+}
+
+func (*datadogProvider) unaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return grpc_dd.UnaryClientInterceptor()
+}
+
+func (*datadogProvider) streamClientInterceptor() grpc.StreamClientInterceptor {
+	return grpc_dd.StreamClientInterceptor()
+}
+
+func (*datadogProvider) wrapHandler(h http.Handler, service string, ignore func(*http.Request) bool) http.Handler {
+	return http_dd.WrapHandler(h, service, "", http_dd.WithIgnoreRequest(ignore))
+}
+
+func (*datadogProvider) stop(context.Context) error {
+	tracer.Stop()
+	profiler.Stop()
+	return nil
+}
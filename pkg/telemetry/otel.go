@@ -0,0 +1,155 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/metric/global"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/export/metric"
+	controller "go.opentelemetry.io/otel/sdk/metric/controller/basic"
+	processor "go.opentelemetry.io/otel/sdk/metric/processor/basic"
+	"go.opentelemetry.io/otel/sdk/metric/selector/simple"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+const instrumentationName = "github.com/kei6u/dogfood"
+
+type otelProvider struct {
+	tp         *sdktrace.TracerProvider
+	controller *controller.Controller
+	tracer     trace.Tracer
+}
+
+func newOTelProvider(ctx context.Context, backend Backend, service string) (*otelProvider, error) {
+	var spanExporter sdktrace.SpanExporter
+	var metricExporter metric.Exporter
+	var err error
+	switch backend {
+	case BackendOTLP:
+		if spanExporter, err = otlptracegrpc.New(ctx); err != nil {
+			return nil, err
+		}
+		if metricExporter, err = otlpmetricgrpc.New(ctx); err != nil {
+			return nil, err
+		}
+	default:
+		if spanExporter, err = stdouttrace.New(); err != nil {
+			return nil, err
+		}
+		if metricExporter, err = stdoutmetric.New(); err != nil {
+			return nil, err
+		}
+	}
+
+	if v := os.Getenv("OTEL_SERVICE_NAME"); v != "" {
+		service = v
+	}
+	res := resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(service))
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+	)
+	cont := controller.New(
+		processor.NewFactory(simple.NewWithInexpensiveDistribution(), metricExporter),
+		controller.WithExporter(metricExporter),
+		controller.WithResource(res),
+	)
+	if err := cont.Start(ctx); err != nil {
+		return nil, err
+	}
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	global.SetMeterProvider(cont)
+	return &otelProvider{
+		tp:         tp,
+		controller: cont,
+		tracer:     tp.Tracer(instrumentationName),
+	}, nil
+}
+
+type otelSpan struct {
+	trace.Span
+}
+
+func (s otelSpan) Finish() {
+	s.End()
+}
+
+func (p *otelProvider) startSpan(ctx context.Context, operation, resource string) (Span, context.Context) {
+	ctx, span := p.tracer.Start(ctx, operation, trace.WithAttributes(attribute.String("resource.name", resource)))
+	return otelSpan{span}, ctx
+}
+
+func (*otelProvider) inject(ctx context.Context, h http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(h))
+}
+
+func (*otelProvider) logFields(ctx context.Context) []zap.Field {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	return []zap.Field{
+		zap.String("trace_id", sc.TraceID().String()),
+		zap.String("span_id", sc.SpanID().String()),
+	}
+}
+
+func (*otelProvider) unaryServerInterceptor(ignoredMethods []string) grpc.UnaryServerInterceptor {
+	traced := otelgrpc.UnaryServerInterceptor()
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if isIgnored(info.FullMethod, ignoredMethods) {
+			return handler(ctx, req)
+		}
+		return traced(ctx, req, info, handler)
+	}
+}
+
+func (*otelProvider) streamServerInterceptor(ignoredMethods []string) grpc.StreamServerInterceptor {
+	traced := otelgrpc.StreamServerInterceptor()
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if isIgnored(info.FullMethod, ignoredMethods) {
+			return handler(srv, ss)
+		}
+		return traced(srv, ss, info, handler)
+	}
+}
+
+func (*otelProvider) unaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return otelgrpc.UnaryClientInterceptor()
+}
+
+func (*otelProvider) streamClientInterceptor() grpc.StreamClientInterceptor {
+	return otelgrpc.StreamClientInterceptor()
+}
+
+func (*otelProvider) wrapHandler(h http.Handler, service string, ignore func(*http.Request) bool) http.Handler {
+	return otelhttp.NewHandler(h, service, otelhttp.WithFilter(func(r *http.Request) bool {
+		return !ignore(r)
+	}))
+}
+
+func (p *otelProvider) stop(ctx context.Context) error {
+	return multierr.Combine(
+		p.tp.Shutdown(ctx),
+		p.controller.Stop(ctx),
+	)
+}
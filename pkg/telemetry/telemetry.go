@@ -0,0 +1,227 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/kei6u/dogfood/pkg/requestid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Backend is where traces and metrics are sent.
+type Backend string
+
+const (
+	// BackendDatadog sends traces and profiles to the Datadog agent.
+	BackendDatadog Backend = "datadog"
+	// BackendOTLP sends traces and metrics to an OpenTelemetry collector.
+	// The endpoint is configured by OTEL_EXPORTER_OTLP_ENDPOINT, e.g. http://localhost:4317.
+	BackendOTLP Backend = "otlp"
+	// BackendStdout writes traces and metrics to stdout.
+	BackendStdout Backend = "stdout"
+	// BackendNone disables telemetry.
+	BackendNone Backend = "none"
+)
+
+// TraceHeaders are headers which carry trace context between processes.
+var TraceHeaders = []string{
+	"x-datadog-trace-id",
+	"x-datadog-parent-id",
+	"x-datadog-sampling-priority",
+	"traceparent",
+	"tracestate",
+}
+
+// Span is a unit of work in a trace.
+type Span interface {
+	// Finish finishes the span.
+	Finish()
+}
+
+type provider interface {
+	startSpan(ctx context.Context, operation, resource string) (Span, context.Context)
+	inject(ctx context.Context, h http.Header)
+	logFields(ctx context.Context) []zap.Field
+	unaryServerInterceptor(ignoredMethods []string) grpc.UnaryServerInterceptor
+	streamServerInterceptor(ignoredMethods []string) grpc.StreamServerInterceptor
+	unaryClientInterceptor() grpc.UnaryClientInterceptor
+	streamClientInterceptor() grpc.StreamClientInterceptor
+	wrapHandler(h http.Handler, service string, ignore func(*http.Request) bool) http.Handler
+	stop(ctx context.Context) error
+}
+
+var (
+	mu      sync.RWMutex
+	current provider = noopProvider{}
+)
+
+func get() provider {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// GetBackend gets a backend from TELEMETRY_BACKEND, and defaults to datadog.
+func GetBackend() Backend {
+	if b := os.Getenv("TELEMETRY_BACKEND"); b != "" {
+		return Backend(b)
+	}
+	return BackendDatadog
+}
+
+// Option configures Start.
+type Option func(o *options)
+
+type options struct {
+	logger *zap.Logger
+}
+
+// WithLogger logs failures of telemetry which don't fail callers, e.g. propagating trace context.
+// They are discarded by default.
+func WithLogger(l *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// Start starts sending telemetry of service to backend, and returns a function to flush and stop it.
+func Start(ctx context.Context, backend Backend, service string, opts ...Option) (stop func(context.Context) error, err error) {
+	o := &options{logger: zap.NewNop()}
+	for _, opt := range opts {
+		opt(o)
+	}
+	var p provider
+	switch backend {
+	case BackendDatadog:
+		p, err = newDatadogProvider(o.logger)
+	case BackendOTLP, BackendStdout:
+		p, err = newOTelProvider(ctx, backend, service)
+	case BackendNone:
+		p = noopProvider{}
+	default:
+		return nil, fmt.Errorf("telemetry backend %s is not supported", backend)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to start %s telemetry: %w", backend, err)
+	}
+
+	mu.Lock()
+	current = p
+	mu.Unlock()
+	return p.stop, nil
+}
+
+// StartSpanFromContext starts a span as a child of a span in ctx, and returns ctx containing it.
+func StartSpanFromContext(ctx context.Context, operation, resource string) (Span, context.Context) {
+	return get().startSpan(ctx, operation, resource)
+}
+
+// Inject writes trace context of a span in ctx to h.
+func Inject(ctx context.Context, h http.Header) {
+	get().inject(ctx, h)
+}
+
+// LogFields returns zap fields to correlate logs with a span and a request ID in ctx.
+func LogFields(ctx context.Context) []zap.Field {
+	return append(get().logFields(ctx), requestid.LogFields(ctx)...)
+}
+
+// UnaryServerInterceptor traces unary RPCs except ignoredMethods.
+func UnaryServerInterceptor(ignoredMethods ...string) grpc.UnaryServerInterceptor {
+	return get().unaryServerInterceptor(ignoredMethods)
+}
+
+// StreamServerInterceptor traces streaming RPCs except ignoredMethods.
+func StreamServerInterceptor(ignoredMethods ...string) grpc.StreamServerInterceptor {
+	return get().streamServerInterceptor(ignoredMethods)
+}
+
+// UnaryClientInterceptor traces unary RPCs and propagates trace context.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return get().unaryClientInterceptor()
+}
+
+// StreamClientInterceptor traces streaming RPCs and propagates trace context.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return get().streamClientInterceptor()
+}
+
+// WrapHandler traces requests to h except ones ignore returns true.
+func WrapHandler(h http.Handler, service string, ignore func(*http.Request) bool) http.Handler {
+	return get().wrapHandler(h, service, ignore)
+}
+
+// MetadataFromRequest forwards trace context of r to gRPC metadata.
+// Trace headers of r are forwarded as they are, and overwritten by a span in the context of r if any.
+func MetadataFromRequest(r *http.Request) metadata.MD {
+	h := http.Header{}
+	for _, k := range TraceHeaders {
+		if v := r.Header.Get(k); v != "" {
+			h.Set(k, v)
+		}
+	}
+	Inject(r.Context(), h)
+	md := metadata.MD{}
+	for k, v := range h {
+		md.Set(k, v...)
+	}
+	return md
+}
+
+func isIgnored(method string, ignoredMethods []string) bool {
+	for _, m := range ignoredMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+type noopSpan struct{}
+
+func (noopSpan) Finish() {}
+
+type noopProvider struct{}
+
+func (noopProvider) startSpan(ctx context.Context, _, _ string) (Span, context.Context) {
+	return noopSpan{}, ctx
+}
+
+func (noopProvider) inject(context.Context, http.Header) {}
+
+func (noopProvider) logFields(context.Context) []zap.Field { return nil }
+
+func (noopProvider) unaryServerInterceptor([]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(ctx, req)
+	}
+}
+
+func (noopProvider) streamServerInterceptor([]string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, ss)
+	}
+}
+
+func (noopProvider) unaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func (noopProvider) streamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+func (noopProvider) wrapHandler(h http.Handler, _ string, _ func(*http.Request) bool) http.Handler {
+	return h
+}
+
+func (noopProvider) stop(context.Context) error { return nil }
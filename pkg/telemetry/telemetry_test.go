@@ -0,0 +1,106 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+func TestMetadataFromRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/v1/dogfood/records", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.Header.Set("x-datadog-trace-id", "1234")
+	r.Header.Set("authorization", "secret")
+
+	md := MetadataFromRequest(r)
+	if got, want := md.Get("traceparent"), []string{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("traceparent = %v, want %v", got, want)
+	}
+	if got, want := md.Get("x-datadog-trace-id"), []string{"1234"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("x-datadog-trace-id = %v, want %v", got, want)
+	}
+	if got := md.Get("authorization"); len(got) != 0 {
+		t.Errorf("authorization = %v, want nothing", got)
+	}
+}
+
+func TestStart(t *testing.T) {
+	if _, err := Start(context.Background(), Backend("unknown"), "dogfood"); err == nil {
+		t.Error("Start() error = nil, want error for an unknown backend")
+	}
+	stop, err := Start(context.Background(), BackendNone, "dogfood")
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := stop(context.Background()); err != nil {
+		t.Errorf("stop() error = %v", err)
+	}
+}
+
+// foreignSpan is a span of another tracer, whose context can't be injected.
+type foreignSpan struct{ ddtrace.Span }
+
+func (foreignSpan) Context() ddtrace.SpanContext { return foreignSpanContext{} }
+
+type foreignSpanContext struct{}
+
+func (foreignSpanContext) SpanID() uint64                            { return 0 }
+func (foreignSpanContext) TraceID() uint64                           { return 0 }
+func (foreignSpanContext) ForeachBaggageItem(func(k, v string) bool) {}
+
+func TestDatadogProvider_Inject(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	core, logs := observer.New(zap.WarnLevel)
+	p := &datadogProvider{logger: zap.New(core)}
+
+	span, ctx := p.startSpan(context.Background(), "op", "resource")
+	defer span.Finish()
+	h := http.Header{}
+	p.inject(ctx, h)
+	ddSpan, _ := tracer.SpanFromContext(ctx)
+	if got, want := h.Get("x-datadog-trace-id"), strconv.FormatUint(ddSpan.Context().TraceID(), 10); got != want {
+		t.Errorf("x-datadog-trace-id = %q, want %q", got, want)
+	}
+
+	// Contexts without spans inject nothing.
+	h = http.Header{}
+	p.inject(context.Background(), h)
+	if len(h) != 0 {
+		t.Errorf("headers = %v, want nothing", h)
+	}
+
+	// Failures are logged rather than dropped.
+	p.inject(tracer.ContextWithSpan(context.Background(), foreignSpan{}), http.Header{})
+	if got := logs.FilterMessage("failed to propagate trace context").Len(); got != 1 {
+		t.Errorf("%d failures are logged, want 1", got)
+	}
+}
+
+func TestOTelProvider_Inject(t *testing.T) {
+	p, err := newOTelProvider(context.Background(), BackendStdout, "dogfood-test")
+	if err != nil {
+		t.Fatalf("newOTelProvider() error = %v", err)
+	}
+	defer p.stop(context.Background())
+
+	span, ctx := p.startSpan(context.Background(), "op", "resource")
+	defer span.Finish()
+	h := http.Header{}
+	p.inject(ctx, h)
+	traceID := trace.SpanContextFromContext(ctx).TraceID().String()
+	if got := h.Get("traceparent"); !strings.Contains(got, traceID) {
+		t.Errorf("traceparent = %q, want trace ID %s", got, traceID)
+	}
+}
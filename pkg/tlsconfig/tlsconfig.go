@@ -0,0 +1,217 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultReloadInterval is how often certificate files are checked for rotation.
+const defaultReloadInterval = 30 * time.Second
+
+// Config describes certificates used by a listener or an upstream connection.
+type Config struct {
+	// CertFile and KeyFile are the certificate presented to the peer.
+	CertFile string
+	KeyFile  string
+	// CAFile is a PEM bundle used to verify the peer.
+	CAFile string
+	// ClientAuth requires and verifies client certificates by CAFile on a listener.
+	ClientAuth bool
+	// ServerName is the expected name of the server on an upstream connection.
+	ServerName string
+	// ReloadInterval is how often CertFile and KeyFile are checked for rotation.
+	ReloadInterval time.Duration
+}
+
+// FromEnv loads Config from environment variables prefixed with prefix,
+// e.g. TLS_CERT_FILE, TLS_KEY_FILE, TLS_CA_FILE, TLS_CLIENT_AUTH and TLS_SERVER_NAME for "TLS".
+// It returns nil when no certificate and no CA are configured, which means plaintext.
+func FromEnv(prefix string) (*Config, error) {
+	c := &Config{
+		CertFile:       os.Getenv(prefix + "_CERT_FILE"),
+		KeyFile:        os.Getenv(prefix + "_KEY_FILE"),
+		CAFile:         os.Getenv(prefix + "_CA_FILE"),
+		ServerName:     os.Getenv(prefix + "_SERVER_NAME"),
+		ReloadInterval: defaultReloadInterval,
+	}
+	if c.CertFile == "" && c.KeyFile == "" && c.CAFile == "" {
+		return nil, nil
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return nil, fmt.Errorf("both %s_CERT_FILE and %s_KEY_FILE must be set", prefix, prefix)
+	}
+	if v := os.Getenv(prefix + "_CLIENT_AUTH"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s_CLIENT_AUTH is invalid: %w", prefix, err)
+		}
+		c.ClientAuth = b
+	}
+	if v := os.Getenv(prefix + "_RELOAD_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s_RELOAD_INTERVAL is invalid: %w", prefix, err)
+		}
+		c.ReloadInterval = d
+	}
+	return c, nil
+}
+
+// ServerTLSConfig returns *tls.Config for a listener.
+// The certificate is reloaded when its files are rotated.
+func (c *Config) ServerTLSConfig() (*tls.Config, error) {
+	if c.CertFile == "" {
+		return nil, errors.New("certificate is missing")
+	}
+	r, err := NewReloader(c.CertFile, c.KeyFile, c.ReloadInterval)
+	if err != nil {
+		return nil, err
+	}
+	tc := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+	if c.ClientAuth {
+		pool, err := loadCertPool(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tc.ClientCAs = pool
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tc, nil
+}
+
+// ClientTLSConfig returns *tls.Config for an upstream connection.
+// The client certificate, if any, is reloaded when its files are rotated.
+func (c *Config) ClientTLSConfig() (*tls.Config, error) {
+	tc := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: c.ServerName,
+	}
+	if c.CAFile != "" {
+		pool, err := loadCertPool(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tc.RootCAs = pool
+	}
+	if c.CertFile != "" {
+		r, err := NewReloader(c.CertFile, c.KeyFile, c.ReloadInterval)
+		if err != nil {
+			return nil, err
+		}
+		tc.GetClientCertificate = r.GetClientCertificate
+	}
+	return tc, nil
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	if caFile == "" {
+		return nil, errors.New("CA file is missing")
+	}
+	b, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificate is found in %s", caFile)
+	}
+	return pool, nil
+}
+
+// Reloader keeps a key pair up to date with files on disk.
+// Files are checked at most once per interval during handshakes, so rotated
+// certificates are picked up without restarting.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// NewReloader loads a key pair and returns Reloader.
+func NewReloader(certFile, keyFile string, interval time.Duration) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate is for tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.certificate()
+}
+
+// GetClientCertificate is for tls.Config.GetClientCertificate.
+func (r *Reloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.certificate()
+}
+
+func (r *Reloader) certificate() (*tls.Certificate, error) {
+	r.mu.RLock()
+	cert, checkedAt := r.cert, r.checkedAt
+	r.mu.RUnlock()
+	if time.Since(checkedAt) < r.interval {
+		return cert, nil
+	}
+	if err := r.reload(); err != nil {
+		// Keep serving the last good certificate while files are being rotated.
+		return cert, nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// reload loads the key pair if its files are modified. Files are checked at most once per interval
+// even if they can't be stat'ed, e.g. while they are being rotated.
+func (r *Reloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkedAt = time.Now()
+	modTime, err := latestModTime(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	if r.cert != nil && !modTime.After(r.modTime) {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load key pair: %w", err)
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
+
+func latestModTime(files ...string) (time.Time, error) {
+	var t time.Time
+	for _, f := range files {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to stat %s: %w", f, err)
+		}
+		if fi.ModTime().After(t) {
+			t = fi.ModTime()
+		}
+	}
+	return t, nil
+}
@@ -0,0 +1,257 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, dir string) (*testCA, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "dogfood test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", der)
+	return &testCA{cert: cert, key: key}, caFile
+}
+
+// issue writes a key pair signed by ca and returns paths to the files.
+func (ca *testCA) issue(t *testing.T, dir, name string, serial int64) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(dir, name+".pem")
+	keyFile := filepath.Join(dir, name+"-key.pem")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTLSServer(t *testing.T, c *Config) *httptest.Server {
+	t.Helper()
+	serverTLS, err := c.ServerTLSConfig()
+	if err != nil {
+		t.Fatalf("ServerTLSConfig() error = %v", err)
+	}
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	s.TLS = serverTLS
+	s.StartTLS()
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caFile := newTestCA(t, dir)
+	serverCert, serverKey := ca.issue(t, dir, "server", 2)
+	clientCert, clientKey := ca.issue(t, dir, "client", 3)
+
+	s := newTLSServer(t, &Config{
+		CertFile:   serverCert,
+		KeyFile:    serverKey,
+		CAFile:     caFile,
+		ClientAuth: true,
+	})
+
+	tests := []struct {
+		name    string
+		c       *Config
+		wantErr bool
+	}{
+		{
+			name: "with client certificate",
+			c: &Config{
+				CertFile:   clientCert,
+				KeyFile:    clientKey,
+				CAFile:     caFile,
+				ServerName: "localhost",
+			},
+		},
+		{
+			name: "without client certificate",
+			c: &Config{
+				CAFile:     caFile,
+				ServerName: "localhost",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientTLS, err := tt.c.ClientTLSConfig()
+			if err != nil {
+				t.Fatalf("ClientTLSConfig() error = %v", err)
+			}
+			hc := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+			res, err := hc.Get(s.URL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				res.Body.Close()
+			}
+		})
+	}
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	ca, caFile := newTestCA(t, dir)
+	certFile, keyFile := ca.issue(t, dir, "server", 2)
+
+	s := newTLSServer(t, &Config{
+		CertFile: certFile,
+		KeyFile:  keyFile,
+	})
+	clientTLS, err := (&Config{CAFile: caFile, ServerName: "localhost"}).ClientTLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serial := func() int64 {
+		t.Helper()
+		conn, err := tls.Dial("tcp", s.Listener.Addr().String(), clientTLS)
+		if err != nil {
+			t.Fatalf("Dial() error = %v", err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+	}
+
+	if got := serial(); got != 2 {
+		t.Fatalf("serial = %d, want 2", got)
+	}
+
+	// Rotate the certificate and make sure it's newer than the last one.
+	ca.issue(t, dir, "server", 4)
+	future := time.Now().Add(time.Minute)
+	for _, f := range []string{certFile, keyFile} {
+		if err := os.Chtimes(f, future, future); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := serial(); got != 4 {
+		t.Fatalf("serial = %d after rotation, want 4", got)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Run("plaintext", func(t *testing.T) {
+		c, err := FromEnv("TEST_TLS")
+		if err != nil || c != nil {
+			t.Errorf("FromEnv() = %v, %v, want nil, nil", c, err)
+		}
+	})
+	t.Run("missing key", func(t *testing.T) {
+		t.Setenv("TEST_TLS_CERT_FILE", "cert.pem")
+		if _, err := FromEnv("TEST_TLS"); err == nil {
+			t.Error("FromEnv() error = nil, want error")
+		}
+	})
+	t.Run("client auth", func(t *testing.T) {
+		t.Setenv("TEST_TLS_CERT_FILE", "cert.pem")
+		t.Setenv("TEST_TLS_KEY_FILE", "key.pem")
+		t.Setenv("TEST_TLS_CA_FILE", "ca.pem")
+		t.Setenv("TEST_TLS_CLIENT_AUTH", "true")
+		t.Setenv("TEST_TLS_RELOAD_INTERVAL", "1m")
+		c, err := FromEnv("TEST_TLS")
+		if err != nil {
+			t.Fatalf("FromEnv() error = %v", err)
+		}
+		if !c.ClientAuth || c.ReloadInterval != time.Minute || c.CAFile != "ca.pem" {
+			t.Errorf("FromEnv() = %+v", c)
+		}
+	})
+}
+
+func TestReloader_MissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	ca, _ := newTestCA(t, dir)
+	certFile, keyFile := ca.issue(t, dir, "server", 2)
+	r, err := NewReloader(certFile, keyFile, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := r.cert
+
+	// Files disappear while they are being rotated.
+	for _, f := range []string{certFile, keyFile} {
+		if err := os.Remove(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.checkedAt = time.Time{}
+	got, err := r.GetCertificate(nil)
+	if err != nil || got != want {
+		t.Fatalf("GetCertificate() = %v, %v, want the last good certificate", got, err)
+	}
+	// They are not checked again until the interval passes.
+	if time.Since(r.checkedAt) > time.Minute {
+		t.Errorf("checkedAt = %s, want it updated even if files can't be stat'ed", r.checkedAt)
+	}
+}
@@ -0,0 +1,47 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned when a URL of a subscription points to an address which is not on
+// the public internet, so that subscriptions can't make the backend send requests to internal services.
+var ErrForbiddenAddress = errors.New("address is forbidden")
+
+// sharedAddressSpace is 100.64.0.0/10 for carrier-grade NAT, which some clusters use for pods.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// forbiddenIP reports whether ip is loopback, private, link-local, e.g. 169.254.169.254 of cloud metadata,
+// multicast or unspecified.
+func forbiddenIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
+
+// checkDialAddress rejects a resolved address to connect to if it's forbidden. It's checked on every
+// connection rather than when subscriptions are created, because hosts may resolve to other addresses later.
+func checkDialAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+	if ip := net.ParseIP(host); ip == nil || forbiddenIP(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
+
+// newHTTPClient returns a client which connects to public addresses only, even if redirected.
+func newHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: checkDialAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would connect to subscribers on behalf of the worker without the check.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
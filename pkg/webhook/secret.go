@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// encryptedSecretPrefix marks secrets encrypted by SecretCipher, so that secrets stored in plaintext before
+// they were encrypted are still read, until they are rotated.
+const encryptedSecretPrefix = "v1:"
+
+// SecretCipher encrypts secrets of subscriptions at rest by AES-256-GCM.
+// Secrets can't be hashed, because the worker signs payloads with them.
+type SecretCipher struct {
+	aead cipher.AEAD
+}
+
+// NewSecretCipher returns SecretCipher by a 32 bytes key.
+func NewSecretCipher(key []byte) (*SecretCipher, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key must be 32 bytes, but %d bytes", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize cipher: %w", err)
+	}
+	return &SecretCipher{aead}, nil
+}
+
+// SecretCipherFromEnv returns SecretCipher by WEBHOOK_SECRET_KEY, a base64 encoded 32 bytes key,
+// e.g. generated by `openssl rand -base64 32`. It returns nil if WEBHOOK_SECRET_KEY is not set.
+func SecretCipherFromEnv() (*SecretCipher, error) {
+	v := os.Getenv("WEBHOOK_SECRET_KEY")
+	if v == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return nil, fmt.Errorf("WEBHOOK_SECRET_KEY is invalid: %w", err)
+	}
+	c, err := NewSecretCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("WEBHOOK_SECRET_KEY is invalid: %w", err)
+	}
+	return c, nil
+}
+
+// Encrypt encrypts secret with a random nonce.
+func (c *SecretCipher) Encrypt(secret string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate a nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(secret), nil)
+	return encryptedSecretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a secret encrypted by Encrypt. A secret stored in plaintext is returned as is.
+func (c *SecretCipher) Decrypt(stored string) (string, error) {
+	if !strings.HasPrefix(stored, encryptedSecretPrefix) {
+		return stored, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, encryptedSecretPrefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode a secret: %w", err)
+	}
+	if len(sealed) < c.aead.NonceSize() {
+		return "", errors.New("failed to decrypt a secret: it's too short")
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	secret, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt a secret: %w", err)
+	}
+	return string(secret), nil
+}
@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SignatureHeader carries a timestamp and HMAC-SHA256 of a payload,
+	// e.g. t=1638316800,v1=5257a869e7ecebeda32affa62cdca3fa51cad7e77a0e56ff536d0ce8e108d8bd.
+	SignatureHeader = "X-Dogfood-Signature"
+	// EventTypeHeader carries a type of an event, e.g. record.created.
+	EventTypeHeader = "X-Dogfood-Event"
+	// DeliveryIDHeader carries an ID of a delivery, which is the same among retries.
+	DeliveryIDHeader = "X-Dogfood-Delivery"
+)
+
+// Sign returns a value of SignatureHeader. The timestamp is signed together with the payload
+// so that receivers can reject replayed requests.
+func Sign(secret string, t time.Time, payload []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", ts, mac(secret, ts, payload))
+}
+
+// Verify verifies a value of SignatureHeader, and rejects it if it was signed more than tolerance ago.
+func Verify(secret, header string, payload []byte, now time.Time, tolerance time.Duration) error {
+	var ts, sig string
+	for _, kv := range strings.Split(header, ",") {
+		switch {
+		case strings.HasPrefix(kv, "t="):
+			ts = strings.TrimPrefix(kv, "t=")
+		case strings.HasPrefix(kv, "v1="):
+			sig = strings.TrimPrefix(kv, "v1=")
+		}
+	}
+	if ts == "" || sig == "" {
+		return errors.New("signature is malformed")
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("timestamp is invalid: %w", err)
+	}
+	if d := now.Sub(time.Unix(unix, 0)); d > tolerance || d < -tolerance {
+		return errors.New("timestamp is out of tolerance")
+	}
+	if !hmac.Equal([]byte(sig), []byte(mac(secret, ts, payload))) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func mac(secret, ts string, payload []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(ts))
+	h.Write([]byte("."))
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,245 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Queue is a queue of deliveries consumed by Worker.
+type Queue interface {
+	// Claim leases due pending deliveries for lease so that other workers don't deliver them concurrently.
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Delivery, error)
+	// Succeed marks a delivery succeeded.
+	Succeed(ctx context.Context, id int64, at time.Time) error
+	// Fail records a failed attempt, and schedules the next one at next unless dead.
+	Fail(ctx context.Context, id int64, attempts int, next time.Time, lastErr string, dead bool) error
+}
+
+// Store persists subscriptions and deliveries.
+type Store interface {
+	Queue
+	CreateSubscription(ctx context.Context, sub *Subscription) error
+	GetSubscription(ctx context.Context, id int64) (*Subscription, error)
+	ListSubscriptions(ctx context.Context) ([]*Subscription, error)
+	UpdateSubscription(ctx context.Context, sub *Subscription) error
+	DeleteSubscription(ctx context.Context, id int64) error
+	// Enqueue adds a delivery of an event for every subscription within tx,
+	// so that the event is delivered if and only if tx is committed.
+	Enqueue(ctx context.Context, tx *sql.Tx, eventType string, payload []byte) error
+	ListDeliveries(ctx context.Context, f DeliveryFilter) ([]*Delivery, error)
+}
+
+type psqlStore struct {
+	db     *sql.DB
+	cipher *SecretCipher
+}
+
+// NewPsqlStore returns Store backed by the webhook_subscription and webhook_delivery tables of Postgres.
+// Secrets of subscriptions are encrypted by c.
+func NewPsqlStore(db *sql.DB, c *SecretCipher) Store {
+	return &psqlStore{db, c}
+}
+
+func (s *psqlStore) CreateSubscription(ctx context.Context, sub *Subscription) error {
+	secret, err := s.cipher.Encrypt(sub.Secret)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := s.db.QueryRowContext(
+		ctx,
+		"INSERT INTO webhook_subscription (url, secret, created_at, updated_at) VALUES ($1, $2, $3, $3) RETURNING id",
+		sub.URL, secret, now,
+	).Scan(&sub.ID); err != nil {
+		return fmt.Errorf("failed to insert a subscription: %w", err)
+	}
+	sub.CreatedAt = now
+	sub.UpdatedAt = now
+	return nil
+}
+
+func (s *psqlStore) GetSubscription(ctx context.Context, id int64) (*Subscription, error) {
+	var sub Subscription
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT id, url, secret, created_at, updated_at FROM webhook_subscription WHERE id = $1",
+		id,
+	).Scan(&sub.ID, &sub.URL, &sub.Secret, &sub.CreatedAt, &sub.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query a subscription: %w", err)
+	}
+	if sub.Secret, err = s.cipher.Decrypt(sub.Secret); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (s *psqlStore) ListSubscriptions(ctx context.Context) ([]*Subscription, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, url, secret, created_at, updated_at FROM webhook_subscription ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subscriptions: %w", err)
+	}
+	defer rows.Close()
+	var subs []*Subscription
+	for rows.Next() {
+		var sub Subscription
+		if err := rows.Scan(&sub.ID, &sub.URL, &sub.Secret, &sub.CreatedAt, &sub.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan subscriptions: %w", err)
+		}
+		var err error
+		if sub.Secret, err = s.cipher.Decrypt(sub.Secret); err != nil {
+			return nil, err
+		}
+		subs = append(subs, &sub)
+	}
+	return subs, rows.Err()
+}
+
+func (s *psqlStore) UpdateSubscription(ctx context.Context, sub *Subscription) error {
+	// A secret stored in plaintext is encrypted on every update, even if it's not rotated.
+	secret, err := s.cipher.Encrypt(sub.Secret)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	res, err := s.db.ExecContext(
+		ctx,
+		"UPDATE webhook_subscription SET url = $2, secret = $3, updated_at = $4 WHERE id = $1",
+		sub.ID, sub.URL, secret, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update a subscription: %w", err)
+	}
+	if err := mustAffect(res); err != nil {
+		return err
+	}
+	sub.UpdatedAt = now
+	return nil
+}
+
+func (s *psqlStore) DeleteSubscription(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM webhook_subscription WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete a subscription: %w", err)
+	}
+	return mustAffect(res)
+}
+
+func mustAffect(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *psqlStore) Enqueue(ctx context.Context, tx *sql.Tx, eventType string, payload []byte) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO webhook_delivery (subscription_id, event_type, payload, status, attempts, next_attempt_at, last_error, created_at)
+		SELECT id, $1, $2, $3, 0, $4, '', $4 FROM webhook_subscription`,
+		eventType, string(payload), StatusPending, time.Now(),
+	); err != nil {
+		return fmt.Errorf("failed to enqueue deliveries: %w", err)
+	}
+	return nil
+}
+
+func (s *psqlStore) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Delivery, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`UPDATE webhook_delivery d SET next_attempt_at = $2
+		FROM webhook_subscription s
+		WHERE s.id = d.subscription_id AND d.id IN (
+			SELECT id FROM webhook_delivery
+			WHERE status = $3 AND next_attempt_at <= $1
+			ORDER BY next_attempt_at LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, d.subscription_id, d.event_type, d.payload, d.attempts, d.created_at, s.url, s.secret`,
+		now, now.Add(lease), StatusPending, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+	defer rows.Close()
+	var ds []*Delivery
+	for rows.Next() {
+		d := Delivery{Status: StatusPending}
+		var payload string
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventType, &payload, &d.Attempts, &d.CreatedAt, &d.URL, &d.Secret); err != nil {
+			return nil, fmt.Errorf("failed to scan deliveries: %w", err)
+		}
+		d.Payload = []byte(payload)
+		var err error
+		if d.Secret, err = s.cipher.Decrypt(d.Secret); err != nil {
+			return nil, err
+		}
+		ds = append(ds, &d)
+	}
+	return ds, rows.Err()
+}
+
+func (s *psqlStore) Succeed(ctx context.Context, id int64, at time.Time) error {
+	if _, err := s.db.ExecContext(
+		ctx,
+		"UPDATE webhook_delivery SET status = $2, attempts = attempts + 1, last_error = '', delivered_at = $3 WHERE id = $1",
+		id, StatusSucceeded, at,
+	); err != nil {
+		return fmt.Errorf("failed to mark a delivery succeeded: %w", err)
+	}
+	return nil
+}
+
+func (s *psqlStore) Fail(ctx context.Context, id int64, attempts int, next time.Time, lastErr string, dead bool) error {
+	st := StatusPending
+	if dead {
+		st = StatusDead
+	}
+	if _, err := s.db.ExecContext(
+		ctx,
+		"UPDATE webhook_delivery SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5 WHERE id = $1",
+		id, st, attempts, next, lastErr,
+	); err != nil {
+		return fmt.Errorf("failed to record a failed delivery: %w", err)
+	}
+	return nil
+}
+
+func (s *psqlStore) ListDeliveries(ctx context.Context, f DeliveryFilter) ([]*Delivery, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, subscription_id, event_type, payload, status, attempts, next_attempt_at, last_error, created_at, delivered_at
+		FROM webhook_delivery
+		WHERE ($1 = 0 OR subscription_id = $1) AND ($2 = '' OR status = $2)
+		ORDER BY id DESC LIMIT $3`,
+		f.SubscriptionID, f.Status, f.PageSize,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deliveries: %w", err)
+	}
+	defer rows.Close()
+	var ds []*Delivery
+	for rows.Next() {
+		var d Delivery
+		var payload string
+		var deliveredAt sql.NullTime
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventType, &payload, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.LastError, &d.CreatedAt, &deliveredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan deliveries: %w", err)
+		}
+		d.Payload = []byte(payload)
+		if deliveredAt.Valid {
+			d.DeliveredAt = &deliveredAt.Time
+		}
+		ds = append(ds, &d)
+	}
+	return ds, rows.Err()
+}
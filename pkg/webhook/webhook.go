@@ -0,0 +1,92 @@
+// Package webhook delivers events to subscribers' URLs durably.
+// Deliveries are enqueued to an outbox table in the same transaction as the change which
+// emits the event, and a worker POSTs them with HMAC signatures, retrying with exponential
+// backoff until they succeed or are dead-lettered.
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when a subscription doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// Subscription is a URL which receives events.
+type Subscription struct {
+	ID        int64
+	URL       string
+	Secret    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ValidateURL validates u is an absolute http or https URL whose host is not localhost or a forbidden IP.
+// Hosts resolved to forbidden IPs are rejected by the worker when payloads are posted.
+func ValidateURL(u string) error {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return fmt.Errorf("url is invalid: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("scheme of url must be http or https: %s", u)
+	}
+	host := parsed.Hostname()
+	if host == "" {
+		return fmt.Errorf("host of url is missing: %s", u)
+	}
+	if ip := net.ParseIP(host); (ip != nil && forbiddenIP(ip)) || strings.EqualFold(strings.TrimSuffix(host, "."), "localhost") {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
+
+// Status is a status of a delivery.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusSucceeded Status = "succeeded"
+	// StatusDead means the delivery has given up after the maximum attempts.
+	StatusDead Status = "dead"
+)
+
+// Delivery is an event to be delivered to a subscription.
+type Delivery struct {
+	ID             int64
+	SubscriptionID int64
+	EventType      string
+	Payload        []byte
+	Status         Status
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastError      string
+	CreatedAt      time.Time
+	DeliveredAt    *time.Time
+
+	// URL and Secret are filled when the delivery is claimed. Secret is decrypted.
+	URL    string
+	Secret string
+}
+
+// DeliveryFilter filters deliveries.
+type DeliveryFilter struct {
+	SubscriptionID int64
+	Status         Status
+	PageSize       int
+}
+
+// NewSecret returns a random secret to sign payloads.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate a secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,275 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1638316800, 0)
+	payload := []byte(`{"type":"record.created"}`)
+	sig := Sign("secret", now, payload)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		payload []byte
+		now     time.Time
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			secret:  "secret",
+			header:  sig,
+			payload: payload,
+			now:     now,
+		},
+		{
+			name:    "wrong secret",
+			secret:  "other",
+			header:  sig,
+			payload: payload,
+			now:     now,
+			wantErr: true,
+		},
+		{
+			name:    "tampered payload",
+			secret:  "secret",
+			header:  sig,
+			payload: []byte(`{"type":"record.deleted"}`),
+			now:     now,
+			wantErr: true,
+		},
+		{
+			name:    "replayed",
+			secret:  "secret",
+			header:  sig,
+			payload: payload,
+			now:     now.Add(time.Hour),
+			wantErr: true,
+		},
+		{
+			name:    "malformed",
+			secret:  "secret",
+			header:  "v1=abc",
+			payload: payload,
+			now:     now,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(tt.secret, tt.header, tt.payload, tt.now, 5*time.Minute); (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type fakeQueue struct {
+	mu         sync.Mutex
+	deliveries map[int64]*Delivery
+}
+
+func (q *fakeQueue) Claim(_ context.Context, now time.Time, lease time.Duration, limit int) ([]*Delivery, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var ds []*Delivery
+	for _, d := range q.deliveries {
+		if d.Status == StatusPending && !d.NextAttemptAt.After(now) && len(ds) < limit {
+			d.NextAttemptAt = now.Add(lease)
+			c := *d
+			ds = append(ds, &c)
+		}
+	}
+	return ds, nil
+}
+
+func (q *fakeQueue) Succeed(_ context.Context, id int64, at time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	d := q.deliveries[id]
+	d.Status = StatusSucceeded
+	d.Attempts++
+	d.DeliveredAt = &at
+	return nil
+}
+
+func (q *fakeQueue) Fail(_ context.Context, id int64, attempts int, next time.Time, lastErr string, dead bool) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	d := q.deliveries[id]
+	d.Attempts = attempts
+	d.NextAttemptAt = next
+	d.LastError = lastErr
+	if dead {
+		d.Status = StatusDead
+	}
+	return nil
+}
+
+func TestWorker_DeliverDue(t *testing.T) {
+	var mu sync.Mutex
+	failures := 2
+	var verifyErr error
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		b, _ := io.ReadAll(r.Body)
+		// The worker signs with its fake clock, which advances up to a few hours.
+		verifyErr = Verify("secret", r.Header.Get(SignatureHeader), b, time.Now(), 24*time.Hour)
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name         string
+		maxAttempts  int
+		wantStatus   Status
+		wantAttempts int
+	}{
+		{
+			name:         "succeeds after retries",
+			maxAttempts:  5,
+			wantStatus:   StatusSucceeded,
+			wantAttempts: 3,
+		},
+		{
+			name:         "dead-lettered",
+			maxAttempts:  2,
+			wantStatus:   StatusDead,
+			wantAttempts: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			failures = 2
+			mu.Unlock()
+
+			now := time.Now()
+			q := &fakeQueue{deliveries: map[int64]*Delivery{
+				1: {ID: 1, EventType: "record.created", Payload: []byte(`{}`), Status: StatusPending, NextAttemptAt: now, URL: ts.URL, Secret: "secret"},
+			}}
+			// The test server listens on loopback, which the default client rejects.
+			w := NewWorker(q, zap.NewNop(), WithMaxAttempts(tt.maxAttempts), WithBackoff(time.Second, time.Minute), WithHTTPClient(ts.Client()))
+			w.now = func() time.Time { return now }
+
+			// Advance the clock beyond backoff on every round.
+			for i := 0; i < 5; i++ {
+				if err := w.DeliverDue(context.Background()); err != nil {
+					t.Fatalf("DeliverDue() error = %v", err)
+				}
+				now = now.Add(time.Hour)
+			}
+			d := q.deliveries[1]
+			if d.Status != tt.wantStatus || d.Attempts != tt.wantAttempts {
+				t.Errorf("delivery = %s after %d attempts, want %s after %d attempts", d.Status, d.Attempts, tt.wantStatus, tt.wantAttempts)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if verifyErr != nil {
+				t.Errorf("signature is invalid: %v", verifyErr)
+			}
+		})
+	}
+}
+
+func TestWorker_ForbiddenAddress(t *testing.T) {
+	var called bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer ts.Close()
+
+	for _, u := range []string{ts.URL, "http://169.254.169.254/latest/meta-data/", "http://[::1]:80/"} {
+		err := NewWorker(nil, zap.NewNop()).post(context.Background(), &Delivery{ID: 1, URL: u, Secret: "secret"})
+		if !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("post(%s) error = %v, want %v", u, err, ErrForbiddenAddress)
+		}
+	}
+	if called {
+		t.Error("forbidden address is requested")
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://example.com/hook", false},
+		{"http://93.184.216.34:8080/hook", false},
+		{"ftp://example.com/hook", true},
+		{"https:///hook", true},
+		{"http://localhost:8080/hook", true},
+		{"http://127.0.0.1/hook", true},
+		{"http://10.0.0.1/hook", true},
+		{"http://169.254.169.254/latest/meta-data/", true},
+		{"http://[fe80::1]/hook", true},
+		{"http://[::ffff:192.168.0.1]/hook", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateURL(tt.url); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateURL(%s) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSecretCipher(t *testing.T) {
+	c, err := NewSecretCipher(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("NewSecretCipher() error = %v", err)
+	}
+	encrypted, err := c.Encrypt("secret")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if strings.Contains(encrypted, "secret") {
+		t.Errorf("Encrypt() = %s, which contains the secret", encrypted)
+	}
+	for _, stored := range []string{encrypted, "secret"} {
+		if got, err := c.Decrypt(stored); err != nil || got != "secret" {
+			t.Errorf("Decrypt(%s) = %s, %v, want secret", stored, got, err)
+		}
+	}
+	other, _ := NewSecretCipher(bytes.Repeat([]byte{1}, 32))
+	if _, err := other.Decrypt(encrypted); err == nil {
+		t.Error("Decrypt() by another key succeeded")
+	}
+	if _, err := NewSecretCipher(make([]byte, 16)); err == nil {
+		t.Error("NewSecretCipher() with a 16 bytes key succeeded")
+	}
+}
+
+func TestWorker_Backoff(t *testing.T) {
+	w := NewWorker(nil, zap.NewNop(), WithBackoff(time.Second, 10*time.Second))
+	for attempts, want := range map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 8 * time.Second,
+		5: 10 * time.Second,
+		9: 10 * time.Second,
+	} {
+		if got := w.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
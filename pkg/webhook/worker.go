@@ -0,0 +1,181 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMaxAttempts  = 10
+	defaultBaseBackoff  = 10 * time.Second
+	defaultMaxBackoff   = time.Hour
+	defaultPollInterval = 5 * time.Second
+	defaultBatchSize    = 20
+	defaultTimeout      = 10 * time.Second
+	// maxErrorBodySize bounds a response body recorded as an error.
+	maxErrorBodySize = 512
+)
+
+// Worker delivers pending deliveries in Queue.
+type Worker struct {
+	queue        Queue
+	client       *http.Client
+	logger       *zap.Logger
+	maxAttempts  int
+	baseBackoff  time.Duration
+	maxBackoff   time.Duration
+	pollInterval time.Duration
+	batchSize    int
+	now          func() time.Time
+}
+
+// WorkerOption configures Worker.
+type WorkerOption func(w *Worker)
+
+// WithMaxAttempts sets attempts after which a delivery is dead-lettered.
+func WithMaxAttempts(n int) WorkerOption {
+	return func(w *Worker) {
+		w.maxAttempts = n
+	}
+}
+
+// WithBackoff sets a delay after the first failure, which doubles up to max on every failure.
+func WithBackoff(base, max time.Duration) WorkerOption {
+	return func(w *Worker) {
+		w.baseBackoff = base
+		w.maxBackoff = max
+	}
+}
+
+// WithPollInterval sets an interval to look for due deliveries.
+func WithPollInterval(d time.Duration) WorkerOption {
+	return func(w *Worker) {
+		w.pollInterval = d
+	}
+}
+
+// WithHTTPClient sets a client to POST payloads. The default client connects to public addresses only,
+// and rejects ones such as loopback, private and link-local addresses with ErrForbiddenAddress.
+func WithHTTPClient(c *http.Client) WorkerOption {
+	return func(w *Worker) {
+		w.client = c
+	}
+}
+
+// NewWorker returns Worker.
+func NewWorker(queue Queue, logger *zap.Logger, opts ...WorkerOption) *Worker {
+	w := &Worker{
+		queue:        queue,
+		client:       newHTTPClient(defaultTimeout),
+		logger:       logger,
+		maxAttempts:  defaultMaxAttempts,
+		baseBackoff:  defaultBaseBackoff,
+		maxBackoff:   defaultMaxBackoff,
+		pollInterval: defaultPollInterval,
+		batchSize:    defaultBatchSize,
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Run delivers due deliveries every poll interval until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	t := time.NewTicker(w.pollInterval)
+	defer t.Stop()
+	for {
+		if err := w.DeliverDue(ctx); err != nil {
+			w.logger.Error("failed to deliver webhooks", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// DeliverDue delivers a batch of due deliveries concurrently.
+func (w *Worker) DeliverDue(ctx context.Context) error {
+	// Deliveries are leased until the longest attempt ends, so that they are retried if this worker dies.
+	ds, err := w.queue.Claim(ctx, w.now(), w.client.Timeout+w.pollInterval, w.batchSize)
+	if err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	for _, d := range ds {
+		wg.Add(1)
+		go func(d *Delivery) {
+			defer wg.Done()
+			w.deliver(ctx, d)
+		}(d)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (w *Worker) deliver(ctx context.Context, d *Delivery) {
+	logger := w.logger.With(zap.Int64("delivery_id", d.ID), zap.Int64("subscription_id", d.SubscriptionID))
+	attempts := d.Attempts + 1
+	perr := w.post(ctx, d)
+	if perr == nil {
+		if err := w.queue.Succeed(ctx, d.ID, w.now()); err != nil {
+			logger.Error("failed to mark a webhook delivered", zap.Error(err))
+		}
+		return
+	}
+
+	dead := attempts >= w.maxAttempts
+	next := w.now().Add(w.backoff(attempts))
+	if err := w.queue.Fail(ctx, d.ID, attempts, next, perr.Error(), dead); err != nil {
+		logger.Error("failed to record a failed webhook delivery", zap.Error(err))
+	}
+	if dead {
+		logger.Error("webhook delivery is dead-lettered", zap.Int("attempts", attempts), zap.Error(perr))
+		return
+	}
+	logger.Warn("webhook delivery failed, retry later", zap.Int("attempts", attempts), zap.Time("next_attempt_at", next), zap.Error(perr))
+}
+
+func (w *Worker) post(ctx context.Context, d *Delivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventTypeHeader, d.EventType)
+	req.Header.Set(DeliveryIDHeader, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(d.Secret, w.now(), d.Payload))
+	res, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBodySize))
+		return fmt.Errorf("subscriber responded %s: %s", res.Status, b)
+	}
+	return nil
+}
+
+// backoff returns a delay after attempts failures.
+func (w *Worker) backoff(attempts int) time.Duration {
+	d := w.baseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= w.maxBackoff {
+			return w.maxBackoff
+		}
+	}
+	return d
+}
@@ -0,0 +1,109 @@
+package protov1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gogo/status"
+	"github.com/kei6u/dogfood/pkg/alerting"
+	"github.com/kei6u/dogfood/pkg/telemetry"
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultAlertEvaluationInterval = time.Minute
+	defaultAlertsPageSize          = 100
+	alertWebhookTimeout            = 10 * time.Second
+)
+
+func (s *Server) ListAlerts(ctx context.Context, req *dogfoodpb.ListAlertsRequest) (*dogfoodpb.ListAlertsResponse, error) {
+	var span telemetry.Span
+	span, ctx = telemetry.StartSpanFromContext(ctx, "ListAlerts", "Alerts")
+	defer span.Finish()
+
+	if s.alerts == nil {
+		return nil, errSQLStoreRequired("alerts")
+	}
+	st := alerting.State(req.GetState())
+	if st != "" && st != alerting.StateFiring && st != alerting.StateResolved {
+		return nil, status.Errorf(codes.InvalidArgument, "state %s is invalid, it must be firing or resolved", st)
+	}
+	pageSize := int(req.GetPageSize())
+	if pageSize <= 0 {
+		pageSize = defaultAlertsPageSize
+	}
+
+	alerts, err := s.alerts.ListTransitions(ctx, alerting.ListFilter{
+		Dog:      req.GetDogName(),
+		State:    st,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list up alerts: %s", err)
+	}
+	res := &dogfoodpb.ListAlertsResponse{Alerts: make([]*dogfoodpb.Alert, len(alerts))}
+	for i, a := range alerts {
+		res.Alerts[i] = &dogfoodpb.Alert{
+			RuleName:       a.RuleName,
+			DogName:        a.Dog,
+			Type:           string(a.Type),
+			State:          string(a.State),
+			Message:        a.Message,
+			TransitionedAt: timestamppb.New(a.TransitionedAt),
+		}
+	}
+	return res, nil
+}
+
+// initializeAlerting loads rules from ALERT_RULES_FILE. Alerts are not evaluated if it's not specified.
+// Transitions are always logged, and posted to ALERT_WEBHOOK_URL if specified.
+// Alerting is disabled if the store is not backed by Postgres.
+func (s *Server) initializeAlerting() error {
+	path := os.Getenv("ALERT_RULES_FILE")
+	if s.db == nil {
+		if path != "" {
+			return errors.New("ALERT_RULES_FILE requires Postgres")
+		}
+		return nil
+	}
+	s.alerts = alerting.NewPsqlStore(s.db)
+	if path == "" {
+		return nil
+	}
+	rules, err := alerting.LoadRules(path)
+	if err != nil {
+		return err
+	}
+	notifier := alerting.MultiNotifier{&alerting.LogNotifier{Logger: s.logger}}
+	if u := os.Getenv("ALERT_WEBHOOK_URL"); u != "" {
+		notifier = append(notifier, &alerting.WebhookNotifier{
+			URL:    u,
+			Client: &http.Client{Timeout: alertWebhookTimeout},
+		})
+	}
+	s.alertEvaluator = alerting.NewEvaluator(s.alerts, rules, notifier, s.logger)
+	return nil
+}
+
+// runAlerting evaluates alert rules every ALERT_EVALUATION_INTERVAL until ctx is done.
+func (s *Server) runAlerting(ctx context.Context) {
+	if s.alertEvaluator == nil {
+		return
+	}
+	interval := defaultAlertEvaluationInterval
+	if v := os.Getenv("ALERT_EVALUATION_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			s.logger.Warn("ALERT_EVALUATION_INTERVAL is invalid, use default", zap.String("value", v))
+		} else {
+			interval = d
+		}
+	}
+	s.alertEvaluator.Run(ctx, interval)
+}
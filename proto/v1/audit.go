@@ -0,0 +1,56 @@
+package protov1
+
+import (
+	"context"
+
+	"github.com/gogo/status"
+	"github.com/kei6u/dogfood/pkg/store"
+	"github.com/kei6u/dogfood/pkg/telemetry"
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultAuditEventsPageSize = 100
+	maxAuditEventsPageSize     = 1000
+)
+
+func (s *Server) ListAuditEvents(ctx context.Context, req *dogfoodpb.ListAuditEventsRequest) (*dogfoodpb.ListAuditEventsResponse, error) {
+	var span telemetry.Span
+	span, ctx = telemetry.StartSpanFromContext(ctx, "ListAuditEvents", "AuditEvents")
+	defer span.Finish()
+
+	pageSize := int(req.GetPageSize())
+	switch {
+	case pageSize < 0:
+		return nil, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	case pageSize == 0:
+		pageSize = defaultAuditEventsPageSize
+	case pageSize > maxAuditEventsPageSize:
+		pageSize = maxAuditEventsPageSize
+	}
+	events, err := s.store.ListAuditEvents(ctx, store.AuditFilter{
+		RecordID: req.GetRecordId(),
+		Actor:    req.GetActor(),
+		BeforeID: req.GetBeforeId(),
+		PageSize: pageSize,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list up audit events: %s", err)
+	}
+	resp := &dogfoodpb.ListAuditEventsResponse{Events: make([]*dogfoodpb.AuditEvent, 0, len(events))}
+	for _, e := range events {
+		resp.Events = append(resp.Events, &dogfoodpb.AuditEvent{
+			Id:         e.ID,
+			RecordId:   e.RecordID,
+			Action:     string(e.Action),
+			Actor:      e.Actor,
+			RequestId:  e.RequestID,
+			Before:     e.Before,
+			After:      e.After,
+			OccurredAt: timestamppb.New(e.OccurredAt),
+		})
+	}
+	return resp, nil
+}
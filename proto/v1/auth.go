@@ -0,0 +1,127 @@
+package protov1
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/textproto"
+	"os"
+	"strings"
+
+	"github.com/gogo/status"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/kei6u/dogfood/pkg/requestid"
+	"github.com/kei6u/dogfood/pkg/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// apiKeyMetadataKey is metadata which carries an API key, which is client.APIKeyHeader.
+// It's forwarded from X-Api-Key header by the gRPC gateway.
+const apiKeyMetadataKey = "x-api-key"
+
+// authenticatedMethods require an API key, because they correct or erase records, reveal who did it,
+// or make the backend send requests to URLs given by callers.
+var authenticatedMethods = map[string]bool{
+	"/dogfoodpb.v1.DogFoodService/UpdateRecord":              true,
+	"/dogfoodpb.v1.DogFoodService/DeleteRecord":              true,
+	"/dogfoodpb.v1.DogFoodService/RestoreRecord":             true,
+	"/dogfoodpb.v1.DogFoodService/ListAuditEvents":           true,
+	"/dogfoodpb.v1.DogFoodService/CreateWebhookSubscription": true,
+	"/dogfoodpb.v1.DogFoodService/GetWebhookSubscription":    true,
+	"/dogfoodpb.v1.DogFoodService/ListWebhookSubscriptions":  true,
+	"/dogfoodpb.v1.DogFoodService/UpdateWebhookSubscription": true,
+	"/dogfoodpb.v1.DogFoodService/DeleteWebhookSubscription": true,
+	"/dogfoodpb.v1.DogFoodService/ListWebhookDeliveries":     true,
+}
+
+// APIKeysFromEnv returns API keys by API_KEYS, a comma-separated list of name:key, e.g. alice:s3cr3t,bob:p4ss.
+// It's empty if API_KEYS is not set.
+func APIKeysFromEnv() (map[string]string, error) {
+	keys := map[string]string{}
+	v := os.Getenv("API_KEYS")
+	if v == "" {
+		return keys, nil
+	}
+	for _, pair := range strings.Split(v, ",") {
+		name, key := pair, ""
+		if i := strings.Index(pair, ":"); i >= 0 {
+			name, key = strings.TrimSpace(pair[:i]), strings.TrimSpace(pair[i+1:])
+		}
+		if name == "" || key == "" {
+			return nil, fmt.Errorf("API_KEYS is invalid: %q is not name:key", pair)
+		}
+		if _, ok := keys[name]; ok {
+			return nil, fmt.Errorf("API_KEYS is invalid: %s is duplicated", name)
+		}
+		keys[name] = key
+	}
+	return keys, nil
+}
+
+// principals are names of callers by SHA-256 of their API keys.
+// Keys are looked up by their hashes, so that lookups take no time depending on how much of keys match.
+type principals map[[sha256.Size]byte]string
+
+func newPrincipals(keys map[string]string) principals {
+	p := make(principals, len(keys))
+	for name, key := range keys {
+		p[sha256.Sum256([]byte(key))] = name
+	}
+	return p
+}
+
+// authenticate sets a caller authenticated by an API key to ctx as an actor, with a request ID of ctx.
+// Callers without API keys are unknown actors, which call methods except authenticatedMethods.
+func (p principals) authenticate(ctx context.Context, fullMethod string) (context.Context, error) {
+	a := store.Actor{RequestID: requestid.FromContext(ctx)}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if vs := md.Get(apiKeyMetadataKey); len(vs) > 0 {
+		name, ok := p[sha256.Sum256([]byte(vs[0]))]
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "API key is invalid")
+		}
+		a.Name = name
+	} else if authenticatedMethods[fullMethod] {
+		return nil, status.Error(codes.Unauthenticated, "API key is missing")
+	}
+	return store.WithActor(ctx, a), nil
+}
+
+func (p principals) unaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := p.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+type authServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (p principals) streamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := p.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authServerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// incomingHeaderMatcher forwards X-Api-Key header as metadata in addition to the default headers.
+func incomingHeaderMatcher(key string) (string, bool) {
+	if textproto.CanonicalMIMEHeaderKey(key) == textproto.CanonicalMIMEHeaderKey(apiKeyMetadataKey) {
+		return apiKeyMetadataKey, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
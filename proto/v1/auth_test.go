@@ -0,0 +1,78 @@
+package protov1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gogo/status"
+	"github.com/kei6u/dogfood/pkg/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestAPIKeysFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"unset", "", map[string]string{}, false},
+		{"keys", "alice:a-key, bob:b:key", map[string]string{"alice": "a-key", "bob": "b:key"}, false},
+		{"missing key", "alice", nil, true},
+		{"empty name", ":a-key", nil, true},
+		{"duplicated", "alice:a-key,alice:b-key", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("API_KEYS", tt.env)
+			got, err := APIKeysFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("APIKeysFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("APIKeysFromEnv() = %v, want %v", got, tt.want)
+			}
+			for name, key := range tt.want {
+				if got[name] != key {
+					t.Errorf("APIKeysFromEnv()[%s] = %s, want %s", name, got[name], key)
+				}
+			}
+		})
+	}
+}
+
+func TestPrincipals_Authenticate(t *testing.T) {
+	p := newPrincipals(map[string]string{"alice": "a-key"})
+	const (
+		authenticated = "/dogfoodpb.v1.DogFoodService/DeleteRecord"
+		public        = "/dogfoodpb.v1.DogFoodService/CreateRecord"
+	)
+	tests := []struct {
+		name      string
+		md        metadata.MD
+		method    string
+		wantActor string
+		wantCode  codes.Code
+	}{
+		{"valid key", metadata.Pairs(apiKeyMetadataKey, "a-key"), authenticated, "alice", codes.OK},
+		{"forged actor is ignored", metadata.Pairs(apiKeyMetadataKey, "a-key", "x-actor", "bob"), authenticated, "alice", codes.OK},
+		{"invalid key", metadata.Pairs(apiKeyMetadataKey, "b-key"), public, "", codes.Unauthenticated},
+		{"missing key", metadata.Pairs("x-actor", "alice"), authenticated, "", codes.Unauthenticated},
+		{"anonymous", metadata.Pairs("x-actor", "alice"), public, "unknown", codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, err := p.authenticate(metadata.NewIncomingContext(context.Background(), tt.md), tt.method)
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("authenticate() code = %s, want %s", got, tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			if got := store.ActorFromContext(ctx).Name; got != tt.wantActor {
+				t.Errorf("actor = %s, want %s", got, tt.wantActor)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package protov1
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultDBMetricsRefreshInterval = time.Minute
+	// minDBMetricsRefreshInterval keeps queries to Postgres cheap regardless of how often metrics are scraped.
+	minDBMetricsRefreshInterval = 10 * time.Second
+	dbMetricsRefreshTimeout     = 10 * time.Second
+)
+
+// dbMetricsCollector periodically derives business metrics from the store.
+// Unlike metrics recorded by interceptors, they survive restarts and agree across replicas.
+type dbMetricsCollector struct {
+	s        *Server
+	interval time.Duration
+
+	intake24h          *prometheus.GaugeVec
+	secondsSinceMeal   *prometheus.GaugeVec
+	records            prometheus.Gauge
+	lastRefreshSuccess prometheus.Gauge
+}
+
+func newDBMetricsCollector(s *Server) *dbMetricsCollector {
+	interval := defaultDBMetricsRefreshInterval
+	if v := os.Getenv("METRICS_REFRESH_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			s.logger.Warn("METRICS_REFRESH_INTERVAL is invalid, use default", zap.String("value", v))
+		} else {
+			interval = d
+		}
+	}
+	if interval < minDBMetricsRefreshInterval {
+		interval = minDBMetricsRefreshInterval
+	}
+	return &dbMetricsCollector{
+		s:        s,
+		interval: interval,
+		intake24h: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "dogfood_dog_intake_gram_24h",
+				Help: "how much grams dog ate dogfood in the last 24 hours",
+			},
+			[]string{"dog"},
+		),
+		secondsSinceMeal: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "dogfood_dog_seconds_since_last_meal",
+				Help: "seconds since dog ate dogfood last time",
+			},
+			[]string{"dog"},
+		),
+		records: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "dogfood_records",
+			Help: "the number of records in database",
+		}),
+		lastRefreshSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "dogfood_db_metrics_last_refresh_success_timestamp_seconds",
+			Help: "when metrics were derived from database successfully last time",
+		}),
+	}
+}
+
+func (c *dbMetricsCollector) register(r prometheus.Registerer) error {
+	for _, m := range []prometheus.Collector{c.intake24h, c.secondsSinceMeal, c.records, c.lastRefreshSuccess} {
+		if err := r.Register(m); err != nil {
+			return fmt.Errorf("failed to initialize database metrics to prometheus: %w", err)
+		}
+	}
+	return nil
+}
+
+// run refreshes metrics every interval until ctx is done.
+func (c *dbMetricsCollector) run(ctx context.Context) {
+	t := time.NewTicker(c.interval)
+	defer t.Stop()
+	for {
+		if err := c.refresh(ctx); err != nil {
+			c.s.logger.Warn("failed to refresh database metrics", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (c *dbMetricsCollector) refresh(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, dbMetricsRefreshTimeout)
+	defer cancel()
+
+	now := time.Now()
+	dogs, err := c.s.store.DogStats(ctx, now.Add(-24*time.Hour))
+	if err != nil {
+		return err
+	}
+	records, err := c.s.store.CountRecords(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.intake24h.Reset()
+	c.secondsSinceMeal.Reset()
+	// Dogs are labeled by their names as they are, because they come from the store rather than requests,
+	// and stats of a dog must not be hidden by the others, e.g. a dog which missed meals.
+	for _, d := range dogs {
+		c.intake24h.WithLabelValues(d.DogName).Set(d.Grams)
+		c.secondsSinceMeal.WithLabelValues(d.DogName).Set(now.Sub(d.LastMeal).Seconds())
+	}
+	c.records.Set(float64(records))
+	c.lastRefreshSuccess.Set(float64(now.Unix()))
+	return nil
+}
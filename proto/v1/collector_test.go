@@ -0,0 +1,85 @@
+package protov1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kei6u/dogfood/pkg/store"
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestNewDBMetricsCollector_Interval(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		want time.Duration
+	}{
+		{name: "default", want: defaultDBMetricsRefreshInterval},
+		{name: "valid", env: "30s", want: 30 * time.Second},
+		{name: "too short is raised to minimum", env: "1s", want: minDBMetricsRefreshInterval},
+		{name: "invalid falls back to default", env: "soon", want: defaultDBMetricsRefreshInterval},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("METRICS_REFRESH_INTERVAL", tt.env)
+			if got := newDBMetricsCollector(&Server{logger: zap.NewNop()}).interval; got != tt.want {
+				t.Errorf("interval = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDBMetricsCollector_Refresh(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemoryStore()
+	now := time.Now()
+	for _, r := range []*dogfoodpb.Record{
+		{DogfoodName: "royal", Gram: 100, DogName: "pochi", EatenAt: timestamppb.New(now.Add(-time.Hour))},
+		{DogfoodName: "royal", Gram: 50, DogName: "pochi", EatenAt: timestamppb.New(now.Add(-2 * time.Hour))},
+		{DogfoodName: "royal", Gram: 30, DogName: "tama", EatenAt: timestamppb.New(now.Add(-3 * time.Hour))},
+		{DogfoodName: "royal", Gram: 20, DogName: "hachi", EatenAt: timestamppb.New(now.Add(-4 * time.Hour))},
+		// Records older than 24 hours are not in the intake.
+		{DogfoodName: "royal", Gram: 70, DogName: "hachi", EatenAt: timestamppb.New(now.Add(-25 * time.Hour))},
+	} {
+		if err := st.CreateRecord(ctx, r, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := &Server{logger: zap.NewNop(), store: st, dogLabels: newLabelLimiter([]string{"pochi"}, 1)}
+	c := newDBMetricsCollector(s)
+	if err := c.refresh(ctx); err != nil {
+		t.Fatalf("refresh() error = %v", err)
+	}
+
+	// Dogs out of the allow-list of request metrics are labeled by their names as well.
+	for _, tt := range []struct {
+		dog        string
+		intake     float64
+		minSeconds float64
+	}{
+		{dog: "pochi", intake: 150, minSeconds: time.Hour.Seconds()},
+		{dog: "tama", intake: 30, minSeconds: (3 * time.Hour).Seconds()},
+		{dog: "hachi", intake: 20, minSeconds: (4 * time.Hour).Seconds()},
+	} {
+		if got := testutil.ToFloat64(c.intake24h.WithLabelValues(tt.dog)); got != tt.intake {
+			t.Errorf("dogfood_dog_intake_gram_24h{dog=%q} = %v, want %v", tt.dog, got, tt.intake)
+		}
+		if got := testutil.ToFloat64(c.secondsSinceMeal.WithLabelValues(tt.dog)); got < tt.minSeconds || got > tt.minSeconds+60 {
+			t.Errorf("dogfood_dog_seconds_since_last_meal{dog=%q} = %v, want about %v", tt.dog, got, tt.minSeconds)
+		}
+	}
+	if got := testutil.CollectAndCount(c.intake24h); got != 3 {
+		t.Errorf("dogfood_dog_intake_gram_24h has %d series, want 3", got)
+	}
+	if got := testutil.ToFloat64(c.records); got != 5 {
+		t.Errorf("dogfood_records = %v, want 5", got)
+	}
+	if got := testutil.ToFloat64(c.lastRefreshSuccess); got < float64(now.Unix()) {
+		t.Errorf("dogfood_db_metrics_last_refresh_success_timestamp_seconds = %v, want at least %d", got, now.Unix())
+	}
+}
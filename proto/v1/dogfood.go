@@ -3,66 +3,116 @@ package protov1
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
 	"github.com/gogo/status"
+	"github.com/kei6u/dogfood/pkg/store"
+	"github.com/kei6u/dogfood/pkg/telemetry"
 	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
-	_ "github.com/lib/pq"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/protobuf/types/known/timestamppb"
-	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 )
 
 func (s *Server) CreateRecord(ctx context.Context, req *dogfoodpb.CreateRecordRequest) (*dogfoodpb.Record, error) {
-	var span tracer.Span
-	span, ctx = tracer.StartSpanFromContext(ctx, "CreateRecord", tracer.ResourceName("Record"))
+	var span telemetry.Span
+	span, ctx = telemetry.StartSpanFromContext(ctx, "CreateRecord", "Record")
 	defer span.Finish()
 
 	eatenAt := time.Now()
-	if err := s.db.QueryRowContext(
-		ctx,
-		"INSERT INTO record (dogfood_name, gram, dog_name, eaten_at) VALUES ($1, $2, $3, $4)",
-		req.GetDogfoodName(), req.GetGram(), req.GetDogName(), eatenAt,
-	).Err(); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create a record: %s", err)
-	}
-	return &dogfoodpb.Record{
+	r := &dogfoodpb.Record{
 		DogfoodName: req.GetDogfoodName(),
 		Gram:        req.GetGram(),
 		DogName:     req.GetDogName(),
 		EatenAt:     timestamppb.New(eatenAt),
-	}, nil
+	}
+
+	// Events are emitted in the same transaction, so that they are delivered if and only if the record is created.
+	if err := s.store.CreateRecord(ctx, r, func(ctx context.Context, tx *sql.Tx) error {
+		return s.onRecordCreated(ctx, tx, r)
+	}); err != nil {
+		if errors.Is(err, store.ErrAlreadyExists) {
+			return nil, status.Error(codes.AlreadyExists, "record already exists")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to create a record: %s", err)
+	}
+	return r, nil
 }
 
-func (s *Server) ListRecords(ctx context.Context, req *dogfoodpb.ListRecordsRequest) (*dogfoodpb.ListRecordsResponse, error) {
-	var span tracer.Span
-	span, ctx = tracer.StartSpanFromContext(ctx, "ListRecords", tracer.ResourceName("Records"))
+func (s *Server) UpdateRecord(ctx context.Context, req *dogfoodpb.UpdateRecordRequest) (*dogfoodpb.Record, error) {
+	var span telemetry.Span
+	span, ctx = telemetry.StartSpanFromContext(ctx, "UpdateRecord", "Record")
 	defer span.Finish()
 
-	rows, err := s.db.QueryContext(
-		ctx,
-		"SELECT * FROM record WHERE eaten_at >= $1 AND eaten_at < $2 LIMIT $3",
-		req.GetFrom().AsTime(), req.GetTo().AsTime(), req.GetPageSize(),
-	)
-	defer rows.Close()
-	if err == sql.ErrNoRows {
-		return nil, status.Error(codes.NotFound, "no record are found")
+	r, err := s.store.UpdateRecord(ctx, req.GetId(), func(r *dogfoodpb.Record) error {
+		if req.GetDogfoodName() != "" {
+			r.DogfoodName = req.GetDogfoodName()
+		}
+		if req.GetGram() != 0 {
+			r.Gram = req.GetGram()
+		}
+		if req.GetDogName() != "" {
+			r.DogName = req.GetDogName()
+		}
+		if req.GetEatenAt() != nil {
+			r.EatenAt = req.GetEatenAt()
+		}
+		if err := validateImportRecord(r); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, recordStatusError(err, "failed to update a record")
 	}
+	return r, nil
+}
+
+func (s *Server) DeleteRecord(ctx context.Context, req *dogfoodpb.DeleteRecordRequest) (*dogfoodpb.DeleteRecordResponse, error) {
+	var span telemetry.Span
+	span, ctx = telemetry.StartSpanFromContext(ctx, "DeleteRecord", "Record")
+	defer span.Finish()
+
+	if err := s.store.DeleteRecord(ctx, req.GetId(), req.GetPurge()); err != nil {
+		return nil, recordStatusError(err, "failed to delete a record")
+	}
+	return &dogfoodpb.DeleteRecordResponse{}, nil
+}
+
+func (s *Server) RestoreRecord(ctx context.Context, req *dogfoodpb.RestoreRecordRequest) (*dogfoodpb.Record, error) {
+	var span telemetry.Span
+	span, ctx = telemetry.StartSpanFromContext(ctx, "RestoreRecord", "Record")
+	defer span.Finish()
+
+	r, err := s.store.RestoreRecord(ctx, req.GetId())
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to list up records: %s", err)
+		return nil, recordStatusError(err, "failed to restore a record")
 	}
-	var rs []*dogfoodpb.Record
-	for rows.Next() {
-		var r dogfoodpb.Record
-		var t time.Time
-		if err := rows.Scan(&r.DogfoodName, &r.Gram, &r.DogName, &t); err != nil {
-			return nil, status.Errorf(codes.Internal, "failed to list up records: %s", err)
-		}
-		r.EatenAt = timestamppb.New(t)
-		rs = append(rs, &r)
+	return r, nil
+}
+
+// recordStatusError converts an error of mutating a record to a status error.
+func recordStatusError(err error, msg string) error {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return status.Error(codes.NotFound, "record is not found")
+	case errors.Is(err, store.ErrAlreadyExists):
+		return status.Error(codes.AlreadyExists, "record already exists")
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
 	}
-	if err = rows.Err(); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to list data: %s", err)
+	return status.Errorf(codes.Internal, "%s: %s", msg, err)
+}
+
+func (s *Server) ListRecords(ctx context.Context, req *dogfoodpb.ListRecordsRequest) (*dogfoodpb.ListRecordsResponse, error) {
+	var span telemetry.Span
+	span, ctx = telemetry.StartSpanFromContext(ctx, "ListRecords", "Records")
+	defer span.Finish()
+
+	rs, err := s.store.ListRecords(ctx, req.GetFrom().AsTime(), req.GetTo().AsTime(), req.GetPageSize())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list up records: %s", err)
 	}
 	if len(rs) == 0 {
 		return nil, status.Error(codes.NotFound, "no record are found")
@@ -20,8 +20,36 @@ const _ = grpc.SupportPackageIsVersion7
 type DogFoodServiceClient interface {
 	// CreateRecord create a record who ate what, when, and how much.
 	CreateRecord(ctx context.Context, in *CreateRecordRequest, opts ...grpc.CallOption) (*Record, error)
-	// ListRecords list up all records.
+	// UpdateRecord correct a record. Fields which are not specified are unchanged.
+	UpdateRecord(ctx context.Context, in *UpdateRecordRequest, opts ...grpc.CallOption) (*Record, error)
+	// DeleteRecord delete a record. It's soft deleted, so that it can be restored, unless purge is specified.
+	DeleteRecord(ctx context.Context, in *DeleteRecordRequest, opts ...grpc.CallOption) (*DeleteRecordResponse, error)
+	// RestoreRecord restore a soft deleted record.
+	RestoreRecord(ctx context.Context, in *RestoreRecordRequest, opts ...grpc.CallOption) (*Record, error)
+	// ListAuditEvents list up mutations of records in descending order of time, e.g. history of a record.
+	ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*ListAuditEventsResponse, error)
+	// ListRecords list up records in ascending order of eaten_at.
 	ListRecords(ctx context.Context, in *ListRecordsRequest, opts ...grpc.CallOption) (*ListRecordsResponse, error)
+	// ExportRecords stream all records in ascending order of eaten_at.
+	// GET /v1/dogfood/records/export downloads them as CSV or NDJSON.
+	ExportRecords(ctx context.Context, in *ExportRecordsRequest, opts ...grpc.CallOption) (DogFoodService_ExportRecordsClient, error)
+	// ImportRecords import records streamed by a client, e.g. rows of CSV or NDJSON.
+	// Imported records don't emit events or webhooks, since they are historical.
+	ImportRecords(ctx context.Context, opts ...grpc.CallOption) (DogFoodService_ImportRecordsClient, error)
+	// ListAlerts list up state transitions of feeding alerts in descending order of time.
+	ListAlerts(ctx context.Context, in *ListAlertsRequest, opts ...grpc.CallOption) (*ListAlertsResponse, error)
+	// CreateWebhookSubscription subscribes a URL to events, e.g. record.created.
+	CreateWebhookSubscription(ctx context.Context, in *CreateWebhookSubscriptionRequest, opts ...grpc.CallOption) (*WebhookSubscription, error)
+	// GetWebhookSubscription get a webhook subscription.
+	GetWebhookSubscription(ctx context.Context, in *GetWebhookSubscriptionRequest, opts ...grpc.CallOption) (*WebhookSubscription, error)
+	// ListWebhookSubscriptions list up all webhook subscriptions.
+	ListWebhookSubscriptions(ctx context.Context, in *ListWebhookSubscriptionsRequest, opts ...grpc.CallOption) (*ListWebhookSubscriptionsResponse, error)
+	// UpdateWebhookSubscription update a URL or rotate a secret of a webhook subscription.
+	UpdateWebhookSubscription(ctx context.Context, in *UpdateWebhookSubscriptionRequest, opts ...grpc.CallOption) (*WebhookSubscription, error)
+	// DeleteWebhookSubscription delete a webhook subscription and its deliveries.
+	DeleteWebhookSubscription(ctx context.Context, in *DeleteWebhookSubscriptionRequest, opts ...grpc.CallOption) (*DeleteWebhookSubscriptionResponse, error)
+	// ListWebhookDeliveries list up deliveries of a webhook subscription in descending order of creation.
+	ListWebhookDeliveries(ctx context.Context, in *ListWebhookDeliveriesRequest, opts ...grpc.CallOption) (*ListWebhookDeliveriesResponse, error)
 }
 
 type dogFoodServiceClient struct {
@@ -41,6 +69,42 @@ func (c *dogFoodServiceClient) CreateRecord(ctx context.Context, in *CreateRecor
 	return out, nil
 }
 
+func (c *dogFoodServiceClient) UpdateRecord(ctx context.Context, in *UpdateRecordRequest, opts ...grpc.CallOption) (*Record, error) {
+	out := new(Record)
+	err := c.cc.Invoke(ctx, "/dogfoodpb.v1.DogFoodService/UpdateRecord", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dogFoodServiceClient) DeleteRecord(ctx context.Context, in *DeleteRecordRequest, opts ...grpc.CallOption) (*DeleteRecordResponse, error) {
+	out := new(DeleteRecordResponse)
+	err := c.cc.Invoke(ctx, "/dogfoodpb.v1.DogFoodService/DeleteRecord", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dogFoodServiceClient) RestoreRecord(ctx context.Context, in *RestoreRecordRequest, opts ...grpc.CallOption) (*Record, error) {
+	out := new(Record)
+	err := c.cc.Invoke(ctx, "/dogfoodpb.v1.DogFoodService/RestoreRecord", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dogFoodServiceClient) ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*ListAuditEventsResponse, error) {
+	out := new(ListAuditEventsResponse)
+	err := c.cc.Invoke(ctx, "/dogfoodpb.v1.DogFoodService/ListAuditEvents", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *dogFoodServiceClient) ListRecords(ctx context.Context, in *ListRecordsRequest, opts ...grpc.CallOption) (*ListRecordsResponse, error) {
 	out := new(ListRecordsResponse)
 	err := c.cc.Invoke(ctx, "/dogfoodpb.v1.DogFoodService/ListRecords", in, out, opts...)
@@ -50,14 +114,171 @@ func (c *dogFoodServiceClient) ListRecords(ctx context.Context, in *ListRecordsR
 	return out, nil
 }
 
+func (c *dogFoodServiceClient) ExportRecords(ctx context.Context, in *ExportRecordsRequest, opts ...grpc.CallOption) (DogFoodService_ExportRecordsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &DogFoodService_ServiceDesc.Streams[0], "/dogfoodpb.v1.DogFoodService/ExportRecords", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &dogFoodServiceExportRecordsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type DogFoodService_ExportRecordsClient interface {
+	Recv() (*Record, error)
+	grpc.ClientStream
+}
+
+type dogFoodServiceExportRecordsClient struct {
+	grpc.ClientStream
+}
+
+func (x *dogFoodServiceExportRecordsClient) Recv() (*Record, error) {
+	m := new(Record)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *dogFoodServiceClient) ImportRecords(ctx context.Context, opts ...grpc.CallOption) (DogFoodService_ImportRecordsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &DogFoodService_ServiceDesc.Streams[1], "/dogfoodpb.v1.DogFoodService/ImportRecords", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &dogFoodServiceImportRecordsClient{stream}
+	return x, nil
+}
+
+type DogFoodService_ImportRecordsClient interface {
+	Send(*ImportRecordsRequest) error
+	CloseAndRecv() (*ImportRecordsResponse, error)
+	grpc.ClientStream
+}
+
+type dogFoodServiceImportRecordsClient struct {
+	grpc.ClientStream
+}
+
+func (x *dogFoodServiceImportRecordsClient) Send(m *ImportRecordsRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *dogFoodServiceImportRecordsClient) CloseAndRecv() (*ImportRecordsResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(ImportRecordsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *dogFoodServiceClient) ListAlerts(ctx context.Context, in *ListAlertsRequest, opts ...grpc.CallOption) (*ListAlertsResponse, error) {
+	out := new(ListAlertsResponse)
+	err := c.cc.Invoke(ctx, "/dogfoodpb.v1.DogFoodService/ListAlerts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dogFoodServiceClient) CreateWebhookSubscription(ctx context.Context, in *CreateWebhookSubscriptionRequest, opts ...grpc.CallOption) (*WebhookSubscription, error) {
+	out := new(WebhookSubscription)
+	err := c.cc.Invoke(ctx, "/dogfoodpb.v1.DogFoodService/CreateWebhookSubscription", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dogFoodServiceClient) GetWebhookSubscription(ctx context.Context, in *GetWebhookSubscriptionRequest, opts ...grpc.CallOption) (*WebhookSubscription, error) {
+	out := new(WebhookSubscription)
+	err := c.cc.Invoke(ctx, "/dogfoodpb.v1.DogFoodService/GetWebhookSubscription", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dogFoodServiceClient) ListWebhookSubscriptions(ctx context.Context, in *ListWebhookSubscriptionsRequest, opts ...grpc.CallOption) (*ListWebhookSubscriptionsResponse, error) {
+	out := new(ListWebhookSubscriptionsResponse)
+	err := c.cc.Invoke(ctx, "/dogfoodpb.v1.DogFoodService/ListWebhookSubscriptions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dogFoodServiceClient) UpdateWebhookSubscription(ctx context.Context, in *UpdateWebhookSubscriptionRequest, opts ...grpc.CallOption) (*WebhookSubscription, error) {
+	out := new(WebhookSubscription)
+	err := c.cc.Invoke(ctx, "/dogfoodpb.v1.DogFoodService/UpdateWebhookSubscription", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dogFoodServiceClient) DeleteWebhookSubscription(ctx context.Context, in *DeleteWebhookSubscriptionRequest, opts ...grpc.CallOption) (*DeleteWebhookSubscriptionResponse, error) {
+	out := new(DeleteWebhookSubscriptionResponse)
+	err := c.cc.Invoke(ctx, "/dogfoodpb.v1.DogFoodService/DeleteWebhookSubscription", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dogFoodServiceClient) ListWebhookDeliveries(ctx context.Context, in *ListWebhookDeliveriesRequest, opts ...grpc.CallOption) (*ListWebhookDeliveriesResponse, error) {
+	out := new(ListWebhookDeliveriesResponse)
+	err := c.cc.Invoke(ctx, "/dogfoodpb.v1.DogFoodService/ListWebhookDeliveries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DogFoodServiceServer is the server API for DogFoodService service.
 // All implementations should embed UnimplementedDogFoodServiceServer
 // for forward compatibility
 type DogFoodServiceServer interface {
 	// CreateRecord create a record who ate what, when, and how much.
 	CreateRecord(context.Context, *CreateRecordRequest) (*Record, error)
-	// ListRecords list up all records.
+	// UpdateRecord correct a record. Fields which are not specified are unchanged.
+	UpdateRecord(context.Context, *UpdateRecordRequest) (*Record, error)
+	// DeleteRecord delete a record. It's soft deleted, so that it can be restored, unless purge is specified.
+	DeleteRecord(context.Context, *DeleteRecordRequest) (*DeleteRecordResponse, error)
+	// RestoreRecord restore a soft deleted record.
+	RestoreRecord(context.Context, *RestoreRecordRequest) (*Record, error)
+	// ListAuditEvents list up mutations of records in descending order of time, e.g. history of a record.
+	ListAuditEvents(context.Context, *ListAuditEventsRequest) (*ListAuditEventsResponse, error)
+	// ListRecords list up records in ascending order of eaten_at.
 	ListRecords(context.Context, *ListRecordsRequest) (*ListRecordsResponse, error)
+	// ExportRecords stream all records in ascending order of eaten_at.
+	// GET /v1/dogfood/records/export downloads them as CSV or NDJSON.
+	ExportRecords(*ExportRecordsRequest, DogFoodService_ExportRecordsServer) error
+	// ImportRecords import records streamed by a client, e.g. rows of CSV or NDJSON.
+	// Imported records don't emit events or webhooks, since they are historical.
+	ImportRecords(DogFoodService_ImportRecordsServer) error
+	// ListAlerts list up state transitions of feeding alerts in descending order of time.
+	ListAlerts(context.Context, *ListAlertsRequest) (*ListAlertsResponse, error)
+	// CreateWebhookSubscription subscribes a URL to events, e.g. record.created.
+	CreateWebhookSubscription(context.Context, *CreateWebhookSubscriptionRequest) (*WebhookSubscription, error)
+	// GetWebhookSubscription get a webhook subscription.
+	GetWebhookSubscription(context.Context, *GetWebhookSubscriptionRequest) (*WebhookSubscription, error)
+	// ListWebhookSubscriptions list up all webhook subscriptions.
+	ListWebhookSubscriptions(context.Context, *ListWebhookSubscriptionsRequest) (*ListWebhookSubscriptionsResponse, error)
+	// UpdateWebhookSubscription update a URL or rotate a secret of a webhook subscription.
+	UpdateWebhookSubscription(context.Context, *UpdateWebhookSubscriptionRequest) (*WebhookSubscription, error)
+	// DeleteWebhookSubscription delete a webhook subscription and its deliveries.
+	DeleteWebhookSubscription(context.Context, *DeleteWebhookSubscriptionRequest) (*DeleteWebhookSubscriptionResponse, error)
+	// ListWebhookDeliveries list up deliveries of a webhook subscription in descending order of creation.
+	ListWebhookDeliveries(context.Context, *ListWebhookDeliveriesRequest) (*ListWebhookDeliveriesResponse, error)
 }
 
 // UnimplementedDogFoodServiceServer should be embedded to have forward compatible implementations.
@@ -67,9 +288,48 @@ type UnimplementedDogFoodServiceServer struct {
 func (UnimplementedDogFoodServiceServer) CreateRecord(context.Context, *CreateRecordRequest) (*Record, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateRecord not implemented")
 }
+func (UnimplementedDogFoodServiceServer) UpdateRecord(context.Context, *UpdateRecordRequest) (*Record, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateRecord not implemented")
+}
+func (UnimplementedDogFoodServiceServer) DeleteRecord(context.Context, *DeleteRecordRequest) (*DeleteRecordResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteRecord not implemented")
+}
+func (UnimplementedDogFoodServiceServer) RestoreRecord(context.Context, *RestoreRecordRequest) (*Record, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreRecord not implemented")
+}
+func (UnimplementedDogFoodServiceServer) ListAuditEvents(context.Context, *ListAuditEventsRequest) (*ListAuditEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAuditEvents not implemented")
+}
 func (UnimplementedDogFoodServiceServer) ListRecords(context.Context, *ListRecordsRequest) (*ListRecordsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListRecords not implemented")
 }
+func (UnimplementedDogFoodServiceServer) ExportRecords(*ExportRecordsRequest, DogFoodService_ExportRecordsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ExportRecords not implemented")
+}
+func (UnimplementedDogFoodServiceServer) ImportRecords(DogFoodService_ImportRecordsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ImportRecords not implemented")
+}
+func (UnimplementedDogFoodServiceServer) ListAlerts(context.Context, *ListAlertsRequest) (*ListAlertsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAlerts not implemented")
+}
+func (UnimplementedDogFoodServiceServer) CreateWebhookSubscription(context.Context, *CreateWebhookSubscriptionRequest) (*WebhookSubscription, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateWebhookSubscription not implemented")
+}
+func (UnimplementedDogFoodServiceServer) GetWebhookSubscription(context.Context, *GetWebhookSubscriptionRequest) (*WebhookSubscription, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWebhookSubscription not implemented")
+}
+func (UnimplementedDogFoodServiceServer) ListWebhookSubscriptions(context.Context, *ListWebhookSubscriptionsRequest) (*ListWebhookSubscriptionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListWebhookSubscriptions not implemented")
+}
+func (UnimplementedDogFoodServiceServer) UpdateWebhookSubscription(context.Context, *UpdateWebhookSubscriptionRequest) (*WebhookSubscription, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateWebhookSubscription not implemented")
+}
+func (UnimplementedDogFoodServiceServer) DeleteWebhookSubscription(context.Context, *DeleteWebhookSubscriptionRequest) (*DeleteWebhookSubscriptionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteWebhookSubscription not implemented")
+}
+func (UnimplementedDogFoodServiceServer) ListWebhookDeliveries(context.Context, *ListWebhookDeliveriesRequest) (*ListWebhookDeliveriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListWebhookDeliveries not implemented")
+}
 
 // UnsafeDogFoodServiceServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to DogFoodServiceServer will
@@ -100,6 +360,78 @@ func _DogFoodService_CreateRecord_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DogFoodService_UpdateRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRecordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DogFoodServiceServer).UpdateRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dogfoodpb.v1.DogFoodService/UpdateRecord",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DogFoodServiceServer).UpdateRecord(ctx, req.(*UpdateRecordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DogFoodService_DeleteRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRecordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DogFoodServiceServer).DeleteRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dogfoodpb.v1.DogFoodService/DeleteRecord",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DogFoodServiceServer).DeleteRecord(ctx, req.(*DeleteRecordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DogFoodService_RestoreRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreRecordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DogFoodServiceServer).RestoreRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dogfoodpb.v1.DogFoodService/RestoreRecord",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DogFoodServiceServer).RestoreRecord(ctx, req.(*RestoreRecordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DogFoodService_ListAuditEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAuditEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DogFoodServiceServer).ListAuditEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dogfoodpb.v1.DogFoodService/ListAuditEvents",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DogFoodServiceServer).ListAuditEvents(ctx, req.(*ListAuditEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _DogFoodService_ListRecords_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ListRecordsRequest)
 	if err := dec(in); err != nil {
@@ -118,6 +450,179 @@ func _DogFoodService_ListRecords_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DogFoodService_ExportRecords_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportRecordsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DogFoodServiceServer).ExportRecords(m, &dogFoodServiceExportRecordsServer{stream})
+}
+
+type DogFoodService_ExportRecordsServer interface {
+	Send(*Record) error
+	grpc.ServerStream
+}
+
+type dogFoodServiceExportRecordsServer struct {
+	grpc.ServerStream
+}
+
+func (x *dogFoodServiceExportRecordsServer) Send(m *Record) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _DogFoodService_ImportRecords_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DogFoodServiceServer).ImportRecords(&dogFoodServiceImportRecordsServer{stream})
+}
+
+type DogFoodService_ImportRecordsServer interface {
+	SendAndClose(*ImportRecordsResponse) error
+	Recv() (*ImportRecordsRequest, error)
+	grpc.ServerStream
+}
+
+type dogFoodServiceImportRecordsServer struct {
+	grpc.ServerStream
+}
+
+func (x *dogFoodServiceImportRecordsServer) SendAndClose(m *ImportRecordsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *dogFoodServiceImportRecordsServer) Recv() (*ImportRecordsRequest, error) {
+	m := new(ImportRecordsRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _DogFoodService_ListAlerts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAlertsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DogFoodServiceServer).ListAlerts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dogfoodpb.v1.DogFoodService/ListAlerts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DogFoodServiceServer).ListAlerts(ctx, req.(*ListAlertsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DogFoodService_CreateWebhookSubscription_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateWebhookSubscriptionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DogFoodServiceServer).CreateWebhookSubscription(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dogfoodpb.v1.DogFoodService/CreateWebhookSubscription",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DogFoodServiceServer).CreateWebhookSubscription(ctx, req.(*CreateWebhookSubscriptionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DogFoodService_GetWebhookSubscription_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWebhookSubscriptionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DogFoodServiceServer).GetWebhookSubscription(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dogfoodpb.v1.DogFoodService/GetWebhookSubscription",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DogFoodServiceServer).GetWebhookSubscription(ctx, req.(*GetWebhookSubscriptionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DogFoodService_ListWebhookSubscriptions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListWebhookSubscriptionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DogFoodServiceServer).ListWebhookSubscriptions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dogfoodpb.v1.DogFoodService/ListWebhookSubscriptions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DogFoodServiceServer).ListWebhookSubscriptions(ctx, req.(*ListWebhookSubscriptionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DogFoodService_UpdateWebhookSubscription_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateWebhookSubscriptionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DogFoodServiceServer).UpdateWebhookSubscription(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dogfoodpb.v1.DogFoodService/UpdateWebhookSubscription",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DogFoodServiceServer).UpdateWebhookSubscription(ctx, req.(*UpdateWebhookSubscriptionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DogFoodService_DeleteWebhookSubscription_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteWebhookSubscriptionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DogFoodServiceServer).DeleteWebhookSubscription(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dogfoodpb.v1.DogFoodService/DeleteWebhookSubscription",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DogFoodServiceServer).DeleteWebhookSubscription(ctx, req.(*DeleteWebhookSubscriptionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DogFoodService_ListWebhookDeliveries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListWebhookDeliveriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DogFoodServiceServer).ListWebhookDeliveries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dogfoodpb.v1.DogFoodService/ListWebhookDeliveries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DogFoodServiceServer).ListWebhookDeliveries(ctx, req.(*ListWebhookDeliveriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // DogFoodService_ServiceDesc is the grpc.ServiceDesc for DogFoodService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -129,11 +634,66 @@ var DogFoodService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CreateRecord",
 			Handler:    _DogFoodService_CreateRecord_Handler,
 		},
+		{
+			MethodName: "UpdateRecord",
+			Handler:    _DogFoodService_UpdateRecord_Handler,
+		},
+		{
+			MethodName: "DeleteRecord",
+			Handler:    _DogFoodService_DeleteRecord_Handler,
+		},
+		{
+			MethodName: "RestoreRecord",
+			Handler:    _DogFoodService_RestoreRecord_Handler,
+		},
+		{
+			MethodName: "ListAuditEvents",
+			Handler:    _DogFoodService_ListAuditEvents_Handler,
+		},
 		{
 			MethodName: "ListRecords",
 			Handler:    _DogFoodService_ListRecords_Handler,
 		},
+		{
+			MethodName: "ListAlerts",
+			Handler:    _DogFoodService_ListAlerts_Handler,
+		},
+		{
+			MethodName: "CreateWebhookSubscription",
+			Handler:    _DogFoodService_CreateWebhookSubscription_Handler,
+		},
+		{
+			MethodName: "GetWebhookSubscription",
+			Handler:    _DogFoodService_GetWebhookSubscription_Handler,
+		},
+		{
+			MethodName: "ListWebhookSubscriptions",
+			Handler:    _DogFoodService_ListWebhookSubscriptions_Handler,
+		},
+		{
+			MethodName: "UpdateWebhookSubscription",
+			Handler:    _DogFoodService_UpdateWebhookSubscription_Handler,
+		},
+		{
+			MethodName: "DeleteWebhookSubscription",
+			Handler:    _DogFoodService_DeleteWebhookSubscription_Handler,
+		},
+		{
+			MethodName: "ListWebhookDeliveries",
+			Handler:    _DogFoodService_ListWebhookDeliveries_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExportRecords",
+			Handler:       _DogFoodService_ExportRecords_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ImportRecords",
+			Handler:       _DogFoodService_ImportRecords_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/v1/dogfood/dogfood.proto",
 }
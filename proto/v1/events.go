@@ -0,0 +1,83 @@
+package protov1
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kei6u/dogfood/pkg/outbox"
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// RecordCreatedTopic is a topic of dogfoodpb.RecordCreated events.
+const RecordCreatedTopic = "dogfood.record.created"
+
+// onRecordCreated emits events of a created record within tx.
+// tx is nil if the store is not backed by Postgres, where no events are emitted.
+func (s *Server) onRecordCreated(ctx context.Context, tx *sql.Tx, r *dogfoodpb.Record) error {
+	payload, err := recordCreatedPayload(r)
+	if err != nil {
+		return err
+	}
+	if s.webhooks != nil {
+		if err := s.webhooks.Enqueue(ctx, tx, recordCreatedEventType, payload); err != nil {
+			return err
+		}
+	}
+
+	if s.outboxRelay == nil {
+		return nil
+	}
+	eventID, err := outbox.NewEventID()
+	if err != nil {
+		return err
+	}
+	b, err := proto.Marshal(&dogfoodpb.RecordCreated{
+		EventId:    eventID,
+		Record:     r,
+		OccurredAt: r.GetEatenAt(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode RecordCreated: %w", err)
+	}
+	return s.outbox.Add(ctx, tx, &outbox.Message{
+		EventID: eventID,
+		Topic:   RecordCreatedTopic,
+		Key:     r.GetDogName(),
+		Payload: b,
+	})
+}
+
+// initializeOutbox configures a relay to broker by OUTBOX_POLL_INTERVAL.
+// Events are not written to the outbox if broker is nil.
+func (s *Server) initializeOutbox(broker outbox.Broker) error {
+	if broker == nil {
+		return nil
+	}
+	if s.db == nil {
+		return errors.New("outbox requires Postgres")
+	}
+	s.outbox = outbox.NewPsqlStore(s.db)
+	var opts []outbox.RelayOption
+	if v := os.Getenv("OUTBOX_POLL_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			opts = append(opts, outbox.WithPollInterval(d))
+		} else {
+			s.logger.Warn("OUTBOX_POLL_INTERVAL is invalid, use default", zap.String("value", v))
+		}
+	}
+	s.outboxRelay = outbox.NewRelay(s.outbox, broker, s.logger, opts...)
+	return nil
+}
+
+func (s *Server) runOutboxRelay(ctx context.Context) {
+	if s.outboxRelay == nil {
+		return
+	}
+	s.outboxRelay.Run(ctx)
+}
@@ -0,0 +1,156 @@
+package protov1
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gogo/status"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/kei6u/dogfood/pkg/recordio"
+	"github.com/kei6u/dogfood/pkg/requestid"
+	"github.com/kei6u/dogfood/pkg/store"
+	"github.com/kei6u/dogfood/pkg/telemetry"
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	exportRecordsPath = "/v1/dogfood/records/export"
+	// exportFlushRows is how many rows are written before flushing them to a client.
+	exportFlushRows = 100
+)
+
+func (s *Server) ExportRecords(req *dogfoodpb.ExportRecordsRequest, stream dogfoodpb.DogFoodService_ExportRecordsServer) error {
+	span, ctx := telemetry.StartSpanFromContext(stream.Context(), "ExportRecords", "Records")
+	defer span.Finish()
+
+	to := time.Now()
+	if req.GetTo() != nil {
+		to = req.GetTo().AsTime()
+	}
+	from := req.GetFrom().AsTime()
+	if !from.Before(to) {
+		return status.Error(codes.InvalidArgument, "from must be before to")
+	}
+
+	// Records are sent as they are read, so that the result set is never held in memory.
+	var sendErr error
+	if err := s.store.ExportRecords(ctx, store.ExportFilter{
+		From:        from,
+		To:          to,
+		DogName:     req.GetDogName(),
+		DogfoodName: req.GetDogfoodName(),
+	}, func(r *dogfoodpb.Record) error {
+		sendErr = stream.Send(r)
+		return sendErr
+	}); err != nil {
+		if sendErr != nil {
+			return sendErr
+		}
+		return status.Errorf(codes.Internal, "failed to export records: %s", err)
+	}
+	return nil
+}
+
+// parseExportRecordsRequest parses query parameters, from and to in RFC 3339, dog_name and dogfood_name.
+func parseExportRecordsRequest(r *http.Request) (*dogfoodpb.ExportRecordsRequest, error) {
+	q := r.URL.Query()
+	req := &dogfoodpb.ExportRecordsRequest{
+		DogName:     q.Get("dog_name"),
+		DogfoodName: q.Get("dogfood_name"),
+	}
+	for _, p := range []struct {
+		name string
+		ts   **timestamppb.Timestamp
+	}{
+		{"from", &req.From},
+		{"to", &req.To},
+	} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be RFC 3339: %w", p.name, err)
+		}
+		*p.ts = timestamppb.New(t)
+	}
+	return req, nil
+}
+
+// exportRecordsHandler downloads records streamed by ExportRecords as CSV or NDJSON.
+func exportRecordsHandler(client dogfoodpb.DogFoodServiceClient, logger *zap.Logger) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		contentType, ok := recordio.Negotiate(r.Header.Get("Accept"))
+		if !ok {
+			http.Error(w, fmt.Sprintf("only %s and %s are acceptable", recordio.ContentTypeCSV, recordio.ContentTypeNDJSON), http.StatusNotAcceptable)
+			return
+		}
+		req, err := parseExportRecordsRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(metadata.NewOutgoingContext(r.Context(), metadata.Join(telemetry.MetadataFromRequest(r), requestid.Metadata(r.Context()))))
+		defer cancel()
+		stream, err := client.ExportRecords(ctx, req)
+		if err != nil {
+			http.Error(w, err.Error(), runtime.HTTPStatusFromCode(status.Code(err)))
+			return
+		}
+
+		// The status is decided by the first message, because it can't be changed once rows are written.
+		first, err := stream.Recv()
+		if err != nil && err != io.EOF {
+			http.Error(w, status.Convert(err).Message(), runtime.HTTPStatusFromCode(status.Code(err)))
+			return
+		}
+		ext := "csv"
+		if contentType == recordio.ContentTypeNDJSON {
+			ext = "ndjson"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="records.%s"`, ext))
+		w.WriteHeader(http.StatusOK)
+
+		rw := recordio.NewWriter(contentType, w)
+		flush := func() error {
+			if err := rw.Flush(); err != nil {
+				return err
+			}
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+			return nil
+		}
+		for n, rec := 0, first; err != io.EOF; n++ {
+			if err := rw.Write(rec); err != nil {
+				logger.Warn("failed to write exported records", zap.Error(err))
+				return
+			}
+			if n%exportFlushRows == exportFlushRows-1 {
+				if err := flush(); err != nil {
+					logger.Warn("failed to write exported records", zap.Error(err))
+					return
+				}
+			}
+			if rec, err = stream.Recv(); err != nil && err != io.EOF {
+				// Abort the response so that the client doesn't mistake a truncated download for a complete one.
+				logger.Error("failed to export records", zap.Error(err))
+				flush()
+				panic(http.ErrAbortHandler)
+			}
+		}
+		if err := flush(); err != nil {
+			logger.Warn("failed to write exported records", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package protov1
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kei6u/dogfood/pkg/recordio"
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type fakeExportClient struct {
+	dogfoodpb.DogFoodServiceClient
+	records []*dogfoodpb.Record
+	req     *dogfoodpb.ExportRecordsRequest
+}
+
+func (c *fakeExportClient) ExportRecords(_ context.Context, req *dogfoodpb.ExportRecordsRequest, _ ...grpc.CallOption) (dogfoodpb.DogFoodService_ExportRecordsClient, error) {
+	c.req = req
+	return &fakeExportStream{records: c.records}, nil
+}
+
+type fakeExportStream struct {
+	grpc.ClientStream
+	records []*dogfoodpb.Record
+}
+
+func (s *fakeExportStream) Recv() (*dogfoodpb.Record, error) {
+	if len(s.records) == 0 {
+		return nil, io.EOF
+	}
+	r := s.records[0]
+	s.records = s.records[1:]
+	return r, nil
+}
+
+func TestExportRecordsHandler(t *testing.T) {
+	eatenAt := time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)
+	records := []*dogfoodpb.Record{
+		{DogfoodName: "a", Gram: 100, DogName: "pochi", EatenAt: timestamppb.New(eatenAt)},
+		{DogfoodName: "b,c", Gram: 50, DogName: "pochi", EatenAt: timestamppb.New(eatenAt.Add(time.Hour))},
+	}
+	tests := []struct {
+		name            string
+		accept          string
+		records         []*dogfoodpb.Record
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "csv by default",
+			records:         records,
+			wantStatus:      http.StatusOK,
+			wantContentType: recordio.ContentTypeCSV,
+			wantBody: "dog_name,dogfood_name,gram,eaten_at\n" +
+				"pochi,a,100,2021-12-01T08:00:00Z\n" +
+				"pochi,\"b,c\",50,2021-12-01T09:00:00Z\n",
+		},
+		{
+			name:            "csv without records",
+			accept:          "text/csv",
+			wantStatus:      http.StatusOK,
+			wantContentType: recordio.ContentTypeCSV,
+			wantBody:        "dog_name,dogfood_name,gram,eaten_at\n",
+		},
+		{
+			name:            "ndjson",
+			accept:          "application/x-ndjson, text/csv;q=0.5",
+			records:         records[:1],
+			wantStatus:      http.StatusOK,
+			wantContentType: recordio.ContentTypeNDJSON,
+			wantBody:        `{"dogfoodName":"a","gram":100,"dogName":"pochi","eatenAt":"2021-12-01T08:00:00Z"}` + "\n",
+		},
+		{
+			name:       "not acceptable",
+			accept:     "application/xml",
+			wantStatus: http.StatusNotAcceptable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &fakeExportClient{records: tt.records}
+			req := httptest.NewRequest(http.MethodGet, exportRecordsPath+"?dog_name=pochi&from=2021-12-01T00:00:00Z", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			exportRecordsHandler(c, zap.NewNop())(rec, req, nil)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %s, want %s", got, tt.wantContentType)
+			}
+			// protojson randomly adds spaces to discourage depending on its output byte by byte.
+			if got := strings.ReplaceAll(rec.Body.String(), " ", ""); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+			if c.req.GetDogName() != "pochi" || !c.req.GetFrom().AsTime().Equal(time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)) {
+				t.Errorf("request = %v", c.req)
+			}
+		})
+	}
+}
@@ -2,27 +2,189 @@ package protov1
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/gogo/status"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/kei6u/dogfood/pkg/health"
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
 	healthcheckpb "github.com/kei6u/dogfood/proto/v1/healthcheck"
-	_ "github.com/lib/pq"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+const (
+	defaultHealthCheckInterval = 10 * time.Second
+	healthCheckTimeout         = 3 * time.Second
+)
+
+func getDiskCheckPath() string {
+	if p := os.Getenv("HEALTH_DISK_PATH"); p != "" {
+		return p
+	}
+	return os.TempDir()
+}
+
+// healthServices are services whose status is reported by grpc.health.v1.Health.
+// An empty name represents the overall health of the server.
+var healthServices = []string{
+	"",
+	dogfoodpb.DogFoodService_ServiceDesc.ServiceName,
+	healthcheckpb.HealthCheckService_ServiceDesc.ServiceName,
+}
+
 func (s *Server) LivenessProbe(context.Context, *healthcheckpb.LivenessProbeRequest) (*healthcheckpb.LivenessProbeResponse, error) {
 	return &healthcheckpb.LivenessProbeResponse{}, nil
 }
 
-func (s *Server) ReadinessProbe(context.Context, *healthcheckpb.ReadinessProbeRequest) (*healthcheckpb.ReadinessProbeResponse, error) {
-	if err := s.db.Ping(); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to ping database: %s", err)
+func (s *Server) ReadinessProbe(ctx context.Context, _ *healthcheckpb.ReadinessProbeRequest) (*healthcheckpb.ReadinessProbeResponse, error) {
+	if atomic.LoadInt32(&s.notReady) == 1 {
+		return nil, status.Error(codes.Unavailable, "server is shutting down")
+	}
+	if err := s.health.Err(ctx); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "readiness probe failed: %s", err)
 	}
 	return &healthcheckpb.ReadinessProbeResponse{}, nil
 }
 
-func (s *Server) StartupProbe(context.Context, *healthcheckpb.StartupProbeRequest) (*healthcheckpb.StartupProbeResponse, error) {
-	if err := s.db.Ping(); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to ping database: %s", err)
+func (s *Server) StartupProbe(ctx context.Context, _ *healthcheckpb.StartupProbeRequest) (*healthcheckpb.StartupProbeResponse, error) {
+	if err := s.health.Err(ctx); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "startup probe failed: %s", err)
 	}
 	return &healthcheckpb.StartupProbeResponse{}, nil
 }
+
+func (s *Server) Report(ctx context.Context, _ *healthcheckpb.ReportRequest) (*healthcheckpb.ReportResponse, error) {
+	r := s.health.Report(ctx)
+	res := &healthcheckpb.ReportResponse{
+		Status: string(r.Status),
+		Checks: make([]*healthcheckpb.CheckResult, len(r.Checks)),
+	}
+	for i, c := range r.Checks {
+		cr := &healthcheckpb.CheckResult{
+			Name:      c.Name,
+			Status:    string(c.Status),
+			Critical:  c.Critical,
+			Latency:   durationpb.New(c.Latency),
+			LastError: c.LastError,
+		}
+		if !c.CheckedAt.IsZero() {
+			cr.CheckedAt = timestamppb.New(c.CheckedAt)
+		}
+		res.Checks[i] = cr
+	}
+	if r.Status != health.StatusOK {
+		// The report is returned with 503 by the gRPC gateway like the gateway's one, so that HTTP probes fail.
+		if err := grpc.SetHeader(ctx, metadata.Pairs(httpCodeHeader, strconv.Itoa(http.StatusServiceUnavailable))); err != nil {
+			s.logger.Warn("failed to set HTTP status code of health report", zap.Error(err))
+		}
+	}
+	return res, nil
+}
+
+// httpCodeHeader is metadata for RPCs to respond with a message but a status code other than 200 via the gRPC gateway.
+const httpCodeHeader = "x-http-code"
+
+// forwardHTTPCode writes a status code set in httpCodeHeader. It's runtime.WithForwardResponseOption.
+func forwardHTTPCode(ctx context.Context, w http.ResponseWriter, _ proto.Message) error {
+	md, ok := runtime.ServerMetadataFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	vs := md.HeaderMD.Get(httpCodeHeader)
+	if len(vs) == 0 {
+		return nil
+	}
+	code, err := strconv.Atoi(vs[0])
+	if err != nil {
+		return fmt.Errorf("%s is invalid: %w", httpCodeHeader, err)
+	}
+	w.Header().Del(runtime.MetadataHeaderPrefix + httpCodeHeader)
+	w.WriteHeader(code)
+	return nil
+}
+
+// migratedColumns are columns of every table which the backend queries, so that the backend is not ready
+// until init.sql is applied to existing databases. Columns added to existing tables must be listed.
+var migratedColumns = []string{
+	"record.id",
+	"record.deleted_at",
+	"audit_event.request_id",
+	"alert.pending",
+	"webhook_subscription.secret",
+	"webhook_delivery.delivered_at",
+	"outbox.created_at",
+}
+
+// registerHealthChecks registers dependency checks of the backend, followed by checks given by WithHealthCheck.
+func (s *Server) registerHealthChecks(checks []healthCheck) {
+	var opts []health.Option
+	if v := os.Getenv("HEALTH_CHECK_CACHE_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			opts = append(opts, health.WithCacheTTL(d))
+		} else {
+			s.logger.Warn("HEALTH_CHECK_CACHE_TTL is invalid, use default", zap.String("value", v))
+		}
+	}
+	s.health = health.NewChecker(opts...)
+	if s.db != nil {
+		s.health.Register("postgres", health.SQLPing(s.db))
+		s.health.Register("migrations", health.SQLColumnsExist(s.db, migratedColumns...))
+	} else {
+		s.health.Register("store", s.store.Ping)
+	}
+	s.health.Register("disk", health.DiskSpace(getDiskCheckPath(), health.DefaultDiskMinFreeBytes), health.WithNonCritical())
+	for _, c := range checks {
+		s.health.Register(c.name, c.fn, c.opts...)
+	}
+}
+
+// watchHealth updates serving status of grpc.health.v1.Health by dependency checks
+// every HEALTH_CHECK_INTERVAL until ctx is done.
+func (s *Server) watchHealth(ctx context.Context) {
+	interval := defaultHealthCheckInterval
+	if v := os.Getenv("HEALTH_CHECK_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			s.logger.Warn("HEALTH_CHECK_INTERVAL is invalid, use default", zap.String("value", v))
+		} else {
+			interval = d
+		}
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		s.updateServingStatus(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (s *Server) updateServingStatus(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	st := healthpb.HealthCheckResponse_SERVING
+	if err := s.health.Err(ctx); err != nil {
+		s.logger.Warn("health check failed", zap.Error(err))
+		st = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	for _, svc := range healthServices {
+		s.healthServer.SetServingStatus(svc, st)
+	}
+}
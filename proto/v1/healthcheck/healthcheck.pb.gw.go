@@ -85,6 +85,24 @@ func local_request_HealthCheckService_StartupProbe_0(ctx context.Context, marsha
 
 }
 
+func request_HealthCheckService_Report_0(ctx context.Context, marshaler runtime.Marshaler, client HealthCheckServiceClient, req *http.Request, pathParams map[string]string) (proto.Message, runtime.ServerMetadata, error) {
+	var protoReq ReportRequest
+	var metadata runtime.ServerMetadata
+
+	msg, err := client.Report(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	return msg, metadata, err
+
+}
+
+func local_request_HealthCheckService_Report_0(ctx context.Context, marshaler runtime.Marshaler, server HealthCheckServiceServer, req *http.Request, pathParams map[string]string) (proto.Message, runtime.ServerMetadata, error) {
+	var protoReq ReportRequest
+	var metadata runtime.ServerMetadata
+
+	msg, err := server.Report(ctx, &protoReq)
+	return msg, metadata, err
+
+}
+
 // RegisterHealthCheckServiceHandlerServer registers the http handlers for service HealthCheckService to "mux".
 // UnaryRPC     :call HealthCheckServiceServer directly.
 // StreamingRPC :currently unsupported pending https://github.com/grpc/grpc-go/issues/906.
@@ -160,6 +178,29 @@ func RegisterHealthCheckServiceHandlerServer(ctx context.Context, mux *runtime.S
 
 	})
 
+	mux.Handle("GET", pattern_HealthCheckService_Report_0, func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		var stream runtime.ServerTransportStream
+		ctx = grpc.NewContextWithServerTransportStream(ctx, &stream)
+		inboundMarshaler, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
+		rctx, err := runtime.AnnotateIncomingContext(ctx, mux, req, "/healthcheckpb.v1.HealthCheckService/Report", runtime.WithHTTPPathPattern("/v1/healthcheck/report"))
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+		resp, md, err := local_request_HealthCheckService_Report_0(rctx, inboundMarshaler, server, req, pathParams)
+		md.HeaderMD, md.TrailerMD = metadata.Join(md.HeaderMD, stream.Header()), metadata.Join(md.TrailerMD, stream.Trailer())
+		ctx = runtime.NewServerMetadataContext(ctx, md)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+
+		forward_HealthCheckService_Report_0(ctx, mux, outboundMarshaler, w, req, resp, mux.GetForwardResponseOptions()...)
+
+	})
+
 	return nil
 }
 
@@ -261,6 +302,26 @@ func RegisterHealthCheckServiceHandlerClient(ctx context.Context, mux *runtime.S
 
 	})
 
+	mux.Handle("GET", pattern_HealthCheckService_Report_0, func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		inboundMarshaler, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
+		rctx, err := runtime.AnnotateContext(ctx, mux, req, "/healthcheckpb.v1.HealthCheckService/Report", runtime.WithHTTPPathPattern("/v1/healthcheck/report"))
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+		resp, md, err := request_HealthCheckService_Report_0(rctx, inboundMarshaler, client, req, pathParams)
+		ctx = runtime.NewServerMetadataContext(ctx, md)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+
+		forward_HealthCheckService_Report_0(ctx, mux, outboundMarshaler, w, req, resp, mux.GetForwardResponseOptions()...)
+
+	})
+
 	return nil
 }
 
@@ -270,6 +331,8 @@ var (
 	pattern_HealthCheckService_ReadinessProbe_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1, 2, 2}, []string{"v1", "healthcheck", "readinessProbe"}, ""))
 
 	pattern_HealthCheckService_StartupProbe_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1, 2, 2}, []string{"v1", "healthcheck", "startupProbe"}, ""))
+
+	pattern_HealthCheckService_Report_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1, 2, 2}, []string{"v1", "healthcheck", "report"}, ""))
 )
 
 var (
@@ -278,4 +341,6 @@ var (
 	forward_HealthCheckService_ReadinessProbe_0 = runtime.ForwardResponseMessage
 
 	forward_HealthCheckService_StartupProbe_0 = runtime.ForwardResponseMessage
+
+	forward_HealthCheckService_Report_0 = runtime.ForwardResponseMessage
 )
@@ -21,6 +21,9 @@ type HealthCheckServiceClient interface {
 	LivenessProbe(ctx context.Context, in *LivenessProbeRequest, opts ...grpc.CallOption) (*LivenessProbeResponse, error)
 	ReadinessProbe(ctx context.Context, in *ReadinessProbeRequest, opts ...grpc.CallOption) (*ReadinessProbeResponse, error)
 	StartupProbe(ctx context.Context, in *StartupProbeRequest, opts ...grpc.CallOption) (*StartupProbeResponse, error)
+	// Report reports results of all dependency checks.
+	// It responds with 503 over HTTP if any critical check is failing, like the gateway.
+	Report(ctx context.Context, in *ReportRequest, opts ...grpc.CallOption) (*ReportResponse, error)
 }
 
 type healthCheckServiceClient struct {
@@ -58,6 +61,15 @@ func (c *healthCheckServiceClient) StartupProbe(ctx context.Context, in *Startup
 	return out, nil
 }
 
+func (c *healthCheckServiceClient) Report(ctx context.Context, in *ReportRequest, opts ...grpc.CallOption) (*ReportResponse, error) {
+	out := new(ReportResponse)
+	err := c.cc.Invoke(ctx, "/healthcheckpb.v1.HealthCheckService/Report", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // HealthCheckServiceServer is the server API for HealthCheckService service.
 // All implementations should embed UnimplementedHealthCheckServiceServer
 // for forward compatibility
@@ -65,6 +77,9 @@ type HealthCheckServiceServer interface {
 	LivenessProbe(context.Context, *LivenessProbeRequest) (*LivenessProbeResponse, error)
 	ReadinessProbe(context.Context, *ReadinessProbeRequest) (*ReadinessProbeResponse, error)
 	StartupProbe(context.Context, *StartupProbeRequest) (*StartupProbeResponse, error)
+	// Report reports results of all dependency checks.
+	// It responds with 503 over HTTP if any critical check is failing, like the gateway.
+	Report(context.Context, *ReportRequest) (*ReportResponse, error)
 }
 
 // UnimplementedHealthCheckServiceServer should be embedded to have forward compatible implementations.
@@ -80,6 +95,9 @@ func (UnimplementedHealthCheckServiceServer) ReadinessProbe(context.Context, *Re
 func (UnimplementedHealthCheckServiceServer) StartupProbe(context.Context, *StartupProbeRequest) (*StartupProbeResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method StartupProbe not implemented")
 }
+func (UnimplementedHealthCheckServiceServer) Report(context.Context, *ReportRequest) (*ReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Report not implemented")
+}
 
 // UnsafeHealthCheckServiceServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to HealthCheckServiceServer will
@@ -146,6 +164,24 @@ func _HealthCheckService_StartupProbe_Handler(srv interface{}, ctx context.Conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _HealthCheckService_Report_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HealthCheckServiceServer).Report(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/healthcheckpb.v1.HealthCheckService/Report",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HealthCheckServiceServer).Report(ctx, req.(*ReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // HealthCheckService_ServiceDesc is the grpc.ServiceDesc for HealthCheckService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -165,6 +201,10 @@ var HealthCheckService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "StartupProbe",
 			Handler:    _HealthCheckService_StartupProbe_Handler,
 		},
+		{
+			MethodName: "Report",
+			Handler:    _HealthCheckService_Report_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/v1/healthcheck/healthcheck.proto",
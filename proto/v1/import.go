@@ -0,0 +1,191 @@
+package protov1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gogo/status"
+	"github.com/kei6u/dogfood/pkg/store"
+	"github.com/kei6u/dogfood/pkg/telemetry"
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// maxImportErrors bounds the size of ImportRecordsResponse.
+	maxImportErrors = 1000
+	// maxNameLength is the length of dog_name and dogfood_name columns.
+	maxNameLength = 50
+)
+
+func (s *Server) ImportRecords(stream dogfoodpb.DogFoodService_ImportRecordsServer) error {
+	span, ctx := telemetry.StartSpanFromContext(stream.Context(), "ImportRecords", "Records")
+	defer span.Finish()
+
+	var im *importer
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if im != nil {
+				im.rollback()
+			}
+			return err
+		}
+		if im == nil {
+			if im, err = newImporter(ctx, s.store, req.GetOptions()); err != nil {
+				return status.Errorf(codes.Internal, "failed to import records: %s", err)
+			}
+		}
+		for _, row := range req.GetRows() {
+			if err := im.importRow(ctx, row); err != nil {
+				im.rollback()
+				return status.Errorf(codes.Internal, "failed to import records: %s", err)
+			}
+		}
+	}
+	if im == nil {
+		return stream.SendAndClose(&dogfoodpb.ImportRecordsResponse{})
+	}
+	res, err := im.finish()
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to import records: %s", err)
+	}
+	return stream.SendAndClose(res)
+}
+
+type recordKey struct {
+	dogfoodName string
+	dogName     string
+	eatenAt     time.Time
+}
+
+// importer imports rows one by one, so that a stream is never held in memory except for keys to detect duplicates.
+type importer struct {
+	store store.Store
+	opts  *dogfoodpb.ImportOptions
+	// im is nil if rows are validated without being imported.
+	im   store.Import
+	seen map[recordKey]int64 // value: line
+	res  *dogfoodpb.ImportRecordsResponse
+}
+
+func newImporter(ctx context.Context, st store.Store, opts *dogfoodpb.ImportOptions) (*importer, error) {
+	im := &importer{
+		store: st,
+		opts:  opts,
+		seen:  make(map[recordKey]int64),
+		res:   &dogfoodpb.ImportRecordsResponse{},
+	}
+	if !opts.GetDryRun() {
+		i, err := st.BeginImport(ctx, opts.GetSingleTransaction())
+		if err != nil {
+			return nil, err
+		}
+		im.im = i
+	}
+	return im, nil
+}
+
+func (im *importer) importRow(ctx context.Context, row *dogfoodpb.ImportRow) error {
+	if row.GetError() != "" {
+		im.fail(row.GetLine(), row.GetError())
+		return nil
+	}
+	r := row.GetRecord()
+	if err := validateImportRecord(r); err != nil {
+		im.fail(row.GetLine(), err.Error())
+		return nil
+	}
+	key := recordKey{r.GetDogfoodName(), r.GetDogName(), r.GetEatenAt().AsTime()}
+	if line, ok := im.seen[key]; ok {
+		im.fail(row.GetLine(), fmt.Sprintf("duplicate of line %d", line))
+		return nil
+	}
+	im.seen[key] = row.GetLine()
+
+	if im.opts.GetDryRun() {
+		exists, err := im.store.RecordExists(ctx, r)
+		if err != nil {
+			return fmt.Errorf("failed to detect a duplicate: %w", err)
+		}
+		if exists {
+			im.fail(row.GetLine(), "record already exists")
+			return nil
+		}
+		im.res.Imported++
+		return nil
+	}
+
+	inserted, err := im.im.Insert(ctx, r)
+	if err != nil {
+		return err
+	}
+	if !inserted {
+		im.fail(row.GetLine(), "record already exists")
+		return nil
+	}
+	im.res.Imported++
+	return nil
+}
+
+func (im *importer) fail(line int64, msg string) {
+	im.res.Failed++
+	if len(im.res.Errors) < maxImportErrors {
+		im.res.Errors = append(im.res.Errors, &dogfoodpb.ImportError{Line: line, Message: msg})
+	}
+}
+
+func (im *importer) rollback() {
+	if im.im != nil {
+		im.im.Rollback()
+	}
+}
+
+// finish commits a single transaction if all rows succeeded, and rolls it back otherwise.
+func (im *importer) finish() (*dogfoodpb.ImportRecordsResponse, error) {
+	sort.SliceStable(im.res.Errors, func(i, j int) bool { return im.res.Errors[i].Line < im.res.Errors[j].Line })
+	switch {
+	case im.opts.GetDryRun():
+	case !im.opts.GetSingleTransaction():
+		im.res.Committed = true
+	case im.res.Failed > 0:
+		if err := im.im.Rollback(); err != nil {
+			return nil, fmt.Errorf("failed to rollback a transaction: %w", err)
+		}
+		im.res.Imported = 0
+	default:
+		if err := im.im.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit a transaction: %w", err)
+		}
+		im.res.Committed = true
+	}
+	return im.res, nil
+}
+
+func validateImportRecord(r *dogfoodpb.Record) error {
+	switch {
+	case r == nil:
+		return errors.New("record is missing")
+	case r.GetDogName() == "":
+		return errors.New("dog_name is missing")
+	case utf8.RuneCountInString(r.GetDogName()) > maxNameLength:
+		return fmt.Errorf("dog_name must be at most %d characters", maxNameLength)
+	case r.GetDogfoodName() == "":
+		return errors.New("dogfood_name is missing")
+	case utf8.RuneCountInString(r.GetDogfoodName()) > maxNameLength:
+		return fmt.Errorf("dogfood_name must be at most %d characters", maxNameLength)
+	case r.GetGram() <= 0:
+		return errors.New("gram must be positive")
+	case r.GetEatenAt() == nil:
+		return errors.New("eaten_at is missing")
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package protov1
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kei6u/dogfood/pkg/store"
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestValidateImportRecord(t *testing.T) {
+	eatenAt := timestamppb.New(time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC))
+	tests := []struct {
+		name    string
+		r       *dogfoodpb.Record
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			r:    &dogfoodpb.Record{DogName: "pochi", DogfoodName: "a", Gram: 100, EatenAt: eatenAt},
+		},
+		{
+			name: "multibyte name at the limit",
+			r:    &dogfoodpb.Record{DogName: strings.Repeat("ポ", maxNameLength), DogfoodName: "a", Gram: 100, EatenAt: eatenAt},
+		},
+		{
+			name:    "too long name",
+			r:       &dogfoodpb.Record{DogName: strings.Repeat("a", maxNameLength+1), DogfoodName: "a", Gram: 100, EatenAt: eatenAt},
+			wantErr: true,
+		},
+		{
+			name:    "missing dogfood name",
+			r:       &dogfoodpb.Record{DogName: "pochi", Gram: 100, EatenAt: eatenAt},
+			wantErr: true,
+		},
+		{
+			name:    "zero gram",
+			r:       &dogfoodpb.Record{DogName: "pochi", DogfoodName: "a", EatenAt: eatenAt},
+			wantErr: true,
+		},
+		{
+			name:    "missing eaten_at",
+			r:       &dogfoodpb.Record{DogName: "pochi", DogfoodName: "a", Gram: 100},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateImportRecord(tt.r); (err != nil) != tt.wantErr {
+				t.Errorf("validateImportRecord() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// fakeImportStream receives reqs in order, and then recvErr or io.EOF.
+type fakeImportStream struct {
+	grpc.ServerStream
+	reqs    []*dogfoodpb.ImportRecordsRequest
+	recvErr error
+	res     *dogfoodpb.ImportRecordsResponse
+}
+
+func (s *fakeImportStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *fakeImportStream) Recv() (*dogfoodpb.ImportRecordsRequest, error) {
+	if len(s.reqs) == 0 {
+		if s.recvErr != nil {
+			return nil, s.recvErr
+		}
+		return nil, io.EOF
+	}
+	req := s.reqs[0]
+	s.reqs = s.reqs[1:]
+	return req, nil
+}
+
+func (s *fakeImportStream) SendAndClose(res *dogfoodpb.ImportRecordsResponse) error {
+	s.res = res
+	return nil
+}
+
+func TestServer_ImportRecords(t *testing.T) {
+	eatenAt := time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)
+	record := func(dogfood string, hours int) *dogfoodpb.Record {
+		return &dogfoodpb.Record{DogfoodName: dogfood, Gram: 100, DogName: "pochi", EatenAt: timestamppb.New(eatenAt.Add(time.Duration(hours) * time.Hour))}
+	}
+	existing := record("existing", 0)
+	// Rows are split into messages as the importer streams them.
+	valid := [][]*dogfoodpb.ImportRow{
+		{{Line: 2, Record: record("a", 1)}, {Line: 3, Record: record("b", 2)}},
+		{{Line: 4, Record: record("c", 3)}},
+	}
+	invalid := [][]*dogfoodpb.ImportRow{
+		{{Line: 2, Record: record("a", 1)}, {Line: 3, Error: "gram is not a number"}},
+		{{Line: 4, Record: record("a", 1)}, {Line: 5, Record: existing}, {Line: 6, Record: record("c", 3)}},
+	}
+	tests := []struct {
+		name        string
+		opts        *dogfoodpb.ImportOptions
+		messages    [][]*dogfoodpb.ImportRow
+		recvErr     error
+		wantErr     bool
+		want        *dogfoodpb.ImportRecordsResponse
+		wantRecords int64
+	}{
+		{
+			name:     "imports valid rows and reports the others",
+			messages: invalid,
+			want: &dogfoodpb.ImportRecordsResponse{
+				Imported:  2,
+				Failed:    3,
+				Committed: true,
+				Errors: []*dogfoodpb.ImportError{
+					{Line: 3, Message: "gram is not a number"},
+					{Line: 4, Message: "duplicate of line 2"},
+					{Line: 5, Message: "record already exists"},
+				},
+			},
+			wantRecords: 3,
+		},
+		{
+			name:        "single transaction commits if all rows succeed",
+			opts:        &dogfoodpb.ImportOptions{SingleTransaction: true},
+			messages:    valid,
+			want:        &dogfoodpb.ImportRecordsResponse{Imported: 3, Committed: true},
+			wantRecords: 4,
+		},
+		{
+			name:     "single transaction rolls back if any row fails",
+			opts:     &dogfoodpb.ImportOptions{SingleTransaction: true},
+			messages: invalid,
+			want: &dogfoodpb.ImportRecordsResponse{
+				Failed: 3,
+				Errors: []*dogfoodpb.ImportError{
+					{Line: 3, Message: "gram is not a number"},
+					{Line: 4, Message: "duplicate of line 2"},
+					{Line: 5, Message: "record already exists"},
+				},
+			},
+			wantRecords: 1,
+		},
+		{
+			name:     "dry run validates rows without importing them",
+			opts:     &dogfoodpb.ImportOptions{DryRun: true},
+			messages: invalid,
+			want: &dogfoodpb.ImportRecordsResponse{
+				Imported: 2,
+				Failed:   3,
+				Errors: []*dogfoodpb.ImportError{
+					{Line: 3, Message: "gram is not a number"},
+					{Line: 4, Message: "duplicate of line 2"},
+					{Line: 5, Message: "record already exists"},
+				},
+			},
+			wantRecords: 1,
+		},
+		{
+			name:        "broken stream rolls back a single transaction",
+			opts:        &dogfoodpb.ImportOptions{SingleTransaction: true},
+			messages:    valid,
+			recvErr:     errors.New("connection reset"),
+			wantErr:     true,
+			wantRecords: 1,
+		},
+		{
+			name:        "empty stream",
+			want:        &dogfoodpb.ImportRecordsResponse{},
+			wantRecords: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			st := store.NewMemoryStore()
+			if err := st.CreateRecord(ctx, existing, nil); err != nil {
+				t.Fatal(err)
+			}
+			stream := &fakeImportStream{recvErr: tt.recvErr}
+			for i, rows := range tt.messages {
+				req := &dogfoodpb.ImportRecordsRequest{Rows: rows}
+				// Options are sent in the first message.
+				if i == 0 {
+					req.Options = tt.opts
+				}
+				stream.reqs = append(stream.reqs, req)
+			}
+
+			s := &Server{store: st, logger: zap.NewNop()}
+			err := s.ImportRecords(stream)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ImportRecords() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !proto.Equal(stream.res, tt.want) {
+				t.Errorf("ImportRecords() = %v, want %v", stream.res, tt.want)
+			}
+			if got, err := st.CountRecords(ctx); err != nil || got != tt.wantRecords {
+				t.Errorf("CountRecords() = %d, %v, want %d", got, err, tt.wantRecords)
+			}
+		})
+	}
+}
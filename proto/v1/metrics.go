@@ -2,17 +2,41 @@ package protov1
 
 import (
 	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 
 	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
 	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
 	"google.golang.org/grpc"
 )
 
+const (
+	// otherLabelValue buckets label values which are not in an allow-list or over the cardinality limit.
+	otherLabelValue              = "other"
+	defaultLabelCardinalityLimit = 100
+	labelCardinalityLimitEnv     = "METRICS_LABEL_CARDINALITY_LIMIT"
+	dogLabelAllowListEnv         = "METRICS_DOG_ALLOWLIST"
+	dogfoodLabelAllowListEnv     = "METRICS_DOGFOOD_ALLOWLIST"
+)
+
 var (
-	dogfoodGramGuage = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "eaten_dogfood_gram",
-			Help: "how much grams dog ate dogfood",
+	dogfoodGramHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "eaten_dogfood_gram_per_meal",
+			Help:    "how much grams dog ate dogfood per meal",
+			Buckets: []float64{10, 25, 50, 75, 100, 150, 200, 300, 500, 1000},
+		},
+		[]string{"dog", "dogfood"},
+	)
+	dogfoodGramTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eaten_dogfood_gram_total",
+			Help: "total grams dog ate dogfood",
 		},
 		[]string{"dog", "dogfood"},
 	)
@@ -23,20 +47,104 @@ var (
 		},
 		[]string{"dogfood"},
 	)
+
+	// OpenTelemetry instruments are exported when TELEMETRY_BACKEND is otlp or stdout.
+	otelMeter            = metric.Must(global.Meter("github.com/kei6u/dogfood"))
+	otelDogfoodGram      = otelMeter.NewInt64Histogram("eaten_dogfood_gram_per_meal")
+	otelDogfoodNameCount = otelMeter.NewInt64Counter("eaten_dogfood_count")
 )
 
-func metricsUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+// metricsUnaryServerInterceptor records feeding metrics after a record is created successfully.
+// Label values are bounded by dogs and dogfoods, since they come from user input.
+func metricsUnaryServerInterceptor(dogs, dogfoods *labelLimiter) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
-		switch r := req.(type) {
-		case *dogfoodpb.CreateRecordRequest:
-			dogfoodGramGuage.With(prometheus.Labels{
-				"dog":     r.GetDogName(),
-				"dogfood": r.GetDogfoodName(),
-			}).Set(float64(r.GetGram()))
-			dogfoodNameCount.With(prometheus.Labels{
-				"dogfood": r.GetDogfoodName(),
-			}).Inc()
+		resp, err = handler(ctx, req)
+		if err != nil {
+			return resp, err
+		}
+		if _, ok := req.(*dogfoodpb.CreateRecordRequest); !ok {
+			return resp, err
+		}
+		r, ok := resp.(*dogfoodpb.Record)
+		if !ok {
+			return resp, err
+		}
+
+		dog := dogs.value(r.GetDogName())
+		dogfood := dogfoods.value(r.GetDogfoodName())
+		labels := prometheus.Labels{"dog": dog, "dogfood": dogfood}
+		dogfoodGramHistogram.With(labels).Observe(float64(r.GetGram()))
+		dogfoodGramTotal.With(labels).Add(float64(r.GetGram()))
+		dogfoodNameCount.With(prometheus.Labels{"dogfood": dogfood}).Inc()
+		otelDogfoodGram.Record(
+			ctx,
+			int64(r.GetGram()),
+			attribute.String("dog", dog),
+			attribute.String("dogfood", dogfood),
+		)
+		otelDogfoodNameCount.Add(ctx, 1, attribute.String("dogfood", dogfood))
+		return resp, err
+	}
+}
+
+// labelLimiter bounds cardinality of a label.
+// Values in an allow-list are kept if the allow-list is set. Otherwise,
+// values are kept until the limit of distinct values is reached.
+// The other values are bucketed into "other".
+type labelLimiter struct {
+	allowList map[string]struct{}
+	limit     int
+
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+func newLabelLimiter(allowList []string, limit int) *labelLimiter {
+	l := &labelLimiter{
+		limit: limit,
+		seen:  make(map[string]struct{}),
+	}
+	if len(allowList) > 0 {
+		l.allowList = make(map[string]struct{}, len(allowList))
+		for _, v := range allowList {
+			l.allowList[v] = struct{}{}
+		}
+	}
+	return l
+}
+
+// newLabelLimiterFromEnv loads a comma-separated allow-list from allowListEnv,
+// and the cardinality limit from METRICS_LABEL_CARDINALITY_LIMIT.
+func newLabelLimiterFromEnv(allowListEnv string) *labelLimiter {
+	var allowList []string
+	for _, v := range strings.Split(os.Getenv(allowListEnv), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			allowList = append(allowList, v)
 		}
-		return handler(ctx, req)
 	}
+	limit := defaultLabelCardinalityLimit
+	if v, err := strconv.Atoi(os.Getenv(labelCardinalityLimitEnv)); err == nil && v > 0 {
+		limit = v
+	}
+	return newLabelLimiter(allowList, limit)
+}
+
+func (l *labelLimiter) value(v string) string {
+	if l.allowList != nil {
+		if _, ok := l.allowList[v]; ok {
+			return v
+		}
+		return otherLabelValue
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.seen[v]; ok {
+		return v
+	}
+	if len(l.seen) >= l.limit {
+		return otherLabelValue
+	}
+	l.seen[v] = struct{}{}
+	return v
 }
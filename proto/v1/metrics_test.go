@@ -0,0 +1,86 @@
+package protov1
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+)
+
+func TestLabelLimiter(t *testing.T) {
+	tests := []struct {
+		name      string
+		allowList []string
+		limit     int
+		values    []string
+		want      []string
+	}{
+		{
+			name:   "keep values below limit",
+			limit:  2,
+			values: []string{"pochi", "tama", "pochi"},
+			want:   []string{"pochi", "tama", "pochi"},
+		},
+		{
+			name:   "bucket values over limit into other",
+			limit:  2,
+			values: []string{"pochi", "tama", "hachi", "tama"},
+			want:   []string{"pochi", "tama", "other", "tama"},
+		},
+		{
+			name:      "allow-list takes precedence over limit",
+			allowList: []string{"hachi"},
+			limit:     2,
+			values:    []string{"pochi", "hachi"},
+			want:      []string{"other", "hachi"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLabelLimiter(tt.allowList, tt.limit)
+			for i, v := range tt.values {
+				if got := l.value(v); got != tt.want[i] {
+					t.Errorf("value(%q) = %q, want %q", v, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsUnaryServerInterceptor(t *testing.T) {
+	dogfoodGramTotal.Reset()
+	interceptor := metricsUnaryServerInterceptor(newLabelLimiter([]string{"pochi"}, 1), newLabelLimiter([]string{"royal"}, 1))
+	info := &grpc.UnaryServerInfo{FullMethod: "/dogfoodpb.v1.DogFoodService/CreateRecord"}
+	create := func(dog string, gram int32, err error) {
+		req := &dogfoodpb.CreateRecordRequest{DogName: dog, DogfoodName: "royal", Gram: gram}
+		interceptor(context.Background(), req, info, func(context.Context, interface{}) (interface{}, error) {
+			if err != nil {
+				return nil, err
+			}
+			return &dogfoodpb.Record{DogName: req.DogName, DogfoodName: req.DogfoodName, Gram: req.Gram}, nil
+		})
+	}
+
+	create("pochi", 100, nil)
+	create("pochi", 50, nil)
+	create("pochi", 70, errors.New("failed to insert"))
+	create("tama", 30, nil)
+
+	tests := []struct {
+		dog  string
+		want float64
+	}{
+		{"pochi", 150},
+		{"other", 30},
+	}
+	for _, tt := range tests {
+		got := testutil.ToFloat64(dogfoodGramTotal.With(prometheus.Labels{"dog": tt.dog, "dogfood": "royal"}))
+		if got != tt.want {
+			t.Errorf("eaten_dogfood_gram_total{dog=%q} = %v, want %v", tt.dog, got, tt.want)
+		}
+	}
+}
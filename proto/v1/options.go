@@ -0,0 +1,209 @@
+package protov1
+
+import (
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/kei6u/dogfood/pkg/admin"
+	"github.com/kei6u/dogfood/pkg/health"
+	"github.com/kei6u/dogfood/pkg/outbox"
+	"github.com/kei6u/dogfood/pkg/store"
+	"github.com/kei6u/dogfood/pkg/tlsconfig"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+const (
+	defaultGRPCAddr        = ":5000"
+	defaultGRPCGatewayAddr = ":8080"
+)
+
+// MetricsRegistry registers metrics of the server, and gathers them to serve.
+// *prometheus.Registry implements it.
+type MetricsRegistry interface {
+	prometheus.Registerer
+	prometheus.Gatherer
+}
+
+type options struct {
+	gRPCAddr           string
+	gRPCGWAddr         string
+	adminAddr          string
+	metricsAddr        string
+	adminOpts          []admin.Option
+	logger             *zap.Logger
+	store              store.Store
+	registry           MetricsRegistry
+	unaryInterceptors  []grpc.UnaryServerInterceptor
+	streamInterceptors []grpc.StreamServerInterceptor
+	listeners          Listeners
+	gwMuxOpts          []runtime.ServeMuxOption
+	gwDialOpts         []grpc.DialOption
+	tlsConfig          *tlsconfig.Config
+	broker             outbox.Broker
+	apiKeys            map[string]string
+	healthChecks       []healthCheck
+}
+
+// healthCheck is a dependency check registered by WithHealthCheck.
+type healthCheck struct {
+	name string
+	fn   health.CheckFunc
+	opts []health.CheckOption
+}
+
+type Option func(*options)
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		gRPCAddr:   defaultGRPCAddr,
+		gRPCGWAddr: defaultGRPCGatewayAddr,
+		adminAddr:  admin.DefaultAddr,
+		logger:     zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithGRPCAddr sets an address of the gRPC server, either a port, e.g. 5000, or host:port.
+// It defaults to :5000, which is kept if addr is empty.
+func WithGRPCAddr(addr string) Option {
+	return func(o *options) {
+		if addr != "" {
+			o.gRPCAddr = listenAddr(addr)
+		}
+	}
+}
+
+// WithGRPCGatewayAddr sets an address of the gRPC gateway server, either a port or host:port.
+// It defaults to :8080, which is kept if addr is empty.
+func WithGRPCGatewayAddr(addr string) Option {
+	return func(o *options) {
+		if addr != "" {
+			o.gRPCGWAddr = listenAddr(addr)
+		}
+	}
+}
+
+// WithAdminAddr sets an address of the admin server, which serves metrics and profiles.
+// It's either a port, which listens on localhost, or host:port. It defaults to localhost:9092,
+// which is kept if addr is empty.
+func WithAdminAddr(addr string) Option {
+	return func(o *options) {
+		if addr != "" {
+			o.adminAddr = admin.Addr(addr)
+		}
+	}
+}
+
+// WithMetricsAddr serves metrics alone in plaintext on addr, e.g. :9091, for agents outside of the host to
+// scrape them. A port without host listens on all interfaces. Metrics are served only by the admin server by default.
+func WithMetricsAddr(addr string) Option {
+	return func(o *options) {
+		if addr != "" {
+			addr = listenAddr(addr)
+		}
+		o.metricsAddr = addr
+	}
+}
+
+// WithAdminOptions appends options of the admin server, e.g. admin.WithLogLevel.
+// Metrics of the server are always served.
+func WithAdminOptions(opts ...admin.Option) Option {
+	return func(o *options) {
+		o.adminOpts = append(o.adminOpts, opts...)
+	}
+}
+
+// WithLogger sets a logger. Nothing is logged by default.
+func WithLogger(l *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithStore sets a store of records, which is required.
+// Webhooks, the outbox and alerting are available only if it's store.SQLStore.
+func WithStore(st store.Store) Option {
+	return func(o *options) {
+		o.store = st
+	}
+}
+
+// WithMetricsRegistry registers metrics to r, and serves metrics gathered by r.
+// It defaults to a new registry, so that metrics of the server never conflict with the others.
+func WithMetricsRegistry(r MetricsRegistry) Option {
+	return func(o *options) {
+		o.registry = r
+	}
+}
+
+// WithUnaryInterceptors appends interceptors of unary RPCs, which run after built-in ones.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return func(o *options) {
+		o.unaryInterceptors = append(o.unaryInterceptors, interceptors...)
+	}
+}
+
+// WithStreamInterceptors appends interceptors of streaming RPCs, which run after built-in ones.
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) Option {
+	return func(o *options) {
+		o.streamInterceptors = append(o.streamInterceptors, interceptors...)
+	}
+}
+
+// WithListeners serves on listeners bound in advance, e.g. on ephemeral ports in tests.
+// Servers whose listener is nil listen to their addresses.
+// The gRPC gateway dials the address of the gRPC listener, so WithGatewayDialOptions is needed
+// for listeners which are not dialed by address, e.g. bufconn.
+func WithListeners(ls Listeners) Option {
+	return func(o *options) {
+		o.listeners = ls
+	}
+}
+
+// WithGatewayMuxOptions appends options of the gRPC gateway mux, which apply after built-in ones.
+func WithGatewayMuxOptions(opts ...runtime.ServeMuxOption) Option {
+	return func(o *options) {
+		o.gwMuxOpts = append(o.gwMuxOpts, opts...)
+	}
+}
+
+// WithGatewayDialOptions appends options for the gRPC gateway to dial the gRPC server,
+// which apply after built-in ones, e.g. credentials.
+func WithGatewayDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.gwDialOpts = append(o.gwDialOpts, opts...)
+	}
+}
+
+// WithTLS serves all listeners over TLS configured by tc. They are served in plaintext by default.
+func WithTLS(tc *tlsconfig.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = tc
+	}
+}
+
+// WithBroker publishes events to b through the outbox. Events are not published by default.
+func WithBroker(b outbox.Broker) Option {
+	return func(o *options) {
+		o.broker = b
+	}
+}
+
+// WithHealthCheck checks a dependency given by callers, e.g. a message broker, in addition to the store and disk.
+func WithHealthCheck(name string, fn health.CheckFunc, opts ...health.CheckOption) Option {
+	return func(o *options) {
+		o.healthChecks = append(o.healthChecks, healthCheck{name, fn, opts})
+	}
+}
+
+// WithAPIKeys authenticates callers by API keys by their names, e.g. APIKeysFromEnv.
+// Names of callers are recorded as actors in audit events. RPCs which correct records, list audit events
+// or manage webhooks are rejected without API keys, and no API keys are accepted by default.
+func WithAPIKeys(keys map[string]string) Option {
+	return func(o *options) {
+		o.apiKeys = keys
+	}
+}
@@ -2,204 +2,468 @@ package protov1
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/gogo/status"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_zap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
 	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/kei6u/dogfood/pkg/admin"
+	"github.com/kei6u/dogfood/pkg/alerting"
 	"github.com/kei6u/dogfood/pkg/ddconfig"
+	"github.com/kei6u/dogfood/pkg/health"
+	"github.com/kei6u/dogfood/pkg/lifecycle"
+	"github.com/kei6u/dogfood/pkg/outbox"
+	"github.com/kei6u/dogfood/pkg/requestid"
+	"github.com/kei6u/dogfood/pkg/store"
+	"github.com/kei6u/dogfood/pkg/telemetry"
+	"github.com/kei6u/dogfood/pkg/tlsconfig"
+	"github.com/kei6u/dogfood/pkg/webhook"
 	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
 	healthcheckpb "github.com/kei6u/dogfood/proto/v1/healthcheck"
-	_ "github.com/lib/pq"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	grpc_health "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
-	grpc_dd "gopkg.in/DataDog/dd-trace-go.v1/contrib/google.golang.org/grpc"
-	http_dd "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
-	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 )
 
+// failureStopTimeout is a deadline to stop the other servers when one of them dies.
+const failureStopTimeout = 5 * time.Second
+
 var _ dogfoodpb.DogFoodServiceServer = (*Server)(nil)
 var _ healthcheckpb.HealthCheckServiceServer = (*Server)(nil)
 
 type Server struct {
-	gRPCAddr       string
-	gRPCGWAddr     string
-	logger         *zap.Logger
+	gRPCAddr           string
+	gRPCGWAddr         string
+	adminAddr          string
+	metricsAddr        string
+	adminOpts          []admin.Option
+	logger             *zap.Logger
+	store              store.Store
+	registry           MetricsRegistry
+	unaryInterceptors  []grpc.UnaryServerInterceptor
+	streamInterceptors []grpc.StreamServerInterceptor
+	listeners          Listeners
+	gwMuxOpts          []runtime.ServeMuxOption
+	gwDialOpts         []grpc.DialOption
+	// db is nil if store is not backed by Postgres, where webhooks, the outbox and alerting are disabled.
 	db             *sql.DB
 	promMetrics    *grpc_prometheus.ServerMetrics
-	promHttpServer *http.Server
+	adminServer    *http.Server
+	metricsServer  *http.Server
 	grpcServer     *grpc.Server
+	healthServer   *grpc_health.Server
+	notReady       int32
+	dogLabels      *labelLimiter
+	dogfoodLabels  *labelLimiter
+	dbMetrics      *dbMetricsCollector
+	alerts         alerting.Store
+	alertEvaluator *alerting.Evaluator
+	webhooks       webhook.Store
+	webhookWorker  *webhook.Worker
+	outbox         outbox.Store
+	outboxRelay    *outbox.Relay
+	stopOnce       sync.Once
+	stopErr        error
+	health         *health.Checker
 	grpcgwServer   *http.Server
 	conngRPCServer *grpc.ClientConn
-	gRPCListener   net.Listener
+	tlsConfig      *tlsconfig.Config
+	serverTLS      *tls.Config
+	principals     principals
 }
 
-func NewServer(ctx context.Context, gRPCAddr, gRPCGWAddr string, logger *zap.Logger, db *sql.DB) (*Server, error) {
-	if !strings.HasPrefix(gRPCAddr, ":") {
-		gRPCAddr = fmt.Sprintf(":%s", gRPCAddr)
+// NewServer returns a dogfood backend server configured by opts. WithStore is required.
+func NewServer(opts ...Option) (*Server, error) {
+	o := newOptions(opts...)
+	if o.store == nil {
+		return nil, errors.New("store is missing")
 	}
-	if !strings.HasPrefix(gRPCGWAddr, ":") {
-		gRPCGWAddr = fmt.Sprintf(":%s", gRPCGWAddr)
+	if o.registry == nil {
+		o.registry = prometheus.NewRegistry()
 	}
 	s := &Server{
-		gRPCAddr:    gRPCAddr,
-		gRPCGWAddr:  gRPCGWAddr,
-		logger:      logger,
-		db:          db,
-		promMetrics: grpc_prometheus.NewServerMetrics(),
+		gRPCAddr:           o.gRPCAddr,
+		gRPCGWAddr:         o.gRPCGWAddr,
+		adminAddr:          o.adminAddr,
+		metricsAddr:        o.metricsAddr,
+		adminOpts:          o.adminOpts,
+		logger:             o.logger,
+		store:              o.store,
+		registry:           o.registry,
+		unaryInterceptors:  o.unaryInterceptors,
+		streamInterceptors: o.streamInterceptors,
+		listeners:          o.listeners,
+		gwMuxOpts:          o.gwMuxOpts,
+		gwDialOpts:         o.gwDialOpts,
+		promMetrics:        grpc_prometheus.NewServerMetrics(),
+		tlsConfig:          o.tlsConfig,
+		principals:         newPrincipals(o.apiKeys),
+		dogLabels:          newLabelLimiterFromEnv(dogLabelAllowListEnv),
+		dogfoodLabels:      newLabelLimiterFromEnv(dogfoodLabelAllowListEnv),
+	}
+	if ss, ok := o.store.(store.SQLStore); ok {
+		s.db = ss.DB()
+	}
+	s.dbMetrics = newDBMetricsCollector(s)
+	if o.tlsConfig != nil {
+		serverTLS, err := o.tlsConfig.ServerTLSConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize TLS config: %w", err)
+		}
+		s.serverTLS = serverTLS
+	}
+	s.registerHealthChecks(o.healthChecks)
+	if err := s.initializeWebhooks(); err != nil {
+		return nil, fmt.Errorf("failed to initialize webhooks: %w", err)
 	}
-	if err := s.initializePromHttpServer(); err != nil {
+	if err := s.initializeOutbox(o.broker); err != nil {
+		return nil, fmt.Errorf("failed to initialize outbox: %w", err)
+	}
+	if err := s.initializeAlerting(); err != nil {
+		return nil, fmt.Errorf("failed to initialize alerting: %w", err)
+	}
+	if err := s.initializeAdminServer(); err != nil {
 		return nil, err
 	}
 	s.initializegRPCServer()
-	if err := s.initializegRPCGatewayServer(ctx); err != nil {
+	if err := s.initializegRPCGatewayServer(context.Background()); err != nil {
 		return nil, err
 	}
 	return s, nil
 }
 
+// listenAddr turns a port into an address to listen to on all interfaces.
+func listenAddr(addr string) string {
+	if strings.Contains(addr, ":") {
+		return addr
+	}
+	return fmt.Sprintf(":%s", addr)
+}
+
+// Start binds listeners which are not given by WithListeners, and serves on them by Serve.
+// It fails without serving if any listener cannot be bound. Like Serve, it returns after Stop is called
+// or any server dies rather than when ctx is done, so that callers drain servers by Stop.
 func (s *Server) Start(ctx context.Context) error {
+	listeners, err := s.listen()
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, listeners)
+}
+
+// Serve serves on listeners bound in advance until all servers stop, and stops the other servers
+// if one of them dies. The aggregated error of servers is returned.
+// Background workers, e.g. health checks and the outbox relay, run until ctx is done or the servers stop,
+// but servers are stopped only by Stop.
+// The gRPC listener must be bound to the gRPC address, which the gRPC gateway dials.
+func (s *Server) Serve(ctx context.Context, listeners *Listeners) error {
+	if listeners.Admin == nil || listeners.GRPC == nil || listeners.GRPCGateway == nil ||
+		(s.metricsServer != nil && listeners.Metrics == nil) {
+		return errors.New("listeners are missing")
+	}
 	s.logger.Info("dogfood backend server has started")
 
-	lis, err := net.Listen("tcp", s.gRPCAddr)
-	if err != nil {
-		return fmt.Errorf("faild to listen to gRPC Address: %w", err)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go s.watchHealth(ctx)
+	go s.dbMetrics.run(ctx)
+	go s.runAlerting(ctx)
+	go s.runWebhookWorker(ctx)
+	go s.runOutboxRelay(ctx)
+
+	sv := newSupervisor(s.logger, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), failureStopTimeout)
+		defer cancel()
+		s.Stop(ctx)
+	})
+	sv.Go("admin server", func() error { return s.adminServer.Serve(listeners.Admin) })
+	if s.metricsServer != nil {
+		sv.Go("metrics server", func() error { return s.metricsServer.Serve(listeners.Metrics) })
 	}
-	s.gRPCListener = lis
+	sv.Go("gRPC server", func() error { return s.grpcServer.Serve(listeners.GRPC) })
+	sv.Go("gRPC gateway server", func() error { return s.serve(s.grpcgwServer, listeners.GRPCGateway) })
+	return sv.Wait()
+}
 
-	go s.promHttpServer.ListenAndServe()
-	go s.grpcServer.Serve(lis)
-	go s.grpcgwServer.ListenAndServe()
+// Listeners are listeners of servers.
+type Listeners struct {
+	Admin net.Listener
+	// Metrics is used only if the metrics server is enabled by WithMetricsAddr.
+	Metrics     net.Listener
+	GRPC        net.Listener
+	GRPCGateway net.Listener
+}
 
-	<-ctx.Done()
-	return nil
+// listen binds listeners which are not given, and closes bound ones if any of them fails.
+func (s *Server) listen() (*Listeners, error) {
+	ls := s.listeners
+	var bound []net.Listener
+	for _, l := range []struct {
+		name string
+		addr string
+		lis  *net.Listener
+	}{
+		{"admin", s.adminAddr, &ls.Admin},
+		{"metrics", s.metricsAddr, &ls.Metrics},
+		{"gRPC", s.gRPCAddr, &ls.GRPC},
+		{"gRPC gateway", s.gRPCGWAddr, &ls.GRPCGateway},
+	} {
+		if *l.lis != nil || l.addr == "" {
+			continue
+		}
+		lis, err := net.Listen("tcp", l.addr)
+		if err != nil {
+			for _, b := range bound {
+				b.Close()
+			}
+			return nil, fmt.Errorf("failed to listen to %s address %s: %w", l.name, l.addr, err)
+		}
+		*l.lis = lis
+		bound = append(bound, lis)
+	}
+	return &ls, nil
+}
+
+// MarkNotReady makes readiness probes fail and reports NOT_SERVING,
+// so that clients stop sending new requests before draining.
+func (s *Server) MarkNotReady() {
+	atomic.StoreInt32(&s.notReady, 1)
+	s.healthServer.Shutdown()
 }
 
-func (s *Server) Stop() {
-	ctx := context.Background()
-	s.logger.Info("dogfood gateway is shutting down")
-	if err := s.gRPCListener.Close(); err != nil {
-		s.logger.Error("failed to close listener to gRPC server", zap.Error(err))
+// Stop drains in-flight requests until ctx is done, and then stops servers forcibly.
+// Only the first call takes effect, and the others return the same result.
+func (s *Server) Stop(ctx context.Context) error {
+	s.stopOnce.Do(func() {
+		s.stopErr = s.stop(ctx)
+	})
+	return s.stopErr
+}
+
+func (s *Server) stop(ctx context.Context) error {
+	s.logger.Info("dogfood backend server is shutting down")
+	s.MarkNotReady()
+
+	// The gRPC gateway is drained first because it forwards requests to the gRPC server.
+	var err error
+	if serr := s.grpcgwServer.Shutdown(ctx); serr != nil {
+		err = multierr.Append(err, fmt.Errorf("failed to shutdown gRPC gateway server: %w", serr))
+	}
+	if serr := lifecycle.GRPCServer(s.grpcServer)(ctx); serr != nil {
+		err = multierr.Append(err, fmt.Errorf("failed to stop gRPC server gracefully: %w", serr))
+	}
+	if cerr := s.conngRPCServer.Close(); cerr != nil {
+		err = multierr.Append(err, fmt.Errorf("failed to close connection to gRPC server: %w", cerr))
 	}
-	if err := s.promHttpServer.Shutdown(ctx); err != nil {
-		s.logger.Error("failed to shutdown prometheus server", zap.Error(err))
+	if serr := s.adminServer.Shutdown(ctx); serr != nil {
+		err = multierr.Append(err, fmt.Errorf("failed to shutdown admin server: %w", serr))
 	}
-	if err := s.grpcgwServer.Shutdown(ctx); err != nil {
-		s.logger.Error("failed to shutdown gRPC gateway server", zap.Error(err))
+	if s.metricsServer != nil {
+		if serr := s.metricsServer.Shutdown(ctx); serr != nil {
+			err = multierr.Append(err, fmt.Errorf("failed to shutdown metrics server: %w", serr))
+		}
 	}
-	s.grpcServer.GracefulStop()
 	s.logger.Info("bye~~")
+	return err
+}
+
+func (s *Server) serve(hs *http.Server, lis net.Listener) error {
+	if hs.TLSConfig != nil {
+		return hs.ServeTLS(lis, "", "")
+	}
+	return hs.Serve(lis)
 }
 
-func (s *Server) initializePromHttpServer() error {
-	r := prometheus.NewRegistry()
+func (s *Server) initializeAdminServer() error {
+	r := s.registry
 	if err := r.Register(s.promMetrics); err != nil {
 		return fmt.Errorf("failed to initialize gRPC metrics to prometheus: %w", err)
 	}
-	if err := r.Register(dogfoodGramGuage); err != nil {
-		return fmt.Errorf("failed to initialize dogfood gram guage to prometheus: %w", err)
+	if err := r.Register(dogfoodGramHistogram); err != nil {
+		return fmt.Errorf("failed to initialize dogfood gram histogram to prometheus: %w", err)
+	}
+	if err := r.Register(dogfoodGramTotal); err != nil {
+		return fmt.Errorf("failed to initialize dogfood gram total to prometheus: %w", err)
 	}
 	if err := r.Register(dogfoodNameCount); err != nil {
 		return fmt.Errorf("failed to initialize dogfood name count to prometheus: %w", err)
 	}
-	s.promHttpServer = &http.Server{
-		Handler: promhttp.HandlerFor(r, promhttp.HandlerOpts{}),
-		Addr:    ":9092",
+	if err := s.dbMetrics.register(r); err != nil {
+		return err
+	}
+	if s.db != nil {
+		// Stats of the connection pool, e.g. go_sql_wait_count_total, tell whether the pool is exhausted.
+		if err := r.Register(collectors.NewDBStatsCollector(s.db, "dogfood")); err != nil {
+			return fmt.Errorf("failed to initialize database pool stats to prometheus: %w", err)
+		}
+	}
+	// The admin and metrics servers are served in plaintext even if TLS is enabled, because the admin server
+	// listens on localhost and agents scrape the metrics server by http.
+	s.adminServer = admin.NewServer(s.adminAddr, append([]admin.Option{admin.WithMetrics(r)}, s.adminOpts...)...)
+	if s.metricsAddr != "" || s.listeners.Metrics != nil {
+		s.metricsServer = admin.NewMetricsServer(s.metricsAddr, r)
 	}
 	return nil
 }
 
 func (s *Server) initializegRPCServer() {
-	ignoreMethods := make([]string, len(healthcheckpb.HealthCheckService_ServiceDesc.Methods))
-	for i, m := range healthcheckpb.HealthCheckService_ServiceDesc.Methods {
-		ignoreMethods[i] = fmt.Sprintf(
-			"/%s/%s",
-			healthcheckpb.HealthCheckService_ServiceDesc.ServiceName,
-			m.MethodName,
-		)
-	}
-
-	grpcsvc := grpc.NewServer(
-		grpc_middleware.WithUnaryServerChain(
-			grpc_recovery.UnaryServerInterceptor(),
-			grpc_dd.UnaryServerInterceptor(
-				grpc_dd.WithIgnoredMethods(ignoreMethods...),
-			),
-			metricsUnaryServerInterceptor(),
-			s.promMetrics.UnaryServerInterceptor(),
-			grpc_zap.UnaryServerInterceptor(
-				s.logger,
-				grpc_zap.WithDecider(func(fullMethodName string, _ error) bool {
-					return !strings.Contains(fullMethodName, "healthcheck")
-				}),
-				grpc_zap.WithMessageProducer(func(ctx context.Context, msg string, level zapcore.Level, code codes.Code, err error, duration zapcore.Field) {
-					if dds, ok := tracer.SpanFromContext(ctx); ok {
-						grpc_zap.AddFields(
-							ctx,
-							zap.Uint64("dd.trace_id", dds.Context().TraceID()),
-							zap.Uint64("dd.span_id", dds.Context().SpanID()),
-						)
-					}
-					grpc_zap.DefaultMessageProducer(ctx, msg, level, code, err, duration)
-				}),
-			),
-		),
-	)
+	var ignoreMethods []string
+	for _, desc := range []grpc.ServiceDesc{
+		healthcheckpb.HealthCheckService_ServiceDesc,
+		healthpb.Health_ServiceDesc,
+	} {
+		for _, m := range desc.Methods {
+			ignoreMethods = append(ignoreMethods, fmt.Sprintf("/%s/%s", desc.ServiceName, m.MethodName))
+		}
+		for _, st := range desc.Streams {
+			ignoreMethods = append(ignoreMethods, fmt.Sprintf("/%s/%s", desc.ServiceName, st.StreamName))
+		}
+	}
+
+	zapOpts := []grpc_zap.Option{
+		grpc_zap.WithDecider(func(fullMethodName string, _ error) bool {
+			return !strings.Contains(fullMethodName, "healthcheck") &&
+				!strings.HasPrefix(fullMethodName, "/grpc.health.v1.Health/")
+		}),
+		grpc_zap.WithMessageProducer(func(ctx context.Context, msg string, level zapcore.Level, code codes.Code, err error, duration zapcore.Field) {
+			grpc_zap.AddFields(ctx, telemetry.LogFields(ctx)...)
+			grpc_zap.DefaultMessageProducer(ctx, msg, level, code, err, duration)
+		}),
+	}
+	unary := []grpc.UnaryServerInterceptor{
+		grpc_recovery.UnaryServerInterceptor(),
+		requestid.UnaryServerInterceptor(),
+		telemetry.UnaryServerInterceptor(ignoreMethods...),
+		metricsUnaryServerInterceptor(s.dogLabels, s.dogfoodLabels),
+		s.principals.unaryServerInterceptor(),
+		s.promMetrics.UnaryServerInterceptor(),
+		grpc_zap.UnaryServerInterceptor(s.logger, zapOpts...),
+	}
+	stream := []grpc.StreamServerInterceptor{
+		grpc_recovery.StreamServerInterceptor(),
+		requestid.StreamServerInterceptor(),
+		telemetry.StreamServerInterceptor(ignoreMethods...),
+		s.principals.streamServerInterceptor(),
+		s.promMetrics.StreamServerInterceptor(),
+		grpc_zap.StreamServerInterceptor(s.logger, zapOpts...),
+	}
+	opts := []grpc.ServerOption{
+		grpc_middleware.WithUnaryServerChain(append(unary, s.unaryInterceptors...)...),
+		grpc_middleware.WithStreamServerChain(append(stream, s.streamInterceptors...)...),
+	}
+	if s.serverTLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(s.serverTLS)))
+	}
+	grpcsvc := grpc.NewServer(opts...)
 	dogfoodpb.RegisterDogFoodServiceServer(grpcsvc, s)
 	healthcheckpb.RegisterHealthCheckServiceServer(grpcsvc, s)
+	s.healthServer = grpc_health.NewServer()
+	for _, svc := range healthServices {
+		s.healthServer.SetServingStatus(svc, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+	healthpb.RegisterHealthServer(grpcsvc, s.healthServer)
 	s.promMetrics.InitializeMetrics(grpcsvc)
 
 	s.grpcServer = grpcsvc
 }
 
 func (s *Server) initializegRPCGatewayServer(ctx context.Context) error {
+	creds, err := s.dialCredentials()
+	if err != nil {
+		return err
+	}
+	target := s.gRPCAddr
+	if s.listeners.GRPC != nil {
+		target = s.listeners.GRPC.Addr().String()
+	}
 	conn, err := grpc.DialContext(
 		ctx,
-		s.gRPCAddr,
-		grpc.WithInsecure(),
-		grpc.WithDisableHealthCheck(),
+		target,
+		append([]grpc.DialOption{
+			creds,
+			grpc.WithDisableHealthCheck(),
+			grpc.WithUnaryInterceptor(telemetry.UnaryClientInterceptor()),
+			grpc.WithStreamInterceptor(telemetry.StreamClientInterceptor()),
+		}, s.gwDialOpts...)...,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to dial gRPC Server: %w", err)
 	}
 	s.conngRPCServer = conn
 
-	gwmux := runtime.NewServeMux(
-		runtime.WithMetadata(func(ctx context.Context, r *http.Request) metadata.MD {
-			return metadata.New(map[string]string{
-				tracer.DefaultTraceIDHeader:  r.Header.Get(tracer.DefaultTraceIDHeader),
-				tracer.DefaultParentIDHeader: r.Header.Get(tracer.DefaultParentIDHeader),
-				tracer.DefaultPriorityHeader: r.Header.Get(tracer.DefaultPriorityHeader),
-			})
+	gwmux := runtime.NewServeMux(append([]runtime.ServeMuxOption{
+		runtime.WithMetadata(func(_ context.Context, r *http.Request) metadata.MD {
+			return metadata.Join(telemetry.MetadataFromRequest(r), requestid.Metadata(r.Context()))
 		}),
-	)
+		runtime.WithIncomingHeaderMatcher(incomingHeaderMatcher),
+		runtime.WithForwardResponseOption(forwardHTTPCode),
+	}, s.gwMuxOpts...)...)
 	if err := dogfoodpb.RegisterDogFoodServiceHandler(ctx, gwmux, conn); err != nil {
 		return fmt.Errorf("failed to regiser handler: %w", err)
 	}
 	if err := healthcheckpb.RegisterHealthCheckServiceHandler(ctx, gwmux, conn); err != nil {
 		return fmt.Errorf("failed to regiser handler: %w", err)
 	}
+	if err := gwmux.HandlePath(http.MethodGet, exportRecordsPath, exportRecordsHandler(dogfoodpb.NewDogFoodServiceClient(conn), s.logger)); err != nil {
+		return fmt.Errorf("failed to regiser handler: %w", err)
+	}
 	s.grpcgwServer = &http.Server{
-		Addr: s.gRPCGWAddr,
-		Handler: http_dd.WrapHandler(
-			gwmux,
+		Addr:      s.gRPCGWAddr,
+		TLSConfig: s.serverTLS,
+		Handler: telemetry.WrapHandler(
+			requestid.Middleware(gwmux),
 			ddconfig.GetService(ddconfig.WithServiceSuffix(".grpcgateway")),
-			"",
-			http_dd.WithIgnoreRequest(func(r *http.Request) bool {
+			func(r *http.Request) bool {
 				return strings.Contains(strings.ToLower(r.RequestURI), "healthcheck")
-			}),
+			},
 		),
 	}
 	return nil
 }
+
+// dialCredentials returns credentials for the gRPC gateway to dial the gRPC server.
+// The server certificate is presented as a client certificate, so it passes client authentication.
+// It's verified by the configured CA rather than system roots, which don't trust internal certificates.
+func (s *Server) dialCredentials() (grpc.DialOption, error) {
+	if s.tlsConfig == nil {
+		return grpc.WithInsecure(), nil
+	}
+	if s.tlsConfig.CAFile == "" {
+		return nil, errors.New("CA file is required for the gRPC gateway to verify the gRPC server")
+	}
+	tc := *s.tlsConfig
+	if tc.ServerName == "" {
+		tc.ServerName = "localhost"
+	}
+	clientTLS, err := tc.ClientTLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize TLS config to dial gRPC server: %w", err)
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(clientTLS)), nil
+}
+
+// errSQLStoreRequired is returned by RPCs of subsystems which are disabled unless the store is backed by Postgres.
+func errSQLStoreRequired(subsystem string) error {
+	return status.Errorf(codes.Unimplemented, "%s require Postgres", subsystem)
+}
@@ -0,0 +1,566 @@
+package protov1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/kei6u/dogfood/pkg/health"
+	"github.com/kei6u/dogfood/pkg/requestid"
+	"github.com/kei6u/dogfood/pkg/store"
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestNewOptions_Addr(t *testing.T) {
+	tests := []struct {
+		name                             string
+		opts                             []Option
+		wantGRPC, wantGateway, wantAdmin string
+	}{
+		{
+			name:        "defaults",
+			wantGRPC:    ":5000",
+			wantGateway: ":8080",
+			wantAdmin:   "localhost:9092",
+		},
+		{
+			name:        "empty addresses keep defaults",
+			opts:        []Option{WithGRPCAddr(""), WithGRPCGatewayAddr(""), WithAdminAddr("")},
+			wantGRPC:    ":5000",
+			wantGateway: ":8080",
+			wantAdmin:   "localhost:9092",
+		},
+		{
+			name:        "ports",
+			opts:        []Option{WithGRPCAddr("5001"), WithGRPCGatewayAddr("8081"), WithAdminAddr("9093")},
+			wantGRPC:    ":5001",
+			wantGateway: ":8081",
+			wantAdmin:   "localhost:9093",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newOptions(tt.opts...)
+			if o.gRPCAddr != tt.wantGRPC || o.gRPCGWAddr != tt.wantGateway || o.adminAddr != tt.wantAdmin {
+				t.Errorf("addresses = %s, %s, %s, want %s, %s, %s", o.gRPCAddr, o.gRPCGWAddr, o.adminAddr, tt.wantGRPC, tt.wantGateway, tt.wantAdmin)
+			}
+		})
+	}
+}
+
+func TestNewServer_Options(t *testing.T) {
+	grpcLis := bufconn.Listen(1 << 20)
+	gwLis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminLis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	metricsLis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var methods []string
+	registry := prometheus.NewRegistry()
+	s, err := NewServer(
+		WithStore(store.NewMemoryStore()),
+		WithMetricsRegistry(registry),
+		WithListeners(Listeners{Admin: adminLis, Metrics: metricsLis, GRPC: grpcLis, GRPCGateway: gwLis}),
+		WithUnaryInterceptors(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			mu.Lock()
+			methods = append(methods, info.FullMethod)
+			mu.Unlock()
+			return handler(ctx, req)
+		}),
+		WithGatewayDialOptions(grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return grpcLis.DialContext(ctx)
+		})),
+		WithGatewayMuxOptions(runtime.WithOutgoingHeaderMatcher(func(string) (string, bool) { return "", false })),
+	)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	started := make(chan error, 1)
+	go func() { started <- s.Start(ctx) }()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.Stop(ctx); err != nil {
+			t.Errorf("Stop() error = %v", err)
+		}
+		<-started
+	}()
+
+	res, err := http.Post("http://"+gwLis.Addr().String()+"/v1/dogfood/record", "application/json", strings.NewReader(`{"dogfood_name":"a","gram":100,"dog_name":"pochi"}`))
+	if err != nil {
+		t.Fatalf("CreateRecord error = %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("CreateRecord status = %d, want 200", res.StatusCode)
+	}
+	mu.Lock()
+	if len(methods) != 1 || methods[0] != "/dogfoodpb.v1.DogFoodService/CreateRecord" {
+		t.Errorf("intercepted methods = %v", methods)
+	}
+	mu.Unlock()
+
+	mfs, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	found := false
+	for _, mf := range mfs {
+		if mf.GetName() == "grpc_server_handled_total" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("gRPC metrics must be registered to the given registry")
+	}
+	for _, u := range []string{"http://" + metricsLis.Addr().String() + "/metrics", "http://" + adminLis.Addr().String() + "/metrics"} {
+		res, err := http.Get(u)
+		if err != nil {
+			t.Fatalf("GET %s error = %v", u, err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("GET %s status = %d, want 200", u, res.StatusCode)
+		}
+	}
+
+	if _, err := NewServer(); err == nil {
+		t.Error("NewServer() without a store must fail")
+	}
+}
+
+func TestServer_LogRequestID(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	grpcLis := bufconn.Listen(1 << 20)
+	var ls []net.Listener
+	for i := 0; i < 2; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ls = append(ls, l)
+	}
+	dialer := grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return grpcLis.DialContext(ctx)
+	})
+	s, err := NewServer(
+		WithStore(store.NewMemoryStore()),
+		WithLogger(zap.New(core)),
+		WithListeners(Listeners{Admin: ls[0], GRPC: grpcLis, GRPCGateway: ls[1]}),
+		WithGatewayDialOptions(dialer),
+	)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	started := make(chan error, 1)
+	go func() { started <- s.Start(ctx) }()
+	defer func() {
+		s.Stop(ctx)
+		<-started
+	}()
+
+	conn, err := grpc.DialContext(ctx, "bufnet", dialer, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial gRPC server: %v", err)
+	}
+	defer conn.Close()
+	client := dogfoodpb.NewDogFoodServiceClient(conn)
+
+	tests := []struct {
+		method string
+		call   func(ctx context.Context) error
+	}{
+		{
+			method: "CreateRecord",
+			call: func(ctx context.Context) error {
+				_, err := client.CreateRecord(ctx, &dogfoodpb.CreateRecordRequest{DogfoodName: "a", Gram: 100, DogName: "pochi"})
+				return err
+			},
+		},
+		{
+			method: "ExportRecords",
+			call: func(ctx context.Context) error {
+				stream, err := client.ExportRecords(ctx, &dogfoodpb.ExportRecordsRequest{})
+				if err != nil {
+					return err
+				}
+				for {
+					if _, err := stream.Recv(); err == io.EOF {
+						return nil
+					} else if err != nil {
+						return err
+					}
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			id := "req-" + tt.method
+			if err := tt.call(metadata.AppendToOutgoingContext(ctx, requestid.MetadataKey, id)); err != nil {
+				t.Fatalf("%s error = %v", tt.method, err)
+			}
+			// Logs are written after responses are sent.
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				entries := logs.FilterField(zap.String("grpc.method", tt.method)).FilterField(zap.String("request_id", id)).All()
+				if len(entries) > 0 {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("%s is not logged with request ID %s", tt.method, id)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
+
+type unreachableStore struct {
+	store.Store
+}
+
+func (unreachableStore) Ping(context.Context) error {
+	return errors.New("store is unreachable")
+}
+
+func TestServer_HealthReport(t *testing.T) {
+	tests := []struct {
+		name       string
+		store      store.Store
+		wantCode   int
+		wantStatus string
+	}{
+		{name: "ok", store: store.NewMemoryStore(), wantCode: http.StatusOK, wantStatus: "ok"},
+		{name: "failing", store: unreachableStore{store.NewMemoryStore()}, wantCode: http.StatusServiceUnavailable, wantStatus: "failing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grpcLis := bufconn.Listen(1 << 20)
+			var ls []net.Listener
+			for i := 0; i < 2; i++ {
+				l, err := net.Listen("tcp", "127.0.0.1:0")
+				if err != nil {
+					t.Fatal(err)
+				}
+				ls = append(ls, l)
+			}
+			s, err := NewServer(
+				WithStore(tt.store),
+				WithListeners(Listeners{Admin: ls[0], GRPC: grpcLis, GRPCGateway: ls[1]}),
+				WithGatewayDialOptions(grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+					return grpcLis.DialContext(ctx)
+				})),
+			)
+			if err != nil {
+				t.Fatalf("NewServer() error = %v", err)
+			}
+			started := make(chan error, 1)
+			go func() { started <- s.Start(context.Background()) }()
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				s.Stop(ctx)
+				<-started
+			}()
+
+			res, err := http.Get("http://" + ls[1].Addr().String() + "/v1/healthcheck/report")
+			if err != nil {
+				t.Fatalf("Report error = %v", err)
+			}
+			defer res.Body.Close()
+			var report struct {
+				Status string `json:"status"`
+			}
+			if err := json.NewDecoder(res.Body).Decode(&report); err != nil {
+				t.Fatalf("failed to decode a health report: %v", err)
+			}
+			if res.StatusCode != tt.wantCode || report.Status != tt.wantStatus {
+				t.Errorf("Report = %d %s, want %d %s", res.StatusCode, report.Status, tt.wantCode, tt.wantStatus)
+			}
+			if v := res.Header.Get("Grpc-Metadata-X-Http-Code"); v != "" {
+				t.Errorf("%s is leaked as a header: %s", httpCodeHeader, v)
+			}
+		})
+	}
+}
+
+func TestNewServer_HealthCheck(t *testing.T) {
+	s, err := NewServer(
+		WithStore(store.NewMemoryStore()),
+		WithHealthCheck("redis", func(context.Context) error { return errors.New("connection refused") }, health.WithNonCritical()),
+	)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	report := s.health.Report(context.Background())
+	// A failing non-critical check is reported without failing the server.
+	if report.Status != health.StatusOK {
+		t.Errorf("status = %s, want %s", report.Status, health.StatusOK)
+	}
+	found := false
+	for _, c := range report.Checks {
+		if c.Name == "redis" {
+			found = true
+			if c.Status != health.StatusFailing || c.Critical {
+				t.Errorf("redis check = %s, critical = %v, want a failing non-critical check", c.Status, c.Critical)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("redis check is not registered: %+v", report.Checks)
+	}
+}
+
+func TestServer_HealthService(t *testing.T) {
+	tests := []struct {
+		name  string
+		store store.Store
+		want  healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{name: "serving", store: store.NewMemoryStore(), want: healthpb.HealthCheckResponse_SERVING},
+		{name: "not serving", store: unreachableStore{store.NewMemoryStore()}, want: healthpb.HealthCheckResponse_NOT_SERVING},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grpcLis := bufconn.Listen(1 << 20)
+			var ls []net.Listener
+			for i := 0; i < 2; i++ {
+				l, err := net.Listen("tcp", "127.0.0.1:0")
+				if err != nil {
+					t.Fatal(err)
+				}
+				ls = append(ls, l)
+			}
+			dialer := grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return grpcLis.DialContext(ctx)
+			})
+			s, err := NewServer(
+				WithStore(tt.store),
+				WithListeners(Listeners{Admin: ls[0], GRPC: grpcLis, GRPCGateway: ls[1]}),
+				WithGatewayDialOptions(dialer),
+			)
+			if err != nil {
+				t.Fatalf("NewServer() error = %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			// Services are not serving until dependencies are checked.
+			for _, svc := range healthServices {
+				res, err := s.healthServer.Check(ctx, &healthpb.HealthCheckRequest{Service: svc})
+				if err != nil || res.GetStatus() != healthpb.HealthCheckResponse_NOT_SERVING {
+					t.Errorf("Check(%q) before start = %v, %v, want NOT_SERVING", svc, res.GetStatus(), err)
+				}
+			}
+
+			started := make(chan error, 1)
+			go func() { started <- s.Start(ctx) }()
+			stopped := false
+			defer func() {
+				if !stopped {
+					s.Stop(ctx)
+				}
+				<-started
+			}()
+
+			conn, err := grpc.DialContext(ctx, "bufnet", dialer, grpc.WithTransportCredentials(insecure.NewCredentials()))
+			if err != nil {
+				t.Fatalf("failed to dial gRPC server: %v", err)
+			}
+			defer conn.Close()
+			client := healthpb.NewHealthClient(conn)
+
+			watchCtx, cancelWatch := context.WithCancel(ctx)
+			defer cancelWatch()
+			stream, err := client.Watch(watchCtx, &healthpb.HealthCheckRequest{Service: dogfoodpb.DogFoodService_ServiceDesc.ServiceName})
+			if err != nil {
+				t.Fatalf("Watch() error = %v", err)
+			}
+			// Status is streamed until dependencies are checked.
+			for {
+				res, err := stream.Recv()
+				if err != nil {
+					t.Fatalf("Recv() error = %v, want %v", err, tt.want)
+				}
+				if res.GetStatus() == tt.want {
+					break
+				}
+			}
+			for _, svc := range healthServices {
+				res, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: svc})
+				if err != nil || res.GetStatus() != tt.want {
+					t.Errorf("Check(%q) = %v, %v, want %v", svc, res.GetStatus(), err, tt.want)
+				}
+			}
+
+			// Watchers are told to stop sending requests before draining.
+			s.MarkNotReady()
+			if tt.want == healthpb.HealthCheckResponse_SERVING {
+				res, err := stream.Recv()
+				if err != nil || res.GetStatus() != healthpb.HealthCheckResponse_NOT_SERVING {
+					t.Errorf("Recv() after MarkNotReady = %v, %v, want NOT_SERVING", res.GetStatus(), err)
+				}
+			}
+			cancelWatch()
+
+			if err := s.Stop(ctx); err != nil {
+				t.Errorf("Stop() error = %v", err)
+			}
+			stopped = true
+			// Dependency checks don't bring services back after Stop.
+			s.updateServingStatus(ctx)
+			for _, svc := range healthServices {
+				res, err := s.healthServer.Check(ctx, &healthpb.HealthCheckRequest{Service: svc})
+				if err != nil || res.GetStatus() != healthpb.HealthCheckResponse_NOT_SERVING {
+					t.Errorf("Check(%q) after Stop = %v, %v, want NOT_SERVING", svc, res.GetStatus(), err)
+				}
+			}
+		})
+	}
+}
+
+// freeAddr returns an address which is not bound at the moment.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestServer_Start_BindFailure(t *testing.T) {
+	occupied, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer occupied.Close()
+	adminAddr, grpcAddr := freeAddr(t), freeAddr(t)
+
+	// The gRPC gateway is bound last, after the others are bound.
+	s, err := NewServer(
+		WithStore(store.NewMemoryStore()),
+		WithAdminAddr(adminAddr),
+		WithGRPCAddr(grpcAddr),
+		WithGRPCGatewayAddr(occupied.Addr().String()),
+	)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	started := make(chan error, 1)
+	go func() { started <- s.Start(context.Background()) }()
+	select {
+	case err := <-started:
+		if err == nil || !strings.Contains(err.Error(), "gRPC gateway") {
+			t.Errorf("Start() error = %v, want an error of the gRPC gateway", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start() doesn't fail")
+	}
+
+	// Listeners bound before the failure are closed.
+	for _, addr := range []string{adminAddr, grpcAddr} {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			t.Errorf("%s is left bound: %v", addr, err)
+			continue
+		}
+		l.Close()
+	}
+}
+
+// pingCountingStore counts health checks, which run until background workers stop.
+type pingCountingStore struct {
+	store.Store
+	pings int32
+}
+
+func (s *pingCountingStore) Ping(context.Context) error {
+	atomic.AddInt32(&s.pings, 1)
+	return nil
+}
+
+func TestServer_Serve_ServerDies(t *testing.T) {
+	t.Setenv("HEALTH_CHECK_INTERVAL", "10ms")
+	t.Setenv("HEALTH_CHECK_CACHE_TTL", "1ns")
+	st := &pingCountingStore{Store: store.NewMemoryStore()}
+	grpcLis := bufconn.Listen(1 << 20)
+	adminLis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The gRPC gateway dies as soon as it starts to serve.
+	gwLis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gwLis.Close()
+
+	s, err := NewServer(
+		WithStore(st),
+		WithGatewayDialOptions(grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return grpcLis.DialContext(ctx)
+		})),
+	)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(context.Background(), &Listeners{Admin: adminLis, GRPC: grpcLis, GRPCGateway: gwLis})
+	}()
+	select {
+	case err := <-served:
+		if err == nil || !strings.Contains(err.Error(), "gRPC gateway server died") {
+			t.Errorf("Serve() error = %v, want an error of the gRPC gateway", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Serve() doesn't return after the gRPC gateway died")
+	}
+
+	// The other servers are shut down.
+	if conn, err := net.Dial("tcp", adminLis.Addr().String()); err == nil {
+		conn.Close()
+		t.Error("admin server is still serving")
+	}
+	// So are background workers, even though the context of Serve is not done.
+	time.Sleep(50 * time.Millisecond)
+	pings := atomic.LoadInt32(&st.pings)
+	time.Sleep(50 * time.Millisecond)
+	if got := atomic.LoadInt32(&st.pings); got != pings {
+		t.Errorf("health checks keep running after Serve returned: %d -> %d", pings, got)
+	}
+}
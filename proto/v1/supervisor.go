@@ -0,0 +1,56 @@
+package protov1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// supervisor runs servers and calls onFailure once when any of them dies unexpectedly,
+// so that the others are shut down rather than left half-running.
+type supervisor struct {
+	logger    *zap.Logger
+	onFailure func()
+
+	wg   sync.WaitGroup
+	once sync.Once
+	mu   sync.Mutex
+	err  error
+}
+
+func newSupervisor(logger *zap.Logger, onFailure func()) *supervisor {
+	return &supervisor{
+		logger:    logger,
+		onFailure: onFailure,
+	}
+}
+
+// Go runs serve in a goroutine. Errors returned on a normal shutdown are ignored.
+func (sv *supervisor) Go(name string, serve func() error) {
+	sv.wg.Add(1)
+	go func() {
+		defer sv.wg.Done()
+		err := serve()
+		if err == nil || errors.Is(err, http.ErrServerClosed) || errors.Is(err, grpc.ErrServerStopped) {
+			return
+		}
+		sv.logger.Error("server died", zap.String("name", name), zap.Error(err))
+		sv.mu.Lock()
+		sv.err = multierr.Append(sv.err, fmt.Errorf("%s died: %w", name, err))
+		sv.mu.Unlock()
+		sv.once.Do(sv.onFailure)
+	}()
+}
+
+// Wait waits for all servers to stop and returns the aggregated error.
+func (sv *supervisor) Wait() error {
+	sv.wg.Wait()
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	return sv.err
+}
@@ -0,0 +1,26 @@
+package protov1
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestSupervisor(t *testing.T) {
+	stop := make(chan struct{})
+	sv := newSupervisor(zap.NewNop(), func() { close(stop) })
+
+	errBind := errors.New("address already in use")
+	sv.Go("healthy", func() error {
+		<-stop
+		return http.ErrServerClosed
+	})
+	sv.Go("broken", func() error { return errBind })
+
+	err := sv.Wait()
+	if !errors.Is(err, errBind) {
+		t.Errorf("Wait() error = %v, want %v", err, errBind)
+	}
+}
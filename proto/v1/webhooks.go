@@ -0,0 +1,280 @@
+package protov1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gogo/status"
+	"github.com/kei6u/dogfood/pkg/telemetry"
+	"github.com/kei6u/dogfood/pkg/webhook"
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	recordCreatedEventType       = "record.created"
+	defaultWebhookDeliveriesPage = 100
+	maxWebhookDeliveriesPage     = 1000
+)
+
+// webhookEvent is a payload posted to webhook subscriptions.
+type webhookEvent struct {
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	Data       json.RawMessage `json:"data"`
+}
+
+func recordCreatedPayload(r *dogfoodpb.Record) ([]byte, error) {
+	data, err := protojson.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode a record: %w", err)
+	}
+	return json.Marshal(webhookEvent{
+		Type:       recordCreatedEventType,
+		OccurredAt: r.GetEatenAt().AsTime(),
+		Data:       data,
+	})
+}
+
+func (s *Server) CreateWebhookSubscription(ctx context.Context, req *dogfoodpb.CreateWebhookSubscriptionRequest) (*dogfoodpb.WebhookSubscription, error) {
+	var span telemetry.Span
+	span, ctx = telemetry.StartSpanFromContext(ctx, "CreateWebhookSubscription", "WebhookSubscription")
+	defer span.Finish()
+
+	if err := s.webhooksDisabled(); err != nil {
+		return nil, err
+	}
+	if err := webhook.ValidateURL(req.GetUrl()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	sub := &webhook.Subscription{URL: req.GetUrl(), Secret: req.GetSecret()}
+	if sub.Secret == "" {
+		secret, err := webhook.NewSecret()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to create a webhook subscription: %s", err)
+		}
+		sub.Secret = secret
+	}
+	if err := s.webhooks.CreateSubscription(ctx, sub); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create a webhook subscription: %s", err)
+	}
+	return toWebhookSubscriptionpb(sub, true), nil
+}
+
+func (s *Server) GetWebhookSubscription(ctx context.Context, req *dogfoodpb.GetWebhookSubscriptionRequest) (*dogfoodpb.WebhookSubscription, error) {
+	var span telemetry.Span
+	span, ctx = telemetry.StartSpanFromContext(ctx, "GetWebhookSubscription", "WebhookSubscription")
+	defer span.Finish()
+
+	if err := s.webhooksDisabled(); err != nil {
+		return nil, err
+	}
+	sub, err := s.webhooks.GetSubscription(ctx, req.GetId())
+	if err != nil {
+		return nil, webhookError(err, "failed to get a webhook subscription")
+	}
+	return toWebhookSubscriptionpb(sub, false), nil
+}
+
+func (s *Server) ListWebhookSubscriptions(ctx context.Context, _ *dogfoodpb.ListWebhookSubscriptionsRequest) (*dogfoodpb.ListWebhookSubscriptionsResponse, error) {
+	var span telemetry.Span
+	span, ctx = telemetry.StartSpanFromContext(ctx, "ListWebhookSubscriptions", "WebhookSubscriptions")
+	defer span.Finish()
+
+	if err := s.webhooksDisabled(); err != nil {
+		return nil, err
+	}
+	subs, err := s.webhooks.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list up webhook subscriptions: %s", err)
+	}
+	res := &dogfoodpb.ListWebhookSubscriptionsResponse{Subscriptions: make([]*dogfoodpb.WebhookSubscription, len(subs))}
+	for i, sub := range subs {
+		res.Subscriptions[i] = toWebhookSubscriptionpb(sub, false)
+	}
+	return res, nil
+}
+
+func (s *Server) UpdateWebhookSubscription(ctx context.Context, req *dogfoodpb.UpdateWebhookSubscriptionRequest) (*dogfoodpb.WebhookSubscription, error) {
+	var span telemetry.Span
+	span, ctx = telemetry.StartSpanFromContext(ctx, "UpdateWebhookSubscription", "WebhookSubscription")
+	defer span.Finish()
+
+	if err := s.webhooksDisabled(); err != nil {
+		return nil, err
+	}
+	if req.GetSecret() != "" && req.GetRotateSecret() {
+		return nil, status.Error(codes.InvalidArgument, "secret and rotate_secret are exclusive")
+	}
+	sub, err := s.webhooks.GetSubscription(ctx, req.GetId())
+	if err != nil {
+		return nil, webhookError(err, "failed to update a webhook subscription")
+	}
+	if req.GetUrl() != "" {
+		if err := webhook.ValidateURL(req.GetUrl()); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		sub.URL = req.GetUrl()
+	}
+	secretChanged := req.GetSecret() != "" || req.GetRotateSecret()
+	if req.GetSecret() != "" {
+		sub.Secret = req.GetSecret()
+	}
+	if req.GetRotateSecret() {
+		if sub.Secret, err = webhook.NewSecret(); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to update a webhook subscription: %s", err)
+		}
+	}
+	if err := s.webhooks.UpdateSubscription(ctx, sub); err != nil {
+		return nil, webhookError(err, "failed to update a webhook subscription")
+	}
+	return toWebhookSubscriptionpb(sub, secretChanged), nil
+}
+
+func (s *Server) DeleteWebhookSubscription(ctx context.Context, req *dogfoodpb.DeleteWebhookSubscriptionRequest) (*dogfoodpb.DeleteWebhookSubscriptionResponse, error) {
+	var span telemetry.Span
+	span, ctx = telemetry.StartSpanFromContext(ctx, "DeleteWebhookSubscription", "WebhookSubscription")
+	defer span.Finish()
+
+	if err := s.webhooksDisabled(); err != nil {
+		return nil, err
+	}
+	if err := s.webhooks.DeleteSubscription(ctx, req.GetId()); err != nil {
+		return nil, webhookError(err, "failed to delete a webhook subscription")
+	}
+	return &dogfoodpb.DeleteWebhookSubscriptionResponse{}, nil
+}
+
+func (s *Server) ListWebhookDeliveries(ctx context.Context, req *dogfoodpb.ListWebhookDeliveriesRequest) (*dogfoodpb.ListWebhookDeliveriesResponse, error) {
+	var span telemetry.Span
+	span, ctx = telemetry.StartSpanFromContext(ctx, "ListWebhookDeliveries", "WebhookDeliveries")
+	defer span.Finish()
+
+	if err := s.webhooksDisabled(); err != nil {
+		return nil, err
+	}
+	st := webhook.Status(req.GetStatus())
+	switch st {
+	case "", webhook.StatusPending, webhook.StatusSucceeded, webhook.StatusDead:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "status %s is invalid, it must be pending, succeeded or dead", st)
+	}
+	pageSize := int(req.GetPageSize())
+	switch {
+	case pageSize < 0:
+		return nil, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	case pageSize == 0:
+		pageSize = defaultWebhookDeliveriesPage
+	case pageSize > maxWebhookDeliveriesPage:
+		pageSize = maxWebhookDeliveriesPage
+	}
+	ds, err := s.webhooks.ListDeliveries(ctx, webhook.DeliveryFilter{
+		SubscriptionID: req.GetSubscriptionId(),
+		Status:         st,
+		PageSize:       pageSize,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list up webhook deliveries: %s", err)
+	}
+	res := &dogfoodpb.ListWebhookDeliveriesResponse{Deliveries: make([]*dogfoodpb.WebhookDelivery, len(ds))}
+	for i, d := range ds {
+		pb := &dogfoodpb.WebhookDelivery{
+			Id:             d.ID,
+			SubscriptionId: d.SubscriptionID,
+			EventType:      d.EventType,
+			Status:         string(d.Status),
+			Attempts:       int32(d.Attempts),
+			LastError:      d.LastError,
+			CreatedAt:      timestamppb.New(d.CreatedAt),
+		}
+		if d.Status == webhook.StatusPending {
+			pb.NextAttemptAt = timestamppb.New(d.NextAttemptAt)
+		}
+		if d.DeliveredAt != nil {
+			pb.DeliveredAt = timestamppb.New(*d.DeliveredAt)
+		}
+		res.Deliveries[i] = pb
+	}
+	return res, nil
+}
+
+func toWebhookSubscriptionpb(sub *webhook.Subscription, withSecret bool) *dogfoodpb.WebhookSubscription {
+	pb := &dogfoodpb.WebhookSubscription{
+		Id:        sub.ID,
+		Url:       sub.URL,
+		CreatedAt: timestamppb.New(sub.CreatedAt),
+		UpdatedAt: timestamppb.New(sub.UpdatedAt),
+	}
+	if withSecret {
+		pb.Secret = sub.Secret
+	}
+	return pb
+}
+
+func webhookError(err error, msg string) error {
+	if errors.Is(err, webhook.ErrNotFound) {
+		return status.Error(codes.NotFound, "webhook subscription is not found")
+	}
+	return status.Errorf(codes.Internal, "%s: %s", msg, err)
+}
+
+// webhooksDisabled returns an error of RPCs of webhooks if they are disabled.
+func (s *Server) webhooksDisabled() error {
+	if s.db == nil {
+		return errSQLStoreRequired("webhooks")
+	}
+	if s.webhooks == nil {
+		return status.Error(codes.FailedPrecondition, "webhooks require WEBHOOK_SECRET_KEY")
+	}
+	return nil
+}
+
+// initializeWebhooks configures a worker by WEBHOOK_MAX_ATTEMPTS and WEBHOOK_POLL_INTERVAL.
+// Webhooks are disabled if the store is not backed by Postgres, or if WEBHOOK_SECRET_KEY to encrypt
+// secrets of subscriptions is not set.
+func (s *Server) initializeWebhooks() error {
+	if s.db == nil {
+		return nil
+	}
+	c, err := webhook.SecretCipherFromEnv()
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		s.logger.Warn("webhooks are disabled because WEBHOOK_SECRET_KEY is not set")
+		return nil
+	}
+	s.webhooks = webhook.NewPsqlStore(s.db, c)
+	var opts []webhook.WorkerOption
+	if v := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			opts = append(opts, webhook.WithMaxAttempts(n))
+		} else {
+			s.logger.Warn("WEBHOOK_MAX_ATTEMPTS is invalid, use default", zap.String("value", v))
+		}
+	}
+	if v := os.Getenv("WEBHOOK_POLL_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			opts = append(opts, webhook.WithPollInterval(d))
+		} else {
+			s.logger.Warn("WEBHOOK_POLL_INTERVAL is invalid, use default", zap.String("value", v))
+		}
+	}
+	s.webhookWorker = webhook.NewWorker(s.webhooks, s.logger, opts...)
+	return nil
+}
+
+func (s *Server) runWebhookWorker(ctx context.Context) {
+	if s.webhookWorker == nil {
+		return
+	}
+	s.webhookWorker.Run(ctx)
+}
@@ -0,0 +1,50 @@
+package protov1
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/kei6u/dogfood/pkg/webhook"
+	dogfoodpb "github.com/kei6u/dogfood/proto/v1/dogfood"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type pageSizeWebhookStore struct {
+	webhook.Store
+	pageSize int
+}
+
+func (s *pageSizeWebhookStore) ListDeliveries(_ context.Context, f webhook.DeliveryFilter) ([]*webhook.Delivery, error) {
+	s.pageSize = f.PageSize
+	return nil, nil
+}
+
+func TestServer_ListWebhookDeliveries_PageSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		pageSize int32
+		want     int
+		wantCode codes.Code
+	}{
+		{name: "default", pageSize: 0, want: defaultWebhookDeliveriesPage},
+		{name: "requested", pageSize: 10, want: 10},
+		{name: "clamped to max", pageSize: 100000, want: maxWebhookDeliveriesPage},
+		{name: "negative", pageSize: -1, wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &pageSizeWebhookStore{}
+			s := &Server{logger: zap.NewNop(), db: &sql.DB{}, webhooks: st}
+			_, err := s.ListWebhookDeliveries(context.Background(), &dogfoodpb.ListWebhookDeliveriesRequest{PageSize: tt.pageSize})
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("ListWebhookDeliveries() error = %v, want %s", err, tt.wantCode)
+			}
+			if st.pageSize != tt.want {
+				t.Errorf("page size = %d, want %d", st.pageSize, tt.want)
+			}
+		})
+	}
+}